@@ -42,6 +42,15 @@ func (bd *BufferData) updateSize(no int64) {
 	bd.size += no
 }
 
+// memorySize returns the byte size of all field data in buffer
+func (bd *BufferData) memorySize() int64 {
+	var size int64
+	for _, field := range bd.buffer.Data {
+		size += int64(field.GetMemorySize())
+	}
+	return size
+}
+
 // updateTimeRange update BufferData tsFrom, tsTo range according to input time range
 func (bd *BufferData) updateTimeRange(tr TimeRange) {
 	if tr.timestampMin < bd.tsFrom {
@@ -22,6 +22,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus/internal/storage"
 )
 
 func TestBufferData(t *testing.T) {
@@ -112,3 +114,15 @@ func TestBufferData_updateTimeRange(t *testing.T) {
 		})
 	}
 }
+
+func TestBufferData_memorySize(t *testing.T) {
+	Params.DataNodeCfg.FlushInsertBufferSize = 16 * (1 << 20) // 16 MB
+
+	bd, err := newBufferData(16)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), bd.memorySize())
+
+	bd.buffer.Data[100] = &storage.Int64FieldData{Data: []int64{1, 2, 3}}
+	bd.buffer.Data[101] = &storage.FloatFieldData{Data: []float32{1, 2}}
+	assert.Equal(t, int64(3*8+2*4), bd.memorySize())
+}
@@ -0,0 +1,117 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+// superUserActor is the actor of datanode itself, never checked against the ACLProvider.
+const superUserActor UniqueID = 0
+
+// ACLProvider decides whether an actor, e.g. a tenant, may read or write the segments of a collection.
+type ACLProvider interface {
+	CanRead(actor, collectionID UniqueID) bool
+	CanWrite(actor, collectionID UniqueID) bool
+}
+
+// WithAccessControl sets the ACLProvider the access through asActor is checked against.
+func WithAccessControl(acl ACLProvider) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.acl = acl
+	}
+}
+
+// checkAccess returns ErrAccessDenied if the actor may not read, or write if write is set, the collection.
+func (c *ChannelMeta) checkAccess(actor UniqueID, write bool) error {
+	if c.acl == nil || actor == superUserActor {
+		return nil
+	}
+	if write && !c.acl.CanWrite(actor, c.collectionID) {
+		return fmt.Errorf("%w, actor %d cannot write collection %d", ErrAccessDenied, actor, c.collectionID)
+	}
+	if !write && !c.acl.CanRead(actor, c.collectionID) {
+		return fmt.Errorf("%w, actor %d cannot read collection %d", ErrAccessDenied, actor, c.collectionID)
+	}
+	return nil
+}
+
+// ActorView is the access of an actor to the channel, every method checks the ACLProvider of the channel first.
+// Datanode internals keep using the channel directly as superUserActor.
+type ActorView struct {
+	c     *ChannelMeta
+	actor UniqueID
+}
+
+// asActor returns the view of the channel for the actor, superUserActor is never denied.
+func (c *ChannelMeta) asActor(actor UniqueID) *ActorView {
+	return &ActorView{c: c, actor: actor}
+}
+
+func (v *ActorView) getCollectionSchema(ts Timestamp) (*schemapb.CollectionSchema, error) {
+	if err := v.c.checkAccess(v.actor, false); err != nil {
+		return nil, err
+	}
+	return v.c.getCollectionSchema(v.c.collectionID, ts)
+}
+
+func (v *ActorView) listAllSegmentIDs() ([]UniqueID, error) {
+	if err := v.c.checkAccess(v.actor, false); err != nil {
+		return nil, err
+	}
+	return v.c.listAllSegmentIDs(), nil
+}
+
+func (v *ActorView) listPartitionSegments(partID UniqueID) ([]UniqueID, error) {
+	if err := v.c.checkAccess(v.actor, false); err != nil {
+		return nil, err
+	}
+	return v.c.listPartitionSegments(partID), nil
+}
+
+func (v *ActorView) getSegmentStatisticsUpdates(segID UniqueID) (*datapb.SegmentStats, error) {
+	if err := v.c.checkAccess(v.actor, false); err != nil {
+		return nil, err
+	}
+	return v.c.getSegmentStatisticsUpdates(segID)
+}
+
+func (v *ActorView) addSegment(req addSegmentReq) error {
+	if err := v.c.checkAccess(v.actor, true); err != nil {
+		return err
+	}
+	return v.c.addSegment(req)
+}
+
+func (v *ActorView) updateStatistics(segID UniqueID, numRows int64) error {
+	if err := v.c.checkAccess(v.actor, true); err != nil {
+		return err
+	}
+	v.c.updateStatistics(segID, numRows)
+	return nil
+}
+
+func (v *ActorView) removeSegments(segIDs ...UniqueID) error {
+	if err := v.c.checkAccess(v.actor, true); err != nil {
+		return err
+	}
+	v.c.removeSegments(segIDs...)
+	return nil
+}
@@ -0,0 +1,117 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticACL grants read and write per actor and collection.
+type staticACL struct {
+	readers map[UniqueID][]UniqueID // actor -> collections
+	writers map[UniqueID][]UniqueID
+}
+
+func (a *staticACL) CanRead(actor, collectionID UniqueID) bool {
+	return a.CanWrite(actor, collectionID) || containsID(a.readers[actor], collectionID)
+}
+
+func (a *staticACL) CanWrite(actor, collectionID UniqueID) bool {
+	return containsID(a.writers[actor], collectionID)
+}
+
+func containsID(ids []UniqueID, id UniqueID) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestChannelMeta_accessControl(t *testing.T) {
+	const (
+		tenantA UniqueID = 100 // owner of collection 1
+		tenantB UniqueID = 200 // owner of collection 2
+		auditor UniqueID = 300 // reads collection 1
+	)
+	acl := &staticACL{
+		readers: map[UniqueID][]UniqueID{auditor: {1}},
+		writers: map[UniqueID][]UniqueID{tenantA: {1}, tenantB: {2}},
+	}
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil, WithAccessControl(acl))
+	req := func(segID UniqueID) addSegmentReq {
+		return addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: 10}
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		owner := channel.asActor(tenantA)
+		require.NoError(t, owner.addSegment(req(1)))
+		require.NoError(t, owner.updateStatistics(1, 10))
+		stats, err := owner.getSegmentStatisticsUpdates(1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), stats.GetNumRows())
+
+		reader := channel.asActor(auditor)
+		segIDs, err := reader.listAllSegmentIDs()
+		require.NoError(t, err)
+		assert.Equal(t, []UniqueID{1}, segIDs)
+		segIDs, err = reader.listPartitionSegments(10)
+		require.NoError(t, err)
+		assert.Equal(t, []UniqueID{1}, segIDs)
+		_, err = reader.getCollectionSchema(0)
+		assert.NoError(t, err)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		other := channel.asActor(tenantB)
+		assert.ErrorIs(t, other.addSegment(req(2)), ErrAccessDenied)
+		assert.ErrorIs(t, other.updateStatistics(1, 10), ErrAccessDenied)
+		assert.ErrorIs(t, other.removeSegments(1), ErrAccessDenied)
+		_, err := other.listAllSegmentIDs()
+		assert.ErrorIs(t, err, ErrAccessDenied)
+		_, err = other.listPartitionSegments(10)
+		assert.ErrorIs(t, err, ErrAccessDenied)
+		_, err = other.getSegmentStatisticsUpdates(1)
+		assert.ErrorIs(t, err, ErrAccessDenied)
+		_, err = other.getCollectionSchema(0)
+		assert.ErrorIs(t, err, ErrAccessDenied)
+
+		// read only
+		assert.ErrorIs(t, channel.asActor(auditor).addSegment(req(2)), ErrAccessDenied)
+
+		assert.False(t, channel.hasSegment(2, true))
+		assert.Equal(t, int64(10), channel.segments[1].numRows)
+	})
+
+	t.Run("super user", func(t *testing.T) {
+		root := channel.asActor(superUserActor)
+		require.NoError(t, root.addSegment(req(3)))
+		require.NoError(t, root.removeSegments(3))
+		assert.False(t, channel.hasSegment(3, true))
+	})
+
+	t.Run("no acl", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		assert.NoError(t, channel.asActor(tenantB).addSegment(req(1)))
+	})
+}
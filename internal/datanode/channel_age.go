@@ -0,0 +1,125 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+)
+
+// SegmentAgeBase is the time which the age of a segment is measured from.
+type SegmentAgeBase int32
+
+const (
+	// AgeFromAllocation measures segment age from when the segment is added to channel.
+	AgeFromAllocation SegmentAgeBase = iota
+	// AgeFromFirstRow measures segment age from when the first rows are counted into the segment,
+	// empty segments have no age.
+	AgeFromFirstRow
+)
+
+// WithSegmentAgeBase sets the time segment age is measured from, AgeFromAllocation by default.
+func WithSegmentAgeBase(base SegmentAgeBase) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.ageBase = base
+	}
+}
+
+// getSegmentTimes returns when the segment is added to channel and when its first rows are counted,
+// firstRowTime is zero for empty segments.
+func (c *ChannelMeta) getSegmentTimes(segID UniqueID) (allocationTime, firstRowTime time.Time, err error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		return time.Time{}, time.Time{}, fmt.Errorf("error, there's no segment %d", segID)
+	}
+	return seg.allocationTime, seg.firstRowTime, nil
+}
+
+// segmentAge returns the age of the segment at now according to the age base of channel,
+// ok is false if the segment does not exist or has no age yet.
+func (c *ChannelMeta) segmentAge(segID UniqueID, now time.Time) (age time.Duration, ok bool) {
+	allocationTime, firstRowTime, err := c.getSegmentTimes(segID)
+	if err != nil {
+		return 0, false
+	}
+
+	base := allocationTime
+	if c.ageBase == AgeFromFirstRow {
+		base = firstRowTime
+	}
+	if base.IsZero() {
+		return 0, false
+	}
+	return now.Sub(base), true
+}
+
+// SegmentCreation is a segment with the hybrid timestamp it is created at, see createTimestamp.
+type SegmentCreation struct {
+	CreateTime Timestamp
+	Info       *datapb.SegmentInfo
+}
+
+// createTimestamp returns the hybrid timestamp the segment is created at, which is the timestamp of its
+// start position, or its allocation time with logical 0 for segments without one, such as recovered flushed segments.
+func (seg *Segment) createTimestamp() Timestamp {
+	if seg.hasStartPosition() {
+		return seg.startPos.GetTimestamp()
+	}
+	return tsoutil.ComposeTSByTime(seg.allocationTime, 0)
+}
+
+// getSegmentsByCreateTimeRange returns the valid segments created within [start, end], ordered by create time
+// and then segment ID. Both bounds are hybrid timestamps compared as a whole, so a segment created at the same
+// physical time as end with a greater logical part is out of the range.
+func (c *ChannelMeta) getSegmentsByCreateTimeRange(start, end Timestamp) []SegmentCreation {
+	if start > end {
+		return nil
+	}
+
+	c.segMu.RLock()
+	var result []SegmentCreation
+	if !c.collectionDroppedWithoutLock() {
+		for _, seg := range c.segments {
+			if !seg.isValid() {
+				continue
+			}
+			if ts := seg.createTimestamp(); ts >= start && ts <= end {
+				result = append(result, SegmentCreation{CreateTime: ts, Info: c.segmentInfo(seg)})
+			}
+		}
+	}
+	c.segMu.RUnlock()
+
+	sortSegmentCreations(result)
+	return result
+}
+
+func sortSegmentCreations(creations []SegmentCreation) {
+	sort.Slice(creations, func(i, j int) bool {
+		if creations[i].CreateTime != creations[j].CreateTime {
+			return creations[i].CreateTime < creations[j].CreateTime
+		}
+		return creations[i].Info.GetID() < creations[j].Info.GetID()
+	})
+}
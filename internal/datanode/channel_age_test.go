@@ -0,0 +1,164 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+)
+
+func newAgeTestChannel(t *testing.T, opts ...ChannelMetaOption) *ChannelMeta {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil, opts...)
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType: datapb.SegmentType_New,
+		segID:   1,
+		collID:  1,
+	}))
+	return channel
+}
+
+func TestChannelMeta_firstRowTime(t *testing.T) {
+	channel := newAgeTestChannel(t)
+
+	allocationTime, firstRowTime, err := channel.getSegmentTimes(1)
+	require.NoError(t, err)
+	assert.False(t, allocationTime.IsZero())
+	assert.True(t, firstRowTime.IsZero())
+
+	// updates without rows leave the segment empty
+	channel.updateStatistics(1, 0)
+	_, firstRowTime, err = channel.getSegmentTimes(1)
+	require.NoError(t, err)
+	assert.True(t, firstRowTime.IsZero())
+
+	channel.updateStatistics(1, 10)
+	_, firstRowTime, err = channel.getSegmentTimes(1)
+	require.NoError(t, err)
+	assert.False(t, firstRowTime.IsZero())
+	assert.False(t, firstRowTime.Before(allocationTime))
+
+	// set only once
+	channel.updateStatistics(1, 10)
+	_, again, err := channel.getSegmentTimes(1)
+	require.NoError(t, err)
+	assert.Equal(t, firstRowTime, again)
+
+	_, _, err = channel.getSegmentTimes(2)
+	assert.Error(t, err)
+}
+
+func TestChannelMeta_segmentTimesClock(t *testing.T) {
+	now := time.Unix(1000, 0)
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	channel.clock = func() time.Time { return now }
+	require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1}))
+
+	now = now.Add(time.Minute)
+	channel.updateStatistics(1, 10)
+	allocationTime, firstRowTime, err := channel.getSegmentTimes(1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Unix(1000, 0), allocationTime)
+	assert.Equal(t, time.Unix(1060, 0), firstRowTime)
+
+	age, ok := channel.segmentAge(1, now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, age)
+}
+
+func TestChannelMeta_segmentAge(t *testing.T) {
+	t.Run("from allocation", func(t *testing.T) {
+		channel := newAgeTestChannel(t)
+		allocationTime, _, err := channel.getSegmentTimes(1)
+		require.NoError(t, err)
+
+		age, ok := channel.segmentAge(1, allocationTime.Add(time.Minute))
+		assert.True(t, ok)
+		assert.Equal(t, time.Minute, age)
+
+		_, ok = channel.segmentAge(2, time.Now())
+		assert.False(t, ok)
+	})
+
+	t.Run("from first row", func(t *testing.T) {
+		channel := newAgeTestChannel(t, WithSegmentAgeBase(AgeFromFirstRow))
+		_, ok := channel.segmentAge(1, time.Now())
+		assert.False(t, ok)
+
+		channel.updateStatistics(1, 10)
+		_, firstRowTime, err := channel.getSegmentTimes(1)
+		require.NoError(t, err)
+		age, ok := channel.segmentAge(1, firstRowTime.Add(time.Minute))
+		assert.True(t, ok)
+		assert.Equal(t, time.Minute, age)
+	})
+}
+
+func TestChannelMeta_getSegmentsByCreateTimeRange(t *testing.T) {
+	base := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	ts := func(offset time.Duration, logical int64) Timestamp {
+		return tsoutil.ComposeTSByTime(base.Add(offset), logical)
+	}
+
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, seg := range []struct {
+		segID   UniqueID
+		segType datapb.SegmentType
+		startTs Timestamp
+	}{
+		{1, datapb.SegmentType_New, ts(-time.Second, 0)},
+		{2, datapb.SegmentType_New, ts(2*time.Second, 0)},
+		{3, datapb.SegmentType_Normal, ts(0, 0)},
+		// same physical time as the end, logical part beyond it
+		{4, datapb.SegmentType_New, ts(5*time.Second, 2)},
+		{5, datapb.SegmentType_New, ts(5*time.Second, 1)},
+		{6, datapb.SegmentType_Compacted, ts(time.Second, 0)},
+	} {
+		s := &Segment{segmentID: seg.segID, collectionID: 1, partitionID: 10,
+			startPos: &internalpb.MsgPosition{Timestamp: seg.startTs}}
+		s.setType(seg.segType)
+		channel.segments[seg.segID] = s
+	}
+	// without start position, the allocation time counts
+	flushed := &Segment{segmentID: 7, collectionID: 1, partitionID: 10, allocationTime: base.Add(3 * time.Second)}
+	flushed.setType(datapb.SegmentType_Flushed)
+	channel.segments[7] = flushed
+
+	ids := func(creations []SegmentCreation) []UniqueID {
+		var result []UniqueID
+		for _, creation := range creations {
+			result = append(result, creation.Info.GetID())
+		}
+		return result
+	}
+
+	creations := channel.getSegmentsByCreateTimeRange(ts(0, 0), ts(5*time.Second, 1))
+	assert.Equal(t, []UniqueID{3, 2, 7, 5}, ids(creations))
+	assert.Equal(t, ts(3*time.Second, 0), creations[2].CreateTime)
+
+	assert.Equal(t, []UniqueID{1, 3, 2, 7, 5, 4}, ids(channel.getSegmentsByCreateTimeRange(0, ts(time.Hour, 0))))
+	assert.Equal(t, []UniqueID{5}, ids(channel.getSegmentsByCreateTimeRange(ts(5*time.Second, 1), ts(5*time.Second, 1))))
+	assert.Empty(t, channel.getSegmentsByCreateTimeRange(ts(time.Hour, 0), ts(2*time.Hour, 0)))
+	assert.Empty(t, channel.getSegmentsByCreateTimeRange(ts(time.Second, 0), ts(0, 0)))
+}
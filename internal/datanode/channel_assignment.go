@@ -0,0 +1,96 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// maxAssignmentHistory is the number of latest assignments kept per segment.
+const maxAssignmentHistory = 10
+
+// AssignmentHistory is a period a data node owned a segment, zero ReleasedAt means it still owns the segment.
+type AssignmentHistory struct {
+	NodeID     UniqueID
+	AssignedAt time.Time
+	ReleasedAt time.Time
+}
+
+// recordAssignment records that nodeID owns the segment from now on, an assignment still open
+// to another node is released at the same time. Assigning the owner again does nothing.
+func (c *ChannelMeta) recordAssignment(segmentID UniqueID, nodeID UniqueID) error {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segmentID]
+	if !ok {
+		return fmt.Errorf("cannot find segment, id = %d", segmentID)
+	}
+
+	now := c.now()
+	if n := len(seg.assignments); n > 0 && seg.assignments[n-1].ReleasedAt.IsZero() {
+		if seg.assignments[n-1].NodeID == nodeID {
+			return nil
+		}
+		log.Info("segment reassigned without release",
+			zap.Int64("segmentID", segmentID),
+			zap.Int64("from", seg.assignments[n-1].NodeID),
+			zap.Int64("to", nodeID))
+		seg.assignments[n-1].ReleasedAt = now
+	}
+	seg.assignments = append(seg.assignments, AssignmentHistory{NodeID: nodeID, AssignedAt: now})
+	if len(seg.assignments) > maxAssignmentHistory {
+		seg.assignments = append(seg.assignments[:0], seg.assignments[len(seg.assignments)-maxAssignmentHistory:]...)
+	}
+	return nil
+}
+
+// recordRelease records that the current owner of the segment released it.
+func (c *ChannelMeta) recordRelease(segmentID UniqueID) error {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segmentID]
+	if !ok {
+		return fmt.Errorf("cannot find segment, id = %d", segmentID)
+	}
+	n := len(seg.assignments)
+	if n == 0 || !seg.assignments[n-1].ReleasedAt.IsZero() {
+		return fmt.Errorf("segment %d is not assigned to any node", segmentID)
+	}
+	seg.assignments[n-1].ReleasedAt = c.now()
+	return nil
+}
+
+// getAssignmentHistory returns the latest assignments of the segment, oldest first.
+func (c *ChannelMeta) getAssignmentHistory(segmentID UniqueID) ([]AssignmentHistory, error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	seg, ok := c.segments[segmentID]
+	if !ok {
+		return nil, fmt.Errorf("cannot find segment, id = %d", segmentID)
+	}
+	history := make([]AssignmentHistory, len(seg.assignments))
+	copy(history, seg.assignments)
+	return history, nil
+}
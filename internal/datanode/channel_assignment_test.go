@@ -0,0 +1,83 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_assignmentHistory(t *testing.T) {
+	now := time.Unix(1000, 0)
+	channel := newChannel("channel", 1, nil, nil, nil)
+	channel.clock = func() time.Time { return now }
+	seg := &Segment{segmentID: 1, collectionID: 1}
+	seg.setType(datapb.SegmentType_New)
+	channel.putSegmentWithoutLock(seg)
+
+	history, err := channel.getAssignmentHistory(1)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	assert.Error(t, channel.recordRelease(1))
+
+	require.NoError(t, channel.recordAssignment(1, 10))
+	now = now.Add(time.Minute)
+	// assigning the owner again is a no-op
+	require.NoError(t, channel.recordAssignment(1, 10))
+	require.NoError(t, channel.recordRelease(1))
+	now = now.Add(time.Minute)
+	require.NoError(t, channel.recordAssignment(1, 11))
+	now = now.Add(time.Minute)
+	// reassigning releases the previous owner
+	require.NoError(t, channel.recordAssignment(1, 12))
+
+	history, err = channel.getAssignmentHistory(1)
+	require.NoError(t, err)
+	assert.Equal(t, []AssignmentHistory{
+		{NodeID: 10, AssignedAt: time.Unix(1000, 0), ReleasedAt: time.Unix(1060, 0)},
+		{NodeID: 11, AssignedAt: time.Unix(1120, 0), ReleasedAt: time.Unix(1180, 0)},
+		{NodeID: 12, AssignedAt: time.Unix(1180, 0)},
+	}, history)
+
+	// the returned history is a copy
+	history[0].NodeID = 100
+	history, err = channel.getAssignmentHistory(1)
+	require.NoError(t, err)
+	assert.Equal(t, UniqueID(10), history[0].NodeID)
+
+	t.Run("keeps the latest entries", func(t *testing.T) {
+		for i := 0; i < 2*maxAssignmentHistory; i++ {
+			require.NoError(t, channel.recordAssignment(1, UniqueID(100+i)))
+		}
+		history, err := channel.getAssignmentHistory(1)
+		require.NoError(t, err)
+		require.Len(t, history, maxAssignmentHistory)
+		assert.Equal(t, UniqueID(100+maxAssignmentHistory), history[0].NodeID)
+		assert.Equal(t, UniqueID(100+2*maxAssignmentHistory-1), history[maxAssignmentHistory-1].NodeID)
+	})
+
+	t.Run("unknown segment", func(t *testing.T) {
+		assert.Error(t, channel.recordAssignment(2, 10))
+		assert.Error(t, channel.recordRelease(2))
+		_, err := channel.getAssignmentHistory(2)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,175 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+)
+
+const (
+	partitionIndexAggregate = "partition_index"
+	channelIndexAggregate   = "channel_index"
+	collectionRowsAggregate = "collection_rows"
+
+	// aggregateAuditInterval is how often the next channel is audited, one channel at a time.
+	aggregateAuditInterval = time.Minute
+)
+
+// auditAggregates recomputes the incrementally maintained indexes of the channel from segments,
+// repairs the drifted ones and returns their names. segMu is held from the recomputation to the
+// repair, so concurrent mutations wait for the audit instead of being overwritten by it.
+func (c *ChannelMeta) auditAggregates() []string {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	partitionSegments := make(map[UniqueID][]*Segment)
+	channelSegments := make(map[string][]UniqueID)
+	var collectionRows int64
+	for _, seg := range c.segments {
+		if seg.isValid() {
+			collectionRows += seg.numRows
+		}
+		partitionSegments[seg.getPartitionID()] = append(partitionSegments[seg.getPartitionID()], seg)
+		if channel := seg.startPos.GetChannelName(); channel != "" {
+			channelSegments[channel] = append(channelSegments[channel], seg.segmentID)
+		}
+	}
+
+	var drifted []string
+	// ChannelMeta not created by newChannel has no partition index to audit
+	if c.partitionSegments != nil && !samePartitionIndex(c.partitionSegments, partitionSegments) {
+		c.partitionSegments = partitionSegments
+		drifted = append(drifted, partitionIndexAggregate)
+	}
+	if !sameChannelIndex(c.channelSegments, channelSegments) {
+		// the adding order is lost, segment IDs are allocated increasingly so they approximate it
+		for _, segIDs := range channelSegments {
+			sort.Slice(segIDs, func(i, j int) bool { return segIDs[i] < segIDs[j] })
+		}
+		c.channelSegments = channelSegments
+		drifted = append(drifted, channelIndexAggregate)
+	}
+	if c.collectionRows != collectionRows {
+		c.collectionRows = collectionRows
+		drifted = append(drifted, collectionRowsAggregate)
+	}
+
+	for _, aggregate := range drifted {
+		log.Warn("channel aggregate drifted from segments, repaired",
+			zap.String("channel", c.channelName),
+			zap.Int64("collectionID", c.collectionID),
+			zap.String("aggregate", aggregate))
+		metrics.DataNodeAggregateDriftCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), aggregate).Inc()
+	}
+	return drifted
+}
+
+func samePartitionIndex(stored, computed map[UniqueID][]*Segment) bool {
+	if len(stored) != len(computed) {
+		return false
+	}
+	for partID, segs := range computed {
+		if len(stored[partID]) != len(segs) {
+			return false
+		}
+		ids := make(map[UniqueID]*Segment, len(segs))
+		for _, seg := range segs {
+			ids[seg.segmentID] = seg
+		}
+		for _, seg := range stored[partID] {
+			if ids[seg.segmentID] != seg {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sameChannelIndex(stored, computed map[string][]UniqueID) bool {
+	if len(stored) != len(computed) {
+		return false
+	}
+	for channel, segIDs := range computed {
+		if len(stored[channel]) != len(segIDs) {
+			return false
+		}
+		ids := make(map[UniqueID]struct{}, len(segIDs))
+		for _, segID := range segIDs {
+			ids[segID] = struct{}{}
+		}
+		for _, segID := range stored[channel] {
+			if _, ok := ids[segID]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// auditAggregatesLoop audits the channels in turns, one channel every interval, until ctx is done.
+func (fm *flowgraphManager) auditAggregatesLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			last = fm.auditNextChannel(last)
+		}
+	}
+}
+
+// auditNextChannel audits the channel following last in name order, wrapping around, and returns its name.
+func (fm *flowgraphManager) auditNextChannel(last string) string {
+	var names []string
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		names = append(names, key.(string))
+		return true
+	})
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+
+	next := names[0]
+	if i := sort.SearchStrings(names, last); i < len(names) {
+		if names[i] == last {
+			i++
+		}
+		if i < len(names) {
+			next = names[i]
+		}
+	}
+	if fg, ok := fm.flowgraphs.Load(next); ok {
+		if channel := fg.(*dataSyncService).channel; channel.InterfaceVersion() >= channelVersionAuditAggregates {
+			channel.auditAggregates()
+		}
+	}
+	return next
+}
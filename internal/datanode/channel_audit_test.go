@@ -0,0 +1,238 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAuditTestChannel(name string, numSegments int) *ChannelMeta {
+	channel := newChannel(name, 1, nil, nil, nil)
+	for i := 0; i < numSegments; i++ {
+		seg := &Segment{
+			collectionID: 1,
+			partitionID:  UniqueID(i % 3),
+			segmentID:    UniqueID(i),
+			startPos:     &internalpb.MsgPosition{ChannelName: name, Timestamp: 100},
+		}
+		seg.setType(datapb.SegmentType_Normal)
+		channel.putSegmentWithoutLock(seg)
+	}
+	return channel
+}
+
+func driftCount(aggregate string) float64 {
+	return testutil.ToFloat64(metrics.DataNodeAggregateDriftCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), aggregate))
+}
+
+func TestChannelMeta_auditAggregates(t *testing.T) {
+	t.Run("no drift", func(t *testing.T) {
+		channel := newAuditTestChannel("ch-0", 10)
+		assert.Empty(t, channel.auditAggregates())
+	})
+
+	t.Run("repair drift", func(t *testing.T) {
+		channel := newAuditTestChannel("ch-0", 10)
+		partitionBefore := driftCount(partitionIndexAggregate)
+		channelBefore := driftCount(channelIndexAggregate)
+
+		// a code path forgetting the indexes
+		delete(channel.partitionSegments, 1)
+		channel.channelSegments["ch-0"] = append(channel.channelSegments["ch-0"], 100)
+
+		assert.ElementsMatch(t, []string{partitionIndexAggregate, channelIndexAggregate}, channel.auditAggregates())
+		assert.Equal(t, partitionBefore+1, driftCount(partitionIndexAggregate))
+		assert.Equal(t, channelBefore+1, driftCount(channelIndexAggregate))
+		assert.ElementsMatch(t, []UniqueID{1, 4, 7}, channel.listPartitionSegments(1))
+		segID, err := channel.getSegmentIDForChannel(1, "ch-0")
+		require.NoError(t, err)
+		assert.Equal(t, UniqueID(9), segID)
+		assert.Empty(t, channel.auditAggregates())
+	})
+
+	t.Run("concurrent writers", func(t *testing.T) {
+		channel := newAuditTestChannel("ch-0", 100)
+		delete(channel.partitionSegments, 2)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; ctx.Err() == nil; i++ {
+					segID := UniqueID(1000 + w*100000 + i)
+					seg := &Segment{
+						collectionID: 1,
+						partitionID:  UniqueID(i % 3),
+						segmentID:    segID,
+						startPos:     &internalpb.MsgPosition{ChannelName: "ch-0", Timestamp: 100},
+					}
+					seg.setType(datapb.SegmentType_Normal)
+					channel.segMu.Lock()
+					channel.putSegmentWithoutLock(seg)
+					channel.segMu.Unlock()
+					if i%2 == 1 {
+						channel.removeSegments(segID - 1)
+					}
+				}
+			}(w)
+		}
+
+		var drifted []string
+		for i := 0; i < 20; i++ {
+			drifted = append(drifted, channel.auditAggregates()...)
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		wg.Wait()
+
+		assert.Equal(t, []string{partitionIndexAggregate}, drifted)
+		assert.Empty(t, channel.auditAggregates())
+	})
+
+	t.Run("repair rows", func(t *testing.T) {
+		channel := newAuditTestChannel("ch-0", 3)
+		// a code path changing rows behind the cache
+		channel.segments[1].numRows = 10
+
+		assert.Equal(t, []string{collectionRowsAggregate}, channel.auditAggregates())
+		rows, err := channel.getCollectionRows(1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), rows)
+	})
+}
+
+func TestFlowgraphManager_auditNextChannel(t *testing.T) {
+	fm := newFlowgraphManager()
+	assert.Equal(t, "", fm.auditNextChannel(""))
+
+	channels := make(map[string]*ChannelMeta)
+	for _, name := range []string{"ch-1", "ch-0", "ch-2"} {
+		channels[name] = newAuditTestChannel(name, 3)
+		delete(channels[name].partitionSegments, 0)
+		fm.flowgraphs.Store(name, &dataSyncService{channel: channels[name]})
+	}
+
+	last := ""
+	for _, expected := range []string{"ch-0", "ch-1", "ch-2", "ch-0"} {
+		last = fm.auditNextChannel(last)
+		assert.Equal(t, expected, last)
+		assert.NotEmpty(t, channels[last].listPartitionSegments(0))
+	}
+
+	// the channel audited last is released
+	fm.flowgraphs.Delete("ch-0")
+	assert.Equal(t, "ch-1", fm.auditNextChannel("ch-0"))
+
+	t.Run("loop", func(t *testing.T) {
+		delete(channels["ch-2"].partitionSegments, 0)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			fm.auditAggregatesLoop(ctx, time.Millisecond)
+			close(done)
+		}()
+		assert.Eventually(t, func() bool {
+			channels["ch-2"].segMu.RLock()
+			defer channels["ch-2"].segMu.RUnlock()
+			return len(channels["ch-2"].partitionSegments[0]) == 1
+		}, time.Second, time.Millisecond)
+		cancel()
+		<-done
+	})
+}
+
+func TestChannelMeta_collectionRows(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil,
+		WithRemoveDebounce(10*time.Millisecond))
+	recomputed := func() int64 {
+		channel.segMu.RLock()
+		defer channel.segMu.RUnlock()
+		var rows int64
+		for _, seg := range channel.segments {
+			if seg.isValid() {
+				rows += seg.numRows
+			}
+		}
+		return rows
+	}
+	assertRows := func(expected int64) {
+		rows, err := channel.getCollectionRows(1)
+		require.NoError(t, err)
+		assert.Equal(t, expected, rows)
+		assert.Equal(t, recomputed(), rows)
+		assert.Empty(t, channel.auditAggregates())
+	}
+
+	for segID := UniqueID(1); segID <= 3; segID++ {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_Flushed,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+			numOfRows:   10,
+		}))
+	}
+	_, err := channel.addSegments([]addSegmentReq{
+		{segType: datapb.SegmentType_New, segID: 4, collID: 1, partitionID: 10},
+		{segType: datapb.SegmentType_New, segID: 5, collID: 1, partitionID: 10},
+	})
+	require.NoError(t, err)
+	channel.updateStatistics(4, 20)
+	channel.updateStatistics(5, 20)
+	assertRows(70)
+
+	// compaction
+	require.NoError(t, channel.mergeFlushedSegments(&Segment{segmentID: 6, collectionID: 1, partitionID: 10, numRows: 15},
+		100, []UniqueID{1, 2}))
+	assertRows(65)
+
+	// removal of compacted segments keeps the total
+	channel.removeSegmentsWithoutLock(1)
+	assertRows(65)
+
+	// conditional removal
+	require.NoError(t, channel.compareAndRemoveSegment(5, channel.getGeneration()))
+	assertRows(45)
+
+	// debounced removal
+	channel.removeSegments(4)
+	assert.Eventually(t, func() bool { return !channel.hasSegment(4, true) }, time.Second, time.Millisecond)
+	assertRows(25)
+
+	// purge
+	require.NoError(t, channel.markCollectionDropped(1))
+	channel.purgeDroppedCollections(0, time.Now())
+	assertRows(0)
+
+	_, err = channel.getCollectionRows(2)
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+// registerBinlogPaths indexes the binlog paths of the segment, so the segment a binlog belongs to is found
+// by getSegmentByBinlogPath without scanning segments. The paths stay indexed until the segment is removed.
+// A path already indexed for another segment is refused, and none of paths is indexed then.
+func (c *ChannelMeta) registerBinlogPaths(segID UniqueID, paths []string) error {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	if _, ok := c.segments[segID]; !ok {
+		return fmt.Errorf("cannot find segment, id = %d", segID)
+	}
+	for _, path := range paths {
+		if owner, ok := c.binlogSegments[path]; ok && owner != segID {
+			return fmt.Errorf("binlog %s of segment %d already registered for segment %d", path, segID, owner)
+		}
+	}
+
+	if c.binlogSegments == nil {
+		c.binlogSegments = make(map[string]UniqueID)
+		c.segmentBinlogs = make(map[UniqueID][]string)
+	}
+	for _, path := range paths {
+		if _, ok := c.binlogSegments[path]; ok {
+			continue
+		}
+		c.binlogSegments[path] = segID
+		c.segmentBinlogs[segID] = append(c.segmentBinlogs[segID], path)
+	}
+	return nil
+}
+
+// unregisterBinlogPathsWithoutLock drops the binlog paths of the segment from the index, caller shall hold the segMu.
+func (c *ChannelMeta) unregisterBinlogPathsWithoutLock(segID UniqueID) {
+	for _, path := range c.segmentBinlogs[segID] {
+		delete(c.binlogSegments, path)
+	}
+	delete(c.segmentBinlogs, segID)
+}
+
+// getSegmentByBinlogPath returns the segment the binlog is registered for, compacted segments included,
+// as their binlogs are the ones garbage collected.
+func (c *ChannelMeta) getSegmentByBinlogPath(path string) (*Segment, error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	segID, ok := c.binlogSegments[path]
+	if !ok {
+		return nil, fmt.Errorf("binlog %s not registered in channel %s", path, c.channelName)
+	}
+	seg, ok := c.segments[segID]
+	if !ok {
+		return nil, fmt.Errorf("segment %d of binlog %s is not in memory", segID, path)
+	}
+	return seg, nil
+}
+
+// fieldBinlogPaths returns the log paths of the field binlogs.
+func fieldBinlogPaths(fieldBinlogs ...[]*datapb.FieldBinlog) []string {
+	var paths []string
+	for _, binlogs := range fieldBinlogs {
+		for _, fieldBinlog := range binlogs {
+			for _, binlog := range fieldBinlog.GetBinlogs() {
+				if binlog.GetLogPath() != "" {
+					paths = append(paths, binlog.GetLogPath())
+				}
+			}
+		}
+	}
+	return paths
+}
@@ -0,0 +1,85 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestChannelMeta_binlogIndex(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, segID := range []UniqueID{1, 2} {
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: 10}))
+	}
+
+	t.Run("register and lookup", func(t *testing.T) {
+		require.NoError(t, channel.registerBinlogPaths(1, []string{"insert_log/1/10/1/100/1", "stats_log/1/10/1/100/2"}))
+		require.NoError(t, channel.registerBinlogPaths(2, []string{"insert_log/1/10/2/100/3"}))
+		// registering again is a no-op
+		require.NoError(t, channel.registerBinlogPaths(1, []string{"insert_log/1/10/1/100/1"}))
+
+		seg, err := channel.getSegmentByBinlogPath("stats_log/1/10/1/100/2")
+		require.NoError(t, err)
+		assert.Equal(t, UniqueID(1), seg.segmentID)
+		seg, err = channel.getSegmentByBinlogPath("insert_log/1/10/2/100/3")
+		require.NoError(t, err)
+		assert.Equal(t, UniqueID(2), seg.segmentID)
+
+		_, err = channel.getSegmentByBinlogPath("insert_log/1/10/3/100/4")
+		assert.Error(t, err)
+	})
+
+	t.Run("refused", func(t *testing.T) {
+		assert.Error(t, channel.registerBinlogPaths(3, []string{"insert_log/1/10/3/100/4"}))
+		// conflicting with segment 1, the other path is not indexed either
+		assert.Error(t, channel.registerBinlogPaths(2, []string{"insert_log/1/10/2/100/5", "insert_log/1/10/1/100/1"}))
+		_, err := channel.getSegmentByBinlogPath("insert_log/1/10/2/100/5")
+		assert.Error(t, err)
+		seg, err := channel.getSegmentByBinlogPath("insert_log/1/10/1/100/1")
+		require.NoError(t, err)
+		assert.Equal(t, UniqueID(1), seg.segmentID)
+	})
+
+	t.Run("removed with segment", func(t *testing.T) {
+		channel.removeSegments(1)
+		_, err := channel.getSegmentByBinlogPath("insert_log/1/10/1/100/1")
+		assert.Error(t, err)
+		_, err = channel.getSegmentByBinlogPath("stats_log/1/10/1/100/2")
+		assert.Error(t, err)
+		assert.NotContains(t, channel.segmentBinlogs, UniqueID(1))
+
+		seg, err := channel.getSegmentByBinlogPath("insert_log/1/10/2/100/3")
+		require.NoError(t, err)
+		assert.Equal(t, UniqueID(2), seg.segmentID)
+	})
+}
+
+func TestFieldBinlogPaths(t *testing.T) {
+	insert := []*datapb.FieldBinlog{
+		{FieldID: 100, Binlogs: []*datapb.Binlog{{LogPath: "a"}, {LogPath: "b"}}},
+		{FieldID: 101, Binlogs: []*datapb.Binlog{{LogPath: ""}}},
+	}
+	delta := []*datapb.FieldBinlog{{Binlogs: []*datapb.Binlog{{LogPath: "c"}}}}
+	assert.Equal(t, []string{"a", "b", "c"}, fieldBinlogPaths(insert, nil, delta))
+	assert.Empty(t, fieldBinlogPaths())
+}
@@ -0,0 +1,68 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"fmt"
+	"runtime/metrics"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// ErrChannelFull is returned by addSegment when the process heap exceeds the memory budget of the channel.
+var ErrChannelFull = errors.New("channel is full")
+
+const heapObjectsMetric = "/memory/classes/heap/objects:bytes"
+
+// WithMemoryBudget rejects new segments with ErrChannelFull once the heap of the process
+// exceeds budget bytes. Go reports running out of memory as a fatal error rather than
+// a recoverable panic, so segments must be refused before the heap gets there.
+// Zero budget disables the check.
+func WithMemoryBudget(budget uint64) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.memoryBudget = budget
+	}
+}
+
+// heapInUse returns the bytes occupied by heap objects, reading it does not stop the world.
+func heapInUse() uint64 {
+	sample := []metrics.Sample{{Name: heapObjectsMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+func (c *ChannelMeta) checkMemoryBudget(segID UniqueID) error {
+	if c.memoryBudget == 0 {
+		return nil
+	}
+	inUse := heapInUse()
+	if inUse <= c.memoryBudget {
+		return nil
+	}
+	log.Error("refuse to add segment, memory budget exceeded",
+		zap.Int64("segmentID", segID),
+		zap.String("channel", c.channelName),
+		zap.Uint64("heapInUse", inUse),
+		zap.Uint64("budget", c.memoryBudget))
+	return fmt.Errorf("%w, heap in use %d exceeds budget %d", ErrChannelFull, inUse, c.memoryBudget)
+}
@@ -0,0 +1,74 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestChannelMeta_memoryBudget(t *testing.T) {
+	const ballastSize = 256 << 20
+
+	runtime.GC()
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil,
+		WithMemoryBudget(heapInUse()+ballastSize/2))
+
+	var ballast [][]byte
+	var err error
+	added := 0
+	for i := 0; i < 100 && err == nil; i++ {
+		if i == 10 {
+			// simulate memory pressure
+			ballast = append(ballast, make([]byte, ballastSize))
+		}
+		assert.NotPanics(t, func() {
+			err = channel.addSegment(addSegmentReq{
+				segType:     datapb.SegmentType_New,
+				segID:       UniqueID(i),
+				collID:      1,
+				partitionID: 10,
+			})
+		})
+		if err == nil {
+			added++
+		}
+	}
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrChannelFull))
+	assert.Equal(t, 10, added)
+
+	// further adds are rejected while the pressure lasts
+	err = channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 100, collID: 1})
+	assert.True(t, errors.Is(err, ErrChannelFull))
+	assert.Len(t, channel.segments, added)
+	runtime.KeepAlive(ballast)
+
+	ballast = nil
+	runtime.GC()
+	assert.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 100, collID: 1}))
+
+	unlimited := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	assert.NoError(t, unlimited.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1}))
+}
@@ -0,0 +1,158 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxBulkErrorItems is the max number of failed items rendered by BulkError.Error.
+const maxBulkErrorItems = 10
+
+// BulkError is the error of a bulk operation on segments, it holds the error of each failed segment.
+// errors.Is matches a BulkError with any of the item errors.
+type BulkError struct {
+	op     string
+	total  int
+	failed map[UniqueID]error
+}
+
+func newBulkError(op string, total int) *BulkError {
+	return &BulkError{op: op, total: total}
+}
+
+// add records the error of a segment, only the first error of a segment is kept.
+func (e *BulkError) add(segID UniqueID, err error) {
+	if e.failed == nil {
+		e.failed = make(map[UniqueID]error)
+	}
+	if _, ok := e.failed[segID]; !ok {
+		e.failed[segID] = err
+	}
+}
+
+// orNil returns e as an error, or nil if no item failed.
+func (e *BulkError) orNil() error {
+	if len(e.failed) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Failed returns the errors of the failed segments by segment ID.
+func (e *BulkError) Failed() map[UniqueID]error {
+	failed := make(map[UniqueID]error, len(e.failed))
+	for segID, err := range e.failed {
+		failed[segID] = err
+	}
+	return failed
+}
+
+// Error renders the errors of the first maxBulkErrorItems failed segments by segment ID.
+func (e *BulkError) Error() string {
+	segIDs := make([]UniqueID, 0, len(e.failed))
+	for segID := range e.failed {
+		segIDs = append(segIDs, segID)
+	}
+	sort.Slice(segIDs, func(i, j int) bool { return segIDs[i] < segIDs[j] })
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %d of %d failed", e.op, len(segIDs), e.total)
+	for i, segID := range segIDs {
+		if i == maxBulkErrorItems {
+			fmt.Fprintf(&sb, "; and %d more", len(segIDs)-maxBulkErrorItems)
+			break
+		}
+		fmt.Fprintf(&sb, "; segment %d: %s", segID, e.failed[segID].Error())
+	}
+	return sb.String()
+}
+
+// Is reports whether any item error matches target.
+func (e *BulkError) Is(target error) bool {
+	for _, err := range e.failed {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// addSegments adds the segments of reqs under a single acquisition of segMu, for bulk recovery.
+// Unlike addSegment, segments already in the channel, including earlier ones of the same batch, are refused.
+// Validation and pk stats loading happen per request before the lock is taken, so the lock is held
+// only to check and insert. created are the IDs of the added segments, the errors of the others are
+// returned in a *BulkError. The added segments are published as one batch, see BatchListener.
+func (c *ChannelMeta) addSegments(reqs []addSegmentReq) (created []UniqueID, err error) {
+	bulkErr := newBulkError("add segments", len(reqs))
+	segs := make([]*Segment, len(reqs))
+	for i, req := range reqs {
+		seg, err := c.prepareSegment(req)
+		if err != nil {
+			bulkErr.add(req.segID, err)
+			continue
+		}
+		segs[i] = seg
+	}
+
+	var (
+		added     eventBatch
+		addedReqs []addSegmentReq
+	)
+	now := c.now()
+	c.segMu.Lock()
+	c.waitUnfrozenWithoutLock()
+	for i, seg := range segs {
+		if seg == nil {
+			continue
+		}
+		if err := c.checkNotPurgedWithoutLock(seg.segmentID); err != nil {
+			bulkErr.add(seg.segmentID, err)
+			continue
+		}
+		if err := c.checkSegmentIDReuseWithoutLock(seg, reqs[i].adoptReusedID); err != nil {
+			bulkErr.add(seg.segmentID, err)
+			continue
+		}
+		if _, ok := c.segments[seg.segmentID]; ok {
+			bulkErr.add(seg.segmentID, fmt.Errorf("%w, id = %d", ErrSegmentExists, seg.segmentID))
+			continue
+		}
+		if err := c.claimSegmentIDWithoutLock(seg); err != nil {
+			bulkErr.add(seg.segmentID, err)
+			continue
+		}
+		c.putSegmentWithoutLock(seg)
+		c.transitSegment(seg, reqs[i].segType, TransitionAdded)
+		c.markDirtyWithoutLock(seg.segmentID)
+		added.events = append(added.events, ChannelEvent{Type: SegmentAdded, CollectionID: reqs[i].collID, SegmentID: seg.segmentID})
+		added.views = append(added.views, segmentViewWithoutLock(seg, now))
+		addedReqs = append(addedReqs, reqs[i])
+		created = append(created, seg.segmentID)
+	}
+	c.events.stampBatch(added.events)
+	c.segMu.Unlock()
+
+	for _, req := range addedReqs {
+		c.countSegmentAdded(req)
+	}
+	c.events.publishBatch(added)
+	return created, bulkErr.orNil()
+}
@@ -0,0 +1,133 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_addSegments(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_Flushed,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+		numOfRows:   100,
+	}))
+
+	reqs := []addSegmentReq{
+		{segType: datapb.SegmentType_Normal, segID: 2, collID: 1, partitionID: 10, numOfRows: 10},
+		{segType: datapb.SegmentType_Flushed, segID: 1, collID: 1, partitionID: 10, numOfRows: 200},
+		{segType: datapb.SegmentType_Flushed, segID: 3, collID: 1, partitionID: 11, numOfRows: 30},
+		{segType: datapb.SegmentType_Normal, segID: 2, collID: 1, partitionID: 11},
+		{segType: datapb.SegmentType_Normal, segID: 4, collID: 2, partitionID: 10},
+	}
+	created, err := channel.addSegments(reqs)
+	assert.Equal(t, []UniqueID{2, 3}, created)
+	var bulkErr *BulkError
+	require.ErrorAs(t, err, &bulkErr)
+	failed := bulkErr.Failed()
+	require.Len(t, failed, 3)
+	assert.ErrorIs(t, failed[1], ErrSegmentIDReused)
+	assert.ErrorIs(t, failed[2], ErrSegmentExists)
+	assert.ErrorIs(t, failed[4], errMismatchCollection)
+	assert.ErrorIs(t, err, ErrSegmentExists)
+
+	// refused duplicates leave the existing segments untouched
+	assert.ElementsMatch(t, []UniqueID{1, 2, 3}, channel.listAllSegmentIDs())
+	assert.Equal(t, int64(100), channel.segments[1].numRows)
+	assert.Equal(t, UniqueID(10), channel.segments[2].partitionID)
+	assert.ElementsMatch(t, []UniqueID{3}, channel.listPartitionSegments(11))
+}
+
+func TestBulkError(t *testing.T) {
+	t.Run("nil when empty", func(t *testing.T) {
+		assert.NoError(t, newBulkError("op", 3).orNil())
+
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		created, err := channel.addSegments([]addSegmentReq{{segType: datapb.SegmentType_New, segID: 1, collID: 1}})
+		assert.NoError(t, err)
+		assert.Equal(t, []UniqueID{1}, created)
+	})
+
+	t.Run("extraction", func(t *testing.T) {
+		bulkErr := newBulkError("op", 2)
+		bulkErr.add(1, ErrSegmentExists)
+		bulkErr.add(1, errMismatchCollection)
+		err := fmt.Errorf("recover channel: %w", bulkErr.orNil())
+
+		var extracted *BulkError
+		require.ErrorAs(t, err, &extracted)
+		assert.Equal(t, map[UniqueID]error{1: ErrSegmentExists}, extracted.Failed())
+		assert.ErrorIs(t, err, ErrSegmentExists)
+		assert.NotErrorIs(t, err, errMismatchCollection)
+	})
+
+	t.Run("rendering", func(t *testing.T) {
+		bulkErr := newBulkError("op", 20)
+		for i := 2; i >= 1; i-- {
+			bulkErr.add(UniqueID(i), fmt.Errorf("error %d", i))
+		}
+		assert.Equal(t, "op: 2 of 20 failed; segment 1: error 1; segment 2: error 2", bulkErr.Error())
+
+		for i := 3; i <= 15; i++ {
+			bulkErr.add(UniqueID(i), fmt.Errorf("error %d", i))
+		}
+		msg := bulkErr.Error()
+		assert.Contains(t, msg, "op: 15 of 20 failed")
+		assert.Contains(t, msg, "segment 10: error 10")
+		assert.NotContains(t, msg, "segment 11:")
+		assert.True(t, strings.HasSuffix(msg, "; and 5 more"))
+	})
+}
+
+func benchmarkAddSegmentReqs(n int) []addSegmentReq {
+	reqs := make([]addSegmentReq, n)
+	for i := range reqs {
+		reqs[i] = addSegmentReq{segType: datapb.SegmentType_Flushed, segID: UniqueID(i), collID: 1, partitionID: 10}
+	}
+	return reqs
+}
+
+func BenchmarkChannelMeta_addSegments(b *testing.B) {
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	for _, n := range []int{100, 1000} {
+		reqs := benchmarkAddSegmentReqs(n)
+		b.Run(fmt.Sprintf("batch-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				channel := newChannel("channel", 1, nil, rc, nil)
+				channel.addSegments(reqs)
+			}
+		})
+		b.Run(fmt.Sprintf("single-%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				channel := newChannel("channel", 1, nil, rc, nil)
+				for _, req := range reqs {
+					_ = channel.addSegment(req)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+// ReplicaCapabilities are the features a channel supports, compiled in or enabled by its options.
+// During rolling upgrades, consumers branch on them rather than on the build of the node.
+type ReplicaCapabilities struct {
+	// InterfaceVersion is the Channel interface version, methods added after it are not supported
+	InterfaceVersion int `json:"interface_version"`
+
+	// compiled-in features
+	DeltaStats    bool `json:"delta_stats"`    // getSegmentStatisticsDelta reports changed statistics only
+	EventSequence bool `json:"event_sequence"` // channel events carry Seq and are delivered in its order
+	SplitStats    bool `json:"split_stats"`    // time tick statistics are split by the message size limit
+
+	// features enabled by options
+	UpdateValidation      bool `json:"update_validation"`        // WithUpdateValidation
+	StrictMemorySizeCheck bool `json:"strict_memory_size_check"` // WithStrictMemorySizeCheck
+	PositionValidation    bool `json:"position_validation"`      // WithVChannels
+	LazyLoad              bool `json:"lazy_load"`                // WithLazyLoad
+	SegmentOwners         bool `json:"segment_owners"`           // WithSegmentOwners
+	AccessControl         bool `json:"access_control"`           // WithAccessControl
+	AddRateLimit          bool `json:"add_rate_limit"`           // WithAddSegmentRateLimit
+	RemoveDebounce        bool `json:"remove_debounce"`          // WithRemoveDebounce
+	Leases                bool `json:"leases"`                   // WithLeaseOwner
+}
+
+// capabilities returns the features of the channel.
+func (c *ChannelMeta) capabilities() ReplicaCapabilities {
+	return ReplicaCapabilities{
+		InterfaceVersion: c.InterfaceVersion(),
+
+		DeltaStats:    true,
+		EventSequence: true,
+		SplitStats:    true,
+
+		UpdateValidation:      c.updateValidation,
+		StrictMemorySizeCheck: c.strictMemorySizeCheck,
+		PositionValidation:    len(c.vchannels) > 0,
+		LazyLoad:              c.metaStore != nil,
+		SegmentOwners:         c.segmentOwners != nil,
+		AccessControl:         c.acl != nil,
+		AddRateLimit:          c.addLimiter != nil,
+		RemoveDebounce:        c.removeDebounce > 0,
+		Leases:                c.leaseOwner != "",
+	}
+}
@@ -0,0 +1,76 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+)
+
+func TestChannelMeta_capabilities(t *testing.T) {
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+
+	caps := newChannel("channel", 1, nil, rc, nil).capabilities()
+	assert.Equal(t, ReplicaCapabilities{
+		InterfaceVersion: ChannelInterfaceVersion,
+		DeltaStats:       true,
+		EventSequence:    true,
+		SplitStats:       true,
+	}, caps)
+
+	caps = newChannel("channel", 1, nil, rc, nil,
+		WithUpdateValidation(),
+		WithStrictMemorySizeCheck(),
+		WithVChannels("channel"),
+		WithLazyLoad(newMockMetadataStore()),
+		WithSegmentOwners(newSegmentOwners()),
+		WithAccessControl(&staticACL{}),
+		WithAddSegmentRateLimit(10, 1, 0),
+		WithRemoveDebounce(time.Second),
+		WithLeaseOwner("node"),
+	).capabilities()
+	assert.Equal(t, ReplicaCapabilities{
+		InterfaceVersion:      ChannelInterfaceVersion,
+		DeltaStats:            true,
+		EventSequence:         true,
+		SplitStats:            true,
+		UpdateValidation:      true,
+		StrictMemorySizeCheck: true,
+		PositionValidation:    true,
+		LazyLoad:              true,
+		SegmentOwners:         true,
+		AccessControl:         true,
+		AddRateLimit:          true,
+		RemoveDebounce:        true,
+		Leases:                true,
+	}, caps)
+
+	t.Run("retrying channel", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil, WithLazyLoad(newMockMetadataStore()))
+		caps := newRetryingChannel(channel).capabilities()
+		assert.Equal(t, retryingChannelVersion, caps.InterfaceVersion)
+		assert.True(t, caps.LazyLoad)
+
+		// a channel before capabilities is not asked for them
+		caps = newRetryingChannel(&olderChannel{Channel: channel}).capabilities()
+		assert.Equal(t, ReplicaCapabilities{InterfaceVersion: 1}, caps)
+	})
+}
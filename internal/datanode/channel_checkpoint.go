@@ -0,0 +1,101 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"sort"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+)
+
+// SegmentView is a read-only snapshot of a segment for inspection.
+type SegmentView struct {
+	SegmentID   UniqueID `json:"segment_id"`
+	PartitionID UniqueID `json:"partition_id"`
+	Type        string   `json:"type"`
+	NumRows     int64    `json:"num_rows"`
+	// StartTs is the timestamp of the start position, which bounds the checkpoint of the channel
+	StartTs Timestamp `json:"start_ts"`
+	// IdleTime is the time since the latest data of the segment, by its end position
+	IdleTime time.Duration `json:"idle_time"`
+
+	// the syncs of the insert buffer, i.e. flush attempts before the segment is flushed
+	SpillCount   int64 `json:"spill_count"`
+	SpilledBytes int64 `json:"spilled_bytes"`
+	SpilledRows  int64 `json:"spilled_rows"`
+
+	Transitions []SegmentTransition `json:"transitions"`
+}
+
+// getCheckpointBlockers returns up to topN unflushed segments on the channel with the oldest start positions,
+// ascending by start position timestamp, which are the ones holding the channel checkpoint back.
+// Non-positive topN returns all of them.
+func (c *ChannelMeta) getCheckpointBlockers(channelName string, topN int) []SegmentView {
+	now := c.now()
+
+	c.segMu.RLock()
+	var blockers []*Segment
+	for _, seg := range c.segments {
+		if !seg.notFlushed() || !seg.hasStartPosition() {
+			continue
+		}
+		segChannel := seg.startPos.GetChannelName()
+		if segChannel == "" {
+			segChannel = c.channelName
+		}
+		if segChannel == channelName {
+			blockers = append(blockers, seg)
+		}
+	}
+	sort.Slice(blockers, func(i, j int) bool {
+		if blockers[i].startPos.GetTimestamp() != blockers[j].startPos.GetTimestamp() {
+			return blockers[i].startPos.GetTimestamp() < blockers[j].startPos.GetTimestamp()
+		}
+		return blockers[i].segmentID < blockers[j].segmentID
+	})
+	if topN > 0 && len(blockers) > topN {
+		blockers = blockers[:topN]
+	}
+
+	views := make([]SegmentView, 0, len(blockers))
+	for _, seg := range blockers {
+		views = append(views, segmentViewWithoutLock(seg, now))
+	}
+	c.segMu.RUnlock()
+	return views
+}
+
+// segmentViewWithoutLock snapshots the segment at now, caller shall hold the segMu.
+func segmentViewWithoutLock(seg *Segment, now time.Time) SegmentView {
+	latestTs := seg.startPos.GetTimestamp()
+	if seg.hasEndPosition() {
+		latestTs = seg.endPos.GetTimestamp()
+	}
+	return SegmentView{
+		SegmentID:    seg.segmentID,
+		PartitionID:  seg.getPartitionID(),
+		Type:         seg.getType().String(),
+		NumRows:      seg.numRows,
+		StartTs:      seg.startPos.GetTimestamp(),
+		IdleTime:     now.Sub(tsoutil.PhysicalTime(latestTs)),
+		SpillCount:   seg.spillCount,
+		SpilledBytes: seg.spilledBytes,
+		SpilledRows:  seg.spilledRows,
+		Transitions:  seg.transitions.list(),
+	}
+}
@@ -0,0 +1,100 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStuckCheckpointChannel returns a channel whose checkpoint is held back by segment 2,
+// started an hour ago and idle since, while the other segments keep receiving data.
+func newStuckCheckpointChannel(now time.Time) *ChannelMeta {
+	channel := newChannel("ch-0", 1, nil, nil, nil)
+	channel.clock = func() time.Time { return now }
+	ts := func(ago time.Duration) Timestamp {
+		return tsoutil.ComposeTSByTime(now.Add(-ago), 0)
+	}
+
+	for _, seg := range []struct {
+		segID      UniqueID
+		segType    datapb.SegmentType
+		channel    string
+		start, end time.Duration
+	}{
+		{1, datapb.SegmentType_Flushed, "ch-0", 2 * time.Hour, 2 * time.Hour},
+		{2, datapb.SegmentType_Normal, "ch-0", time.Hour, 50 * time.Minute},
+		{3, datapb.SegmentType_Normal, "ch-0", 30 * time.Minute, time.Second},
+		{4, datapb.SegmentType_New, "ch-0", 10 * time.Minute, time.Second},
+		{5, datapb.SegmentType_New, "", 20 * time.Minute, time.Second},
+		{6, datapb.SegmentType_Normal, "ch-1", 3 * time.Hour, time.Second},
+	} {
+		s := &Segment{
+			segmentID:    seg.segID,
+			collectionID: 1,
+			numRows:      int64(seg.segID) * 10,
+			startPos:     &internalpb.MsgPosition{ChannelName: seg.channel, Timestamp: ts(seg.start)},
+			endPos:       &internalpb.MsgPosition{ChannelName: seg.channel, Timestamp: ts(seg.end)},
+		}
+		channel.transitSegment(s, seg.segType, TransitionAdded)
+		channel.putSegmentWithoutLock(s)
+	}
+	return channel
+}
+
+func TestChannelMeta_getCheckpointBlockers(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	channel := newStuckCheckpointChannel(now)
+	channel.segments[2].spillCount = 1
+	channel.segments[2].spilledBytes = 1024
+
+	blockerIDs := func(views []SegmentView) []UniqueID {
+		var ids []UniqueID
+		for _, view := range views {
+			ids = append(ids, view.SegmentID)
+		}
+		return ids
+	}
+
+	// flushed segments and segments of other channels do not block,
+	// segments without channel in position are of the channel meta
+	blockers := channel.getCheckpointBlockers("ch-0", 0)
+	assert.Equal(t, []UniqueID{2, 3, 5, 4}, blockerIDs(blockers))
+
+	straggler := blockers[0]
+	assert.Equal(t, datapb.SegmentType_Normal.String(), straggler.Type)
+	assert.Equal(t, int64(20), straggler.NumRows)
+	assert.Equal(t, 50*time.Minute, straggler.IdleTime)
+	assert.Equal(t, int64(1), straggler.SpillCount)
+	assert.Equal(t, int64(1024), straggler.SpilledBytes)
+	require.Len(t, straggler.Transitions, 1)
+	assert.Equal(t, TransitionAdded, straggler.Transitions[0].Reason)
+
+	assert.Equal(t, []UniqueID{2, 3}, blockerIDs(channel.getCheckpointBlockers("ch-0", 2)))
+	assert.Equal(t, []UniqueID{6}, blockerIDs(channel.getCheckpointBlockers("ch-1", 5)))
+	assert.Empty(t, channel.getCheckpointBlockers("ch-2", 5))
+
+	// flushing the straggler unblocks the checkpoint
+	channel.segmentFlushed(2, unknownFlushedRows)
+	assert.Equal(t, []UniqueID{3}, blockerIDs(channel.getCheckpointBlockers("ch-0", 1)))
+}
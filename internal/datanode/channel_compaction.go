@@ -0,0 +1,141 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+// CompactionPolicy decides which flushed segments are merged by getSegmentsReadyForCompaction.
+type CompactionPolicy struct {
+	// SmallSegmentRows is the row count below which a flushed segment is worth compacting
+	SmallSegmentRows int64
+	// MinGroupSize is the least number of small segments worth a compaction, groups of fewer are left alone
+	MinGroupSize int
+	// MaxGroupSize and MaxGroupRows bound a single compaction, zero means unbounded
+	MaxGroupSize int
+	MaxGroupRows int64
+}
+
+// getSegmentsReadyForCompaction groups the small flushed segments of the collection into compaction
+// batches per policy. Segments of different partitions are never grouped together. Within a partition
+// the smallest segments are packed first, so a group that would exceed MaxGroupRows starts a new one.
+func (c *ChannelMeta) getSegmentsReadyForCompaction(collID UniqueID, policy CompactionPolicy) [][]UniqueID {
+	if !c.validCollection(collID) {
+		return nil
+	}
+
+	type candidate struct {
+		segID   UniqueID
+		numRows int64
+	}
+	partitionCandidates := make(map[UniqueID][]candidate)
+	c.segMu.RLock()
+	for _, seg := range c.segments {
+		if seg.getType() == datapb.SegmentType_Flushed && seg.numRows < policy.SmallSegmentRows {
+			partitionCandidates[seg.getPartitionID()] = append(partitionCandidates[seg.getPartitionID()], candidate{seg.segmentID, seg.numRows})
+		}
+	}
+	c.segMu.RUnlock()
+
+	partIDs := make([]UniqueID, 0, len(partitionCandidates))
+	for partID := range partitionCandidates {
+		partIDs = append(partIDs, partID)
+	}
+	sort.Slice(partIDs, func(i, j int) bool { return partIDs[i] < partIDs[j] })
+
+	var groups [][]UniqueID
+	for _, partID := range partIDs {
+		candidates := partitionCandidates[partID]
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].numRows == candidates[j].numRows {
+				return candidates[i].segID < candidates[j].segID
+			}
+			return candidates[i].numRows < candidates[j].numRows
+		})
+
+		var group []UniqueID
+		var groupRows int64
+		flush := func() {
+			if len(group) >= policy.MinGroupSize && len(group) > 1 {
+				groups = append(groups, group)
+			}
+			group, groupRows = nil, 0
+		}
+		for _, cand := range candidates {
+			if len(group) > 0 &&
+				(policy.MaxGroupSize > 0 && len(group) >= policy.MaxGroupSize ||
+					policy.MaxGroupRows > 0 && groupRows+cand.numRows > policy.MaxGroupRows) {
+				flush()
+			}
+			group = append(group, cand.segID)
+			groupRows += cand.numRows
+		}
+		flush()
+	}
+	return groups
+}
+
+// addDeletedRows counts n rows of the segment deleted by the delete stream. The rows are matched by the
+// pk bloom filters, so they may overcount the rows actually deleted.
+func (c *ChannelMeta) addDeletedRows(segID UniqueID, n int64) {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	if seg, ok := c.segments[segID]; ok && seg.isValid() {
+		seg.deletedRows += n
+	}
+}
+
+// estimateCompactionOutput estimates the segment compacting the flushed segments oldIDs would produce,
+// without compacting. rows are the rows of the sources minus their deleted rows. bytes are estimated
+// from the bytes per row each source spilled, or from the fixed-size fields of the schema if it spilled nothing.
+// Both are estimates, deleted rows may be overcounted and bytes are 0 for sources without any size hint.
+func (c *ChannelMeta) estimateCompactionOutput(oldIDs []UniqueID) (rows int64, bytes int64, err error) {
+	if len(oldIDs) == 0 {
+		return 0, 0, fmt.Errorf("no segment to compact")
+	}
+	c.schemaMut.RLock()
+	schema := c.collSchema
+	c.schemaMut.RUnlock()
+	schemaRowSize, schemaRowSizeOK := minRowSize(schema)
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	for _, segID := range oldIDs {
+		seg, ok := c.segments[segID]
+		if !ok || seg.getType() != datapb.SegmentType_Flushed {
+			return 0, 0, fmt.Errorf("segment %d is not a flushed segment of channel %s", segID, c.channelName)
+		}
+		effective := seg.numRows - seg.deletedRows
+		if effective < 0 {
+			effective = 0
+		}
+		rows += effective
+		switch {
+		case seg.spilledRows > 0:
+			bytes += effective * seg.spilledBytes / seg.spilledRows
+		case schema != nil && schemaRowSizeOK:
+			bytes += effective * schemaRowSize
+		}
+	}
+	return rows, bytes, nil
+}
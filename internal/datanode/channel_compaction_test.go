@@ -0,0 +1,127 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_getSegmentsReadyForCompaction(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, seg := range []struct {
+		segID   UniqueID
+		partID  UniqueID
+		segType datapb.SegmentType
+		numRows int64
+	}{
+		{1, 10, datapb.SegmentType_Flushed, 10},
+		{2, 10, datapb.SegmentType_Flushed, 20},
+		{3, 10, datapb.SegmentType_Flushed, 30},
+		{4, 10, datapb.SegmentType_Flushed, 40},
+		{5, 10, datapb.SegmentType_Flushed, 5000}, // large
+		{6, 10, datapb.SegmentType_Normal, 10},    // growing
+		{7, 11, datapb.SegmentType_Flushed, 10},
+		{8, 11, datapb.SegmentType_Flushed, 10},
+		{9, 12, datapb.SegmentType_Flushed, 10}, // alone in its partition
+	} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     seg.segType,
+			segID:       seg.segID,
+			collID:      1,
+			partitionID: seg.partID,
+			numOfRows:   seg.numRows,
+		}))
+	}
+
+	t.Run("groups per partition", func(t *testing.T) {
+		groups := channel.getSegmentsReadyForCompaction(1, CompactionPolicy{SmallSegmentRows: 1000, MinGroupSize: 2})
+		assert.Equal(t, [][]UniqueID{{1, 2, 3, 4}, {7, 8}}, groups)
+	})
+
+	t.Run("bounded groups", func(t *testing.T) {
+		groups := channel.getSegmentsReadyForCompaction(1, CompactionPolicy{
+			SmallSegmentRows: 1000,
+			MinGroupSize:     2,
+			MaxGroupSize:     3,
+			MaxGroupRows:     50,
+		})
+		// 1 and 2 make 30 rows, 3 and 4 would exceed 50 with any neighbour and are left alone
+		assert.Equal(t, [][]UniqueID{{1, 2}, {7, 8}}, groups)
+
+		groups = channel.getSegmentsReadyForCompaction(1, CompactionPolicy{
+			SmallSegmentRows: 1000,
+			MinGroupSize:     2,
+			MaxGroupSize:     2,
+		})
+		assert.Equal(t, [][]UniqueID{{1, 2}, {3, 4}, {7, 8}}, groups)
+	})
+
+	t.Run("min group size", func(t *testing.T) {
+		groups := channel.getSegmentsReadyForCompaction(1, CompactionPolicy{SmallSegmentRows: 1000, MinGroupSize: 3})
+		assert.Equal(t, [][]UniqueID{{1, 2, 3, 4}}, groups)
+	})
+
+	t.Run("mismatch collection", func(t *testing.T) {
+		assert.Nil(t, channel.getSegmentsReadyForCompaction(2, CompactionPolicy{SmallSegmentRows: 1000}))
+	})
+}
+
+func TestChannelMeta_estimateCompactionOutput(t *testing.T) {
+	schema := &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{
+		{FieldID: 100, Name: "pk", IsPrimaryKey: true, DataType: schemapb.DataType_Int64},
+	}}
+	channel := newChannel("channel", 1, schema, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, req := range []addSegmentReq{
+		{segType: datapb.SegmentType_Flushed, segID: 1, collID: 1, partitionID: 10, numOfRows: 100},
+		{segType: datapb.SegmentType_Flushed, segID: 2, collID: 1, partitionID: 10, numOfRows: 50},
+		{segType: datapb.SegmentType_Flushed, segID: 3, collID: 1, partitionID: 10, numOfRows: 10},
+		{segType: datapb.SegmentType_New, segID: 4, collID: 1, partitionID: 10},
+	} {
+		require.NoError(t, channel.addSegment(req))
+	}
+	// 10 bytes per row spilled before the segment was flushed
+	channel.segments[1].spilledBytes = 1000
+	channel.segments[1].spilledRows = 100
+
+	rows, bytes, err := channel.estimateCompactionOutput([]UniqueID{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(160), rows)
+	// segment 2 and 3 by the 8 bytes of the pk field per row
+	assert.Equal(t, int64(100*10+60*8), bytes)
+
+	channel.addDeletedRows(1, 20)
+	channel.addDeletedRows(1, 5)
+	// overcounted deletes leave no row rather than negative ones
+	channel.addDeletedRows(2, 60)
+	channel.addDeletedRows(99, 1)
+	rows, bytes, err = channel.estimateCompactionOutput([]UniqueID{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, int64(75+0+10), rows)
+	assert.Equal(t, int64(75*10+10*8), bytes)
+
+	_, _, err = channel.estimateCompactionOutput([]UniqueID{1, 4})
+	assert.Error(t, err)
+	_, _, err = channel.estimateCompactionOutput([]UniqueID{1, 99})
+	assert.Error(t, err)
+	_, _, err = channel.estimateCompactionOutput(nil)
+	assert.Error(t, err)
+}
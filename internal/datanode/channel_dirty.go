@@ -0,0 +1,52 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+// markDirtyWithoutLock marks a segment modified since the last sync, caller shall hold the segMu.
+func (c *ChannelMeta) markDirtyWithoutLock(segID UniqueID) {
+	if c.dirtySegments == nil {
+		c.dirtySegments = make(map[UniqueID]bool)
+	}
+	c.dirtySegments[segID] = true
+}
+
+// MarkSynced clears the dirty flags of segments the caller has persisted by other means than GetUnsyncedSegments.
+func (c *ChannelMeta) MarkSynced(segmentIDs []UniqueID) {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	for _, segID := range segmentIDs {
+		delete(c.dirtySegments, segID)
+	}
+}
+
+// GetUnsyncedSegments returns the segments modified since the last sync and clears their dirty flags,
+// both under one acquisition of segMu, so a modification is exported by exactly one call.
+// The caller persisting the segments shall not modify them.
+func (c *ChannelMeta) GetUnsyncedSegments() []*Segment {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	segments := make([]*Segment, 0, len(c.dirtySegments))
+	for segID := range c.dirtySegments {
+		if seg, ok := c.segments[segID]; ok {
+			segments = append(segments, seg)
+		}
+	}
+	c.dirtySegments = nil
+	return segments
+}
@@ -0,0 +1,69 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_unsyncedSegments(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	unsyncedIDs := func() []UniqueID {
+		var segIDs []UniqueID
+		for _, seg := range channel.GetUnsyncedSegments() {
+			segIDs = append(segIDs, seg.segmentID)
+		}
+		return segIDs
+	}
+
+	for _, segID := range []UniqueID{1, 2, 3} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}))
+	}
+	assert.ElementsMatch(t, []UniqueID{1, 2, 3}, unsyncedIDs())
+	// dirty flags are cleared by the export
+	assert.Empty(t, unsyncedIDs())
+
+	channel.updateStatistics(1, 10)
+	channel.segmentFlushed(2, unknownFlushedRows)
+	channel.updateStatistics(3, 10)
+	channel.MarkSynced([]UniqueID{3})
+	assert.ElementsMatch(t, []UniqueID{1, 2}, unsyncedIDs())
+
+	t.Run("compaction", func(t *testing.T) {
+		channel.segmentFlushed(1, unknownFlushedRows)
+		channel.GetUnsyncedSegments()
+		require.NoError(t, channel.mergeFlushedSegments(&Segment{segmentID: 4, collectionID: 1, partitionID: 10, numRows: 10},
+			100, []UniqueID{1, 2}))
+		assert.ElementsMatch(t, []UniqueID{1, 2, 4}, unsyncedIDs())
+	})
+
+	t.Run("removed segments are not exported", func(t *testing.T) {
+		channel.updateStatistics(3, 10)
+		channel.removeSegments(3)
+		assert.Empty(t, unsyncedIDs())
+	})
+}
@@ -0,0 +1,151 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// WithSegmentRemovedByCollectionHook sets fn to be called for each segment removed by the purge of the dropped
+// collection, for callers holding per-segment resources. Unlike the segment evicted hook, fn is called
+// from a goroutine started under segMu, so it may call back into the channel.
+func WithSegmentRemovedByCollectionHook(fn func(segmentID, collectionID UniqueID)) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.segmentPurgedHook = fn
+	}
+}
+
+// markCollectionDropped drops the collection logically. Segments of a dropped collection are
+// retained but hidden from lookups and listings until purgeDroppedCollections finalizes the drop.
+func (c *ChannelMeta) markCollectionDropped(collectionID UniqueID) error {
+	if collectionID != c.collectionID {
+		return fmt.Errorf("%w, ID=%d", errMismatchCollection, collectionID)
+	}
+
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+	if c.collectionDroppedWithoutLock() {
+		return fmt.Errorf("collection already dropped, collectionID=%d", collectionID)
+	}
+	c.droppedAt = c.now()
+	log.Info("collection marked dropped",
+		zap.Int64("collectionID", collectionID),
+		zap.String("channel", c.channelName))
+	return nil
+}
+
+// PurgeResult is what a purge of dropped collections removed, or would remove if DryRun is set.
+type PurgeResult struct {
+	DryRun        bool
+	CollectionIDs []UniqueID
+	SegmentIDs    []UniqueID
+}
+
+// purgeDroppedCollections removes all segments of the collection if it was dropped
+// at least olderThan before now, and returns the IDs of purged collections.
+func (c *ChannelMeta) purgeDroppedCollections(olderThan time.Duration, now time.Time) []UniqueID {
+	return c.purgeDropped(olderThan, now, false).CollectionIDs
+}
+
+// previewPurgeDroppedCollections returns what purgeDroppedCollections would remove with the same arguments,
+// without removing anything.
+func (c *ChannelMeta) previewPurgeDroppedCollections(olderThan time.Duration, now time.Time) PurgeResult {
+	return c.purgeDropped(olderThan, now, true)
+}
+
+// purgeDropped selects the segments of the collection to purge and removes them unless dryRun is set,
+// the selection is shared by both so a preview matches the purge.
+func (c *ChannelMeta) purgeDropped(olderThan time.Duration, now time.Time, dryRun bool) PurgeResult {
+	result := PurgeResult{DryRun: dryRun}
+	c.segMu.Lock()
+	if !c.collectionDroppedWithoutLock() || c.purged || now.Sub(c.droppedAt) < olderThan {
+		c.segMu.Unlock()
+		return result
+	}
+
+	segIDs := make([]UniqueID, 0, len(c.segments))
+	for segID := range c.segments {
+		segIDs = append(segIDs, segID)
+	}
+	result.CollectionIDs = []UniqueID{c.collectionID}
+	if dryRun {
+		c.segMu.Unlock()
+		result.SegmentIDs = segIDs
+		return result
+	}
+	removed := c.removeSegmentsWithoutLock(segIDs...)
+	events := c.removedEventsWithoutLock(removed)
+	c.purged = true
+	if hook := c.segmentPurgedHook; hook != nil && len(removed) > 0 {
+		collID := c.collectionID
+		go func() {
+			for _, segID := range removed {
+				hook(segID, collID)
+			}
+		}()
+	}
+	c.segMu.Unlock()
+
+	c.notifySegmentsRemoved(events)
+	log.Info("dropped collection purged",
+		zap.Int64("collectionID", c.collectionID),
+		zap.String("channel", c.channelName),
+		zap.Int("purged segments", len(removed)))
+	result.SegmentIDs = removed
+	return result
+}
+
+// checkNotPurgedWithoutLock refuses segments of the collection once purged, so a segment added concurrently
+// with the purge is not left behind as an orphan of the removed collection, caller shall hold the segMu.
+func (c *ChannelMeta) checkNotPurgedWithoutLock(segID UniqueID) error {
+	if c.purged {
+		return fmt.Errorf("%w, collectionID=%d, segmentID=%d", ErrCollectionPurged, c.collectionID, segID)
+	}
+	return nil
+}
+
+// collectionDroppedWithoutLock returns whether the collection is dropped, caller shall hold the segMu.
+func (c *ChannelMeta) collectionDroppedWithoutLock() bool {
+	return !c.droppedAt.IsZero()
+}
+
+// restoreCollection undoes markCollectionDropped, making the retained segments visible again.
+// It fails if the collection is not dropped or has already been purged.
+func (c *ChannelMeta) restoreCollection(collectionID UniqueID) error {
+	if collectionID != c.collectionID {
+		return fmt.Errorf("%w, ID=%d", errMismatchCollection, collectionID)
+	}
+
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+	if c.purged {
+		return fmt.Errorf("collection already purged, collectionID=%d", collectionID)
+	}
+	if !c.collectionDroppedWithoutLock() {
+		return fmt.Errorf("collection not dropped, collectionID=%d", collectionID)
+	}
+	c.droppedAt = time.Time{}
+	log.Info("dropped collection restored",
+		zap.Int64("collectionID", collectionID),
+		zap.String("channel", c.channelName))
+	return nil
+}
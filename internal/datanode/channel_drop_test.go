@@ -0,0 +1,214 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func newDropTestChannel(t *testing.T) *ChannelMeta {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, segID := range []UniqueID{1, 2} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}))
+	}
+	return channel
+}
+
+func TestChannelMeta_markCollectionDropped(t *testing.T) {
+	channel := newDropTestChannel(t)
+	now := time.Unix(1000, 0)
+	channel.clock = func() time.Time { return now }
+
+	err := channel.markCollectionDropped(2)
+	assert.True(t, errors.Is(err, errMismatchCollection))
+
+	require.NoError(t, channel.markCollectionDropped(1))
+	assert.Equal(t, now, channel.droppedAt)
+	assert.Error(t, channel.markCollectionDropped(1))
+
+	assert.False(t, channel.hasSegment(1, true))
+	assert.Empty(t, channel.listAllSegmentIDs())
+	assert.Empty(t, channel.listPartitionSegments(10))
+	assert.Empty(t, channel.listNotFlushedSegmentIDs())
+	assert.Empty(t, channel.filterSegments(10))
+	infos, total, err := channel.getSegmentsSummaryPaged(0, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, infos)
+	assert.Equal(t, 0, total)
+
+	// data is retained until purged
+	assert.Len(t, channel.segments, 2)
+}
+
+func TestChannelMeta_purgeDroppedCollections(t *testing.T) {
+	channel := newDropTestChannel(t)
+	assert.Empty(t, channel.purgeDroppedCollections(0, time.Now()))
+	assert.Len(t, channel.segments, 2)
+
+	require.NoError(t, channel.markCollectionDropped(1))
+	now := channel.droppedAt
+
+	assert.Empty(t, channel.purgeDroppedCollections(time.Hour, now.Add(time.Minute)))
+	assert.Len(t, channel.segments, 2)
+
+	events, unsubscribe := channel.subscribe()
+	defer unsubscribe()
+	assert.Equal(t, []UniqueID{1}, channel.purgeDroppedCollections(time.Hour, now.Add(time.Hour)))
+	assert.Empty(t, channel.segments)
+	assert.Empty(t, channel.partitionSegments)
+	assert.Len(t, events, 2)
+
+	assert.Empty(t, channel.purgeDroppedCollections(time.Hour, now.Add(2*time.Hour)))
+}
+
+func TestChannelMeta_segmentRemovedByCollectionHook(t *testing.T) {
+	type removal struct{ segID, collID UniqueID }
+	removals := make(chan removal, 10)
+	var channel *ChannelMeta
+	channel = newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil,
+		WithSegmentRemovedByCollectionHook(func(segmentID, collectionID UniqueID) {
+			// calling back into the channel does not deadlock
+			channel.hasSegment(segmentID, true)
+			removals <- removal{segmentID, collectionID}
+		}))
+	for _, segID := range []UniqueID{1, 2} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}))
+	}
+
+	// plain removals do not call the hook
+	channel.removeSegments(2)
+	require.NoError(t, channel.markCollectionDropped(1))
+	require.Equal(t, []UniqueID{1}, channel.purgeDroppedCollections(0, time.Now()))
+
+	select {
+	case r := <-removals:
+		assert.Equal(t, removal{1, 1}, r)
+	case <-time.After(time.Second):
+		t.Fatal("hook not called")
+	}
+	select {
+	case r := <-removals:
+		t.Fatalf("unexpected removal %v", r)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestChannelMeta_previewPurgeDroppedCollections(t *testing.T) {
+	channel := newDropTestChannel(t)
+	preview := channel.previewPurgeDroppedCollections(0, time.Now())
+	assert.True(t, preview.DryRun)
+	assert.Empty(t, preview.CollectionIDs)
+
+	require.NoError(t, channel.markCollectionDropped(1))
+	now := channel.droppedAt
+	assert.Empty(t, channel.previewPurgeDroppedCollections(time.Hour, now.Add(time.Minute)).SegmentIDs)
+
+	events, unsubscribe := channel.subscribe()
+	defer unsubscribe()
+	preview = channel.previewPurgeDroppedCollections(time.Hour, now.Add(time.Hour))
+	assert.True(t, preview.DryRun)
+	assert.Equal(t, []UniqueID{1}, preview.CollectionIDs)
+	assert.ElementsMatch(t, []UniqueID{1, 2}, preview.SegmentIDs)
+	// nothing is removed by the preview
+	assert.Len(t, channel.segments, 2)
+	assert.Empty(t, events)
+	assert.False(t, channel.purged)
+
+	result := channel.purgeDropped(time.Hour, now.Add(time.Hour), false)
+	assert.False(t, result.DryRun)
+	assert.Equal(t, preview.CollectionIDs, result.CollectionIDs)
+	assert.ElementsMatch(t, preview.SegmentIDs, result.SegmentIDs)
+	assert.Empty(t, channel.segments)
+}
+
+func TestChannelMeta_restoreCollection(t *testing.T) {
+	t.Run("restore before purge", func(t *testing.T) {
+		channel := newDropTestChannel(t)
+		assert.Error(t, channel.restoreCollection(1))
+		assert.True(t, errors.Is(channel.restoreCollection(2), errMismatchCollection))
+
+		require.NoError(t, channel.markCollectionDropped(1))
+		require.Empty(t, channel.listAllSegmentIDs())
+		require.NoError(t, channel.restoreCollection(1))
+
+		assert.ElementsMatch(t, []UniqueID{1, 2}, channel.listAllSegmentIDs())
+		assert.True(t, channel.hasSegment(1, false))
+		assert.Empty(t, channel.purgeDroppedCollections(0, time.Now()))
+
+		// can be dropped again after restored
+		assert.NoError(t, channel.markCollectionDropped(1))
+	})
+
+	t.Run("restore after purge", func(t *testing.T) {
+		channel := newDropTestChannel(t)
+		require.NoError(t, channel.markCollectionDropped(1))
+		require.Equal(t, []UniqueID{1}, channel.purgeDroppedCollections(0, time.Now()))
+
+		assert.Error(t, channel.restoreCollection(1))
+		assert.Empty(t, channel.listAllSegmentIDs())
+	})
+}
+
+func TestConcurrentRemoveCollectionAndAddSegment(t *testing.T) {
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	for i := 0; i < 10000; i++ {
+		channel := newChannel("channel", 1, nil, rc, nil)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, channel.markCollectionDropped(1))
+			assert.Equal(t, []UniqueID{1}, channel.purgeDroppedCollections(0, time.Now().Add(time.Second)))
+		}()
+		var addErr error
+		go func() {
+			defer wg.Done()
+			addErr = channel.addSegment(addSegmentReq{
+				segType:     datapb.SegmentType_New,
+				segID:       1,
+				collID:      1,
+				partitionID: 10,
+			})
+		}()
+		wg.Wait()
+
+		if addErr != nil {
+			require.ErrorIs(t, addErr, ErrCollectionPurged)
+		}
+		// a segment added either before the purge is removed by it, or after it is refused
+		require.Empty(t, channel.segments, "orphan segment in iteration %d", i)
+	}
+}
@@ -0,0 +1,408 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sync"
+
+	"go.uber.org/atomic"
+
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+)
+
+// channelEventBufferSize is the buffer size of each subscriber channel.
+const channelEventBufferSize = 128
+
+// ChannelEventType is the type of ChannelEvent.
+type ChannelEventType int32
+
+const (
+	// SegmentAdded stands for a segment added into channel.
+	SegmentAdded ChannelEventType = iota + 1
+	// SegmentRemoved stands for a segment removed from channel.
+	SegmentRemoved
+	// SegmentUpdated stands for a segment statistics or state update.
+	SegmentUpdated
+)
+
+func (t ChannelEventType) String() string {
+	switch t {
+	case SegmentAdded:
+		return "SegmentAdded"
+	case SegmentRemoved:
+		return "SegmentRemoved"
+	case SegmentUpdated:
+		return "SegmentUpdated"
+	default:
+		return "Unknown"
+	}
+}
+
+// ChannelEvent is a segment change of ChannelMeta delivered to subscribers.
+//
+// Seq is the mutation sequence of the channel, stamped when the mutation commits under segMu and
+// increasing in the commit order. Events are delivered to subscribers and listeners in Seq order,
+// so the events of a segment are observed in the order of its mutations. Mirrors of the channel
+// shall rely on the order of the events of each segment only, the order across segments is unspecified.
+type ChannelEvent struct {
+	Type         ChannelEventType
+	CollectionID UniqueID
+	SegmentID    UniqueID
+	Seq          uint64
+}
+
+// ListenerFilter selects the events delivered to a listener, it is evaluated before dispatch.
+type ListenerFilter func(ev ChannelEvent) bool
+
+// CollectionFilter selects the events of the collections.
+func CollectionFilter(collectionIDs ...UniqueID) ListenerFilter {
+	ids := make(map[UniqueID]struct{}, len(collectionIDs))
+	for _, id := range collectionIDs {
+		ids[id] = struct{}{}
+	}
+	return func(ev ChannelEvent) bool {
+		_, ok := ids[ev.CollectionID]
+		return ok
+	}
+}
+
+// BatchListener receives the segments added or removed by a bulk operation, such as addSegments or the removal
+// of several segments, in a single call. The events of single-segment operations still go to the callback
+// registered with it. See WithBatchListener.
+type BatchListener interface {
+	OnSegmentsAdded(segments []SegmentView)
+	OnSegmentsRemoved(segmentIDs []UniqueID)
+}
+
+// ListenerOption configures a listener on registerListener.
+type ListenerOption func(r *ListenerRegistration)
+
+// WithBatchListener delivers bulk operations to batch instead of calling the callback once per segment.
+func WithBatchListener(batch BatchListener) ListenerOption {
+	return func(r *ListenerRegistration) {
+		r.batch = batch
+	}
+}
+
+// WithAsyncDispatch calls the listener from a goroutine of its own rather than from the mutation, through a
+// queue of queueSize deliveries, a bulk operation being one delivery. When the queue is full the oldest delivery
+// is dropped and its events are counted by the listener_dropped_events_total metric.
+// Non-positive queueSize takes channelEventBufferSize.
+func WithAsyncDispatch(queueSize int) ListenerOption {
+	return func(r *ListenerRegistration) {
+		if queueSize <= 0 {
+			queueSize = channelEventBufferSize
+		}
+		r.queue = &listenerQueue{
+			size:   queueSize,
+			notify: make(chan struct{}, 1),
+			done:   make(chan struct{}),
+		}
+	}
+}
+
+// ListenerRegistration is the handle of a listener registered by registerListener.
+type ListenerRegistration struct {
+	hub    *channelEventHub
+	filter ListenerFilter
+	fn     func(ev ChannelEvent)
+	batch  BatchListener
+	queue  *listenerQueue // nil unless WithAsyncDispatch
+	active atomic.Bool
+}
+
+// Unregister stops the delivery to the listener, it is idempotent and safe to call from the callback.
+// Events dispatched after it returns are not delivered, a callback already running concurrently,
+// such as the one calling Unregister, runs to its end.
+func (r *ListenerRegistration) Unregister() {
+	if !r.active.CAS(true, false) {
+		return
+	}
+	if r.queue != nil {
+		close(r.queue.done)
+	}
+	r.hub.mu.Lock()
+	defer r.hub.mu.Unlock()
+	for i, l := range r.hub.listeners {
+		if l == r {
+			// copy on write, dispatches in progress keep iterating their own slice
+			r.hub.listeners = append(r.hub.listeners[:i:i], r.hub.listeners[i+1:]...)
+			return
+		}
+	}
+}
+
+// droppedEvents returns the number of events dropped for the listener falling behind its async queue.
+func (r *ListenerRegistration) droppedEvents() int64 {
+	if r.queue == nil {
+		return 0
+	}
+	return r.queue.dropped.Load()
+}
+
+func (r *ListenerRegistration) deliver(batch eventBatch) {
+	if !r.active.Load() {
+		return
+	}
+	if r.filter != nil {
+		batch = batch.selected(r.filter)
+	}
+	if len(batch.events) == 0 {
+		return
+	}
+	if r.queue != nil {
+		r.queue.push(batch)
+		return
+	}
+	r.call(batch)
+}
+
+// call runs the callbacks of the listener for batch, the bulk ones in a single call if the listener takes batches.
+func (r *ListenerRegistration) call(batch eventBatch) {
+	if batch.bulk && r.batch != nil {
+		switch batch.events[0].Type {
+		case SegmentAdded:
+			r.batch.OnSegmentsAdded(batch.views)
+			return
+		case SegmentRemoved:
+			ids := make([]UniqueID, 0, len(batch.events))
+			for _, ev := range batch.events {
+				ids = append(ids, ev.SegmentID)
+			}
+			r.batch.OnSegmentsRemoved(ids)
+			return
+		}
+	}
+	for _, ev := range batch.events {
+		if !r.active.Load() {
+			return
+		}
+		r.fn(ev)
+	}
+}
+
+// run calls the listener with the deliveries of its queue until it is unregistered.
+func (r *ListenerRegistration) run() {
+	for {
+		select {
+		case <-r.queue.done:
+			return
+		case <-r.queue.notify:
+		}
+		for _, batch := range r.queue.popAll() {
+			if !r.active.Load() {
+				return
+			}
+			r.call(batch)
+		}
+	}
+}
+
+// listenerQueue is the bounded queue of deliveries of an asynchronous listener.
+type listenerQueue struct {
+	mu      sync.Mutex
+	items   []eventBatch
+	size    int
+	notify  chan struct{}
+	done    chan struct{}
+	dropped atomic.Int64
+}
+
+// push appends batch, dropping the oldest delivery if the queue is full.
+func (q *listenerQueue) push(batch eventBatch) {
+	q.mu.Lock()
+	if len(q.items) >= q.size {
+		n := len(q.items[0].events)
+		q.items = append(q.items[:0], q.items[1:]...)
+		q.dropped.Add(int64(n))
+		metrics.DataNodeListenerDroppedEvents.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Add(float64(n))
+	}
+	q.items = append(q.items, batch)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *listenerQueue) popAll() []eventBatch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// eventBatch is the events of a mutation, with consecutive sequences. It is bulk if the mutation is
+// a bulk operation on several segments, views are the snapshots of the segments added by it.
+type eventBatch struct {
+	events []ChannelEvent
+	views  []SegmentView
+	bulk   bool
+}
+
+// selected returns the events of the batch selected by filter.
+func (b eventBatch) selected(filter ListenerFilter) eventBatch {
+	result := eventBatch{bulk: b.bulk}
+	for i, ev := range b.events {
+		if !filter(ev) {
+			continue
+		}
+		result.events = append(result.events, ev)
+		if b.views != nil {
+			result.views = append(result.views, b.views[i])
+		}
+	}
+	return result
+}
+
+// channelEventHub delivers events to subscribers without blocking the publisher,
+// events are dropped for subscribers whose buffer is full.
+// Listeners are called synchronously by a publisher, in registration order, unless registered WithAsyncDispatch.
+// An event published ahead of an event stamped before it is held until the earlier one is published,
+// then both are delivered by the publisher of the earlier one.
+type channelEventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan ChannelEvent]struct{}
+	listeners   []*ListenerRegistration
+	dropped     atomic.Int64
+
+	stamped atomic.Uint64
+	// delivered is the Seq of the last event delivered, pending are the batches published ahead of their turn
+	// by the Seq of their first event, dispatching is set while a publisher is delivering
+	delivered   uint64
+	pending     map[uint64]eventBatch
+	dispatching bool
+}
+
+// stamp sets the next sequence on ev. The caller shall hold the segMu of the channel, so that sequences follow
+// the commit order, and shall publish every stamped event, as the later events wait for it.
+func (h *channelEventHub) stamp(ev ChannelEvent) ChannelEvent {
+	ev.Seq = h.stamped.Inc()
+	return ev
+}
+
+// stampBatch sets consecutive sequences on the events of a mutation, which shall be published together
+// by publishBatch. The requirements of stamp apply.
+func (h *channelEventHub) stampBatch(events []ChannelEvent) {
+	last := h.stamped.Add(uint64(len(events)))
+	first := last - uint64(len(events)) + 1
+	for i := range events {
+		events[i].Seq = first + uint64(i)
+	}
+}
+
+func (h *channelEventHub) registerListener(filter ListenerFilter, fn func(ev ChannelEvent), opts ...ListenerOption) *ListenerRegistration {
+	r := &ListenerRegistration{hub: h, filter: filter, fn: fn}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.active.Store(true)
+	if r.queue != nil {
+		go r.run()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listeners = append(h.listeners, r)
+	return r
+}
+
+func (h *channelEventHub) subscribe() (<-chan ChannelEvent, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscribers == nil {
+		h.subscribers = make(map[chan ChannelEvent]struct{})
+	}
+	ch := make(chan ChannelEvent, channelEventBufferSize)
+	h.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// publish delivers ev once the events stamped before it are delivered, ev not stamped yet is stamped here.
+func (h *channelEventHub) publish(ev ChannelEvent) {
+	if ev.Seq == 0 {
+		ev = h.stamp(ev)
+	}
+	h.publishBatch(eventBatch{events: []ChannelEvent{ev}})
+}
+
+// publishBatch delivers the events stamped by stampBatch once the events stamped before them are delivered.
+// Subscribers receive the events one by one, listeners the whole batch, see BatchListener.
+func (h *channelEventHub) publishBatch(batch eventBatch) {
+	if len(batch.events) == 0 {
+		return
+	}
+	batch.bulk = len(batch.events) > 1
+
+	h.mu.Lock()
+	if h.pending == nil {
+		h.pending = make(map[uint64]eventBatch)
+	}
+	h.pending[batch.events[0].Seq] = batch
+	if h.dispatching {
+		// the publisher dispatching delivers batch in its turn
+		h.mu.Unlock()
+		return
+	}
+	h.dispatching = true
+	for {
+		next, ok := h.pending[h.delivered+1]
+		if !ok {
+			break
+		}
+		delete(h.pending, h.delivered+1)
+		h.delivered = next.events[len(next.events)-1].Seq
+		for _, ev := range next.events {
+			for ch := range h.subscribers {
+				select {
+				case ch <- ev:
+				default:
+					h.dropped.Inc()
+				}
+			}
+		}
+		// listeners unregistering meanwhile are skipped by deliver
+		listeners := h.listeners
+		h.mu.Unlock()
+
+		for _, l := range listeners {
+			l.deliver(next)
+		}
+		h.mu.Lock()
+	}
+	h.dispatching = false
+	h.mu.Unlock()
+}
+
+// droppedEvents returns the number of events dropped for slow subscribers.
+func (h *channelEventHub) droppedEvents() int64 {
+	return h.dropped.Load()
+}
@@ -0,0 +1,385 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/storage"
+)
+
+func TestChannelMeta_subscribe(t *testing.T) {
+	rc := &RootCoordFactory{
+		pkType: schemapb.DataType_Int64,
+	}
+	cm := storage.NewLocalChunkManager(storage.RootPath(channelMetaNodeTestDir))
+	defer cm.RemoveWithPrefix(context.Background(), "")
+
+	t.Run("events delivered", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm)
+		events, unsubscribe := channel.subscribe()
+		defer unsubscribe()
+
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       1,
+			collID:      1,
+			partitionID: 10,
+		}))
+		channel.updateStatistics(1, 10)
+		channel.segmentFlushed(1, unknownFlushedRows)
+		channel.removeSegments(1)
+
+		expected := []ChannelEventType{SegmentAdded, SegmentUpdated, SegmentUpdated, SegmentRemoved}
+		for _, evType := range expected {
+			select {
+			case ev := <-events:
+				assert.Equal(t, evType, ev.Type)
+				assert.Equal(t, UniqueID(1), ev.SegmentID)
+				assert.Equal(t, UniqueID(1), ev.CollectionID)
+			case <-time.After(time.Second):
+				t.Fatalf("event %s not delivered", evType)
+			}
+		}
+	})
+
+	t.Run("slow subscriber does not block", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm)
+		_, unsubscribe := channel.subscribe()
+		defer unsubscribe()
+
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       1,
+			collID:      1,
+			partitionID: 10,
+		}))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 2*channelEventBufferSize; i++ {
+				channel.updateStatistics(1, 1)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("channel mutation blocked by slow subscriber")
+		}
+		assert.Equal(t, int64(channelEventBufferSize+1), channel.events.droppedEvents())
+	})
+
+	t.Run("unsubscribe", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm)
+		events, unsubscribe := channel.subscribe()
+		unsubscribe()
+		unsubscribe()
+
+		_, ok := <-events
+		assert.False(t, ok)
+		channel.updateStatistics(1, 1)
+		assert.Equal(t, int64(0), channel.events.droppedEvents())
+	})
+}
+
+func TestChannelMeta_registerListener(t *testing.T) {
+	newReq := func(segID UniqueID) addSegmentReq {
+		return addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: 10}
+	}
+
+	t.Run("filtered delivery in registration order", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		var calls []string
+		channel.registerListener(nil, func(ev ChannelEvent) {
+			calls = append(calls, "all:"+ev.Type.String())
+		})
+		channel.registerListener(CollectionFilter(2, 3), func(ev ChannelEvent) {
+			calls = append(calls, "other")
+		})
+		channel.registerListener(CollectionFilter(1), func(ev ChannelEvent) {
+			calls = append(calls, "mine:"+ev.Type.String())
+		})
+		channel.registerListener(func(ev ChannelEvent) bool { return ev.Type == SegmentRemoved }, func(ev ChannelEvent) {
+			calls = append(calls, "removed")
+		})
+
+		require.NoError(t, channel.addSegment(newReq(1)))
+		channel.removeSegments(1)
+		assert.Equal(t, []string{
+			"all:SegmentAdded", "mine:SegmentAdded",
+			"all:SegmentRemoved", "mine:SegmentRemoved", "removed",
+		}, calls)
+	})
+
+	t.Run("unregister during dispatch", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		var calls []string
+		var self, next *ListenerRegistration
+		self = channel.registerListener(nil, func(ev ChannelEvent) {
+			calls = append(calls, "self")
+			self.Unregister()
+			// unregistering a listener later in the same dispatch skips it
+			next.Unregister()
+		})
+		next = channel.registerListener(nil, func(ev ChannelEvent) {
+			calls = append(calls, "next")
+		})
+		channel.registerListener(nil, func(ev ChannelEvent) {
+			calls = append(calls, "last")
+		})
+
+		require.NoError(t, channel.addSegment(newReq(1)))
+		require.NoError(t, channel.addSegment(newReq(2)))
+		assert.Equal(t, []string{"self", "last", "last"}, calls)
+		// idempotent
+		self.Unregister()
+	})
+
+	t.Run("no events after unregister returns", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		require.NoError(t, channel.addSegment(newReq(1)))
+		var delivered, published atomic.Int64
+		reg := channel.registerListener(nil, func(ev ChannelEvent) {
+			delivered.Inc()
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for ctx.Err() == nil {
+				channel.updateStatistics(1, 1)
+				published.Inc()
+			}
+		}()
+		assert.Eventually(t, func() bool { return delivered.Load() > 100 }, time.Second, time.Millisecond)
+
+		reg.Unregister()
+		deliveredAtUnregister := delivered.Load()
+		publishedAtUnregister := published.Load()
+		assert.Eventually(t, func() bool { return published.Load() > publishedAtUnregister+100 }, time.Second, time.Millisecond)
+		cancel()
+		<-done
+		// at most the callback running when Unregister was called
+		assert.LessOrEqual(t, delivered.Load(), deliveredAtUnregister+1)
+
+		channel.updateStatistics(1, 1)
+		assert.LessOrEqual(t, delivered.Load(), deliveredAtUnregister+1)
+	})
+}
+
+func TestChannelMeta_eventSequence(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	var (
+		lastSeq  uint64
+		segSeqs  = make(map[UniqueID]uint64)
+		mirror   = make(map[UniqueID]struct{})
+		disorder []string
+	)
+	// deliveries are serialized by the hub, no lock needed in the listener
+	channel.registerListener(nil, func(ev ChannelEvent) {
+		if ev.Seq <= lastSeq {
+			disorder = append(disorder, fmt.Sprintf("seq %d after %d", ev.Seq, lastSeq))
+		}
+		lastSeq = ev.Seq
+		if ev.Seq <= segSeqs[ev.SegmentID] {
+			disorder = append(disorder, fmt.Sprintf("segment %d seq %d after %d", ev.SegmentID, ev.Seq, segSeqs[ev.SegmentID]))
+		}
+		segSeqs[ev.SegmentID] = ev.Seq
+		switch ev.Type {
+		case SegmentAdded:
+			mirror[ev.SegmentID] = struct{}{}
+		case SegmentRemoved:
+			delete(mirror, ev.SegmentID)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				// workers share segment IDs so that adds and removes of a segment race
+				segID := UniqueID(i%4 + 1)
+				switch (w + i) % 3 {
+				case 0:
+					_ = channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: 10})
+				case 1:
+					channel.updateStatistics(segID, 1)
+				default:
+					channel.removeSegments(segID)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.Empty(t, disorder)
+	assert.Equal(t, channel.events.stamped.Load(), lastSeq)
+	expected := make(map[UniqueID]struct{})
+	for _, segID := range channel.listAllSegmentIDs() {
+		expected[segID] = struct{}{}
+	}
+	assert.Equal(t, expected, mirror)
+}
+
+type recordingBatchListener struct {
+	calls *[]string
+}
+
+func (l recordingBatchListener) OnSegmentsAdded(segments []SegmentView) {
+	ids := make([]UniqueID, 0, len(segments))
+	for _, seg := range segments {
+		ids = append(ids, seg.SegmentID)
+	}
+	*l.calls = append(*l.calls, fmt.Sprintf("added%v", ids))
+}
+
+func (l recordingBatchListener) OnSegmentsRemoved(segmentIDs []UniqueID) {
+	*l.calls = append(*l.calls, fmt.Sprintf("removed%v", segmentIDs))
+}
+
+func TestChannelMeta_batchListener(t *testing.T) {
+	newReq := func(segID UniqueID) addSegmentReq {
+		return addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: 10}
+	}
+
+	t.Run("batch delivery", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		var batched, plain []string
+		channel.registerListener(nil, func(ev ChannelEvent) {
+			batched = append(batched, fmt.Sprintf("%s:%d", ev.Type, ev.SegmentID))
+		}, WithBatchListener(recordingBatchListener{calls: &batched}))
+		channel.registerListener(nil, func(ev ChannelEvent) {
+			plain = append(plain, fmt.Sprintf("%s:%d", ev.Type, ev.SegmentID))
+		})
+		// nothing selected, no call
+		channel.registerListener(CollectionFilter(2), func(ev ChannelEvent) {
+			t.Fatal("unexpected event")
+		}, WithBatchListener(recordingBatchListener{calls: &[]string{}}))
+
+		created, err := channel.addSegments([]addSegmentReq{newReq(1), newReq(2), newReq(3)})
+		require.NoError(t, err)
+		require.Len(t, created, 3)
+		channel.removeSegments(1, 2)
+		require.NoError(t, channel.addSegment(newReq(4)))
+		channel.removeSegments(3)
+
+		assert.Equal(t, []string{"added[1 2 3]", "removed[1 2]", "SegmentAdded:4", "SegmentRemoved:3"}, batched)
+		assert.Equal(t, []string{
+			"SegmentAdded:1", "SegmentAdded:2", "SegmentAdded:3",
+			"SegmentRemoved:1", "SegmentRemoved:2",
+			"SegmentAdded:4", "SegmentRemoved:3",
+		}, plain)
+	})
+
+	t.Run("ordered with singular events", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		var calls []string
+		var lastSeq uint64
+		channel.registerListener(nil, func(ev ChannelEvent) {
+			assert.Greater(t, ev.Seq, lastSeq)
+			lastSeq = ev.Seq
+			calls = append(calls, fmt.Sprintf("%s:%d", ev.Type, ev.SegmentID))
+		}, WithBatchListener(recordingBatchListener{calls: &calls}))
+
+		require.NoError(t, channel.addSegment(newReq(1)))
+		_, err := channel.addSegments([]addSegmentReq{newReq(2), newReq(3)})
+		require.NoError(t, err)
+		channel.updateStatistics(1, 10)
+		channel.removeSegments(2, 3)
+		channel.updateStatistics(1, 10)
+
+		assert.Equal(t, []string{
+			"SegmentAdded:1", "added[2 3]", "SegmentUpdated:1", "removed[2 3]", "SegmentUpdated:1",
+		}, calls)
+		// the batches took consecutive sequences
+		assert.Equal(t, uint64(7), channel.events.stamped.Load())
+		assert.Equal(t, uint64(7), lastSeq)
+	})
+
+	t.Run("async queue overflow", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		require.NoError(t, channel.addSegment(newReq(1)))
+
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var mu sync.Mutex
+		var seqs []uint64
+		reg := channel.registerListener(nil, func(ev ChannelEvent) {
+			if ev.Seq == 2 {
+				close(started)
+				<-release
+			}
+			mu.Lock()
+			seqs = append(seqs, ev.Seq)
+			mu.Unlock()
+		}, WithAsyncDispatch(2))
+		defer reg.Unregister()
+
+		channel.updateStatistics(1, 1)
+		select {
+		case <-started:
+		case <-time.After(time.Second):
+			t.Fatal("async listener not called")
+		}
+		// the listener is blocked, mutations are not
+		for i := 0; i < 5; i++ {
+			channel.updateStatistics(1, 1)
+		}
+		assert.Equal(t, int64(3), reg.droppedEvents())
+		close(release)
+
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(seqs) == 3
+		}, time.Second, time.Millisecond)
+		mu.Lock()
+		assert.Equal(t, []uint64{2, 6, 7}, seqs)
+		mu.Unlock()
+	})
+
+	t.Run("async unregister", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		var delivered atomic.Int64
+		reg := channel.registerListener(nil, func(ev ChannelEvent) {
+			delivered.Inc()
+		}, WithAsyncDispatch(0))
+		require.NoError(t, channel.addSegment(newReq(1)))
+		assert.Eventually(t, func() bool { return delivered.Load() == 1 }, time.Second, time.Millisecond)
+
+		reg.Unregister()
+		reg.Unregister()
+		channel.updateStatistics(1, 1)
+		time.Sleep(10 * time.Millisecond)
+		assert.Equal(t, int64(1), delivered.Load())
+		assert.Zero(t, reg.droppedEvents())
+	})
+}
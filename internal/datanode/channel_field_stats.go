@@ -0,0 +1,131 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"math"
+)
+
+// FieldStats are the statistics of a field over the rows of a segment.
+type FieldStats struct {
+	Min       float64
+	Max       float64
+	NullCount int64
+}
+
+// addFieldNulls counts n more null rows of the field into the segment.
+// The field must be in the collection schema, so stale field IDs are caught when counting.
+func (c *ChannelMeta) addFieldNulls(segID UniqueID, fieldID int64, n int64) error {
+	if n < 0 {
+		return fmt.Errorf("negative null count %d of field %d, segment %d", n, fieldID, segID)
+	}
+	cache, err := c.getSchemaCache(c.collectionID, 0)
+	if err != nil {
+		return err
+	}
+	if _, ok := cache.fieldsByID[fieldID]; !ok {
+		return fmt.Errorf("field %d not in schema of collection %d", fieldID, c.collectionID)
+	}
+
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		return fmt.Errorf("error, there's no segment %d", segID)
+	}
+	if seg.nullCounts == nil {
+		seg.nullCounts = make(map[int64]int64)
+	}
+	seg.nullCounts[fieldID] += n
+	c.markDirtyWithoutLock(segID)
+	return nil
+}
+
+// getSegmentFieldStats returns the null rows per field ID of the segment, fields without null are left out.
+func (c *ChannelMeta) getSegmentFieldStats(segID UniqueID) (map[int64]int64, error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		return nil, fmt.Errorf("error, there's no segment %d", segID)
+	}
+	nullCounts := make(map[int64]int64, len(seg.nullCounts))
+	for fieldID, n := range seg.nullCounts {
+		if n > 0 {
+			nullCounts[fieldID] = n
+		}
+	}
+	return nullCounts, nil
+}
+
+// updateFieldStatistics merges the statistics of a batch of rows of the field, by name, into the segment:
+// the min and max are widened to cover the batch and the null count is added.
+func (c *ChannelMeta) updateFieldStatistics(segmentID UniqueID, fieldName string, min, max float64, nullCount int64) error {
+	if math.IsNaN(min) || math.IsNaN(max) || min > max {
+		return fmt.Errorf("invalid range [%v, %v] of field %s, segment %d", min, max, fieldName, segmentID)
+	}
+	if nullCount < 0 {
+		return fmt.Errorf("negative null count %d of field %s, segment %d", nullCount, fieldName, segmentID)
+	}
+	cache, err := c.getSchemaCache(c.collectionID, 0)
+	if err != nil {
+		return err
+	}
+	if _, ok := cache.fieldsByName[fieldName]; !ok {
+		return fmt.Errorf("field %s not in schema of collection %d", fieldName, c.collectionID)
+	}
+
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segmentID]
+	if !ok || !seg.isValid() {
+		return fmt.Errorf("error, there's no segment %d", segmentID)
+	}
+	if seg.fieldStats == nil {
+		seg.fieldStats = make(map[string]*FieldStats)
+	}
+	stats, ok := seg.fieldStats[fieldName]
+	if !ok {
+		seg.fieldStats[fieldName] = &FieldStats{Min: min, Max: max, NullCount: nullCount}
+	} else {
+		stats.Min = math.Min(stats.Min, min)
+		stats.Max = math.Max(stats.Max, max)
+		stats.NullCount += nullCount
+	}
+	c.markDirtyWithoutLock(segmentID)
+	return nil
+}
+
+// getSegmentFieldStatistics returns a copy of the statistics per field name of the segment.
+func (c *ChannelMeta) getSegmentFieldStatistics(segID UniqueID) (map[string]FieldStats, error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		return nil, fmt.Errorf("error, there's no segment %d", segID)
+	}
+	fieldStats := make(map[string]FieldStats, len(seg.fieldStats))
+	for name, stats := range seg.fieldStats {
+		fieldStats[name] = *stats
+	}
+	return fieldStats, nil
+}
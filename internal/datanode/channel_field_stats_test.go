@@ -0,0 +1,91 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"math"
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_fieldNulls(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+	}))
+
+	stats, err := channel.getSegmentFieldStats(1)
+	require.NoError(t, err)
+	assert.Empty(t, stats)
+
+	require.NoError(t, channel.addFieldNulls(1, 102, 3))
+	require.NoError(t, channel.addFieldNulls(1, 103, 1))
+	require.NoError(t, channel.addFieldNulls(1, 102, 4))
+	require.NoError(t, channel.addFieldNulls(1, 104, 0))
+	stats, err = channel.getSegmentFieldStats(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int64{102: 7, 103: 1}, stats)
+
+	// the result is a copy
+	stats[102] = 0
+	stats, err = channel.getSegmentFieldStats(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), stats[102])
+
+	assert.Error(t, channel.addFieldNulls(1, 102, -1))
+	assert.Error(t, channel.addFieldNulls(1, 999, 1))
+	assert.Error(t, channel.addFieldNulls(2, 102, 1))
+	_, err = channel.getSegmentFieldStats(2)
+	assert.Error(t, err)
+}
+
+func TestChannelMeta_updateFieldStatistics(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+	}))
+
+	require.NoError(t, channel.updateFieldStatistics(1, "int64_field", 10, 20, 1))
+	require.NoError(t, channel.updateFieldStatistics(1, "int64_field", 5, 15, 2))
+	require.NoError(t, channel.updateFieldStatistics(1, "int64_field", 12, 30, 0))
+	require.NoError(t, channel.updateFieldStatistics(1, "float64_field", -1.5, -1.5, 0))
+	stats, err := channel.getSegmentFieldStatistics(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]FieldStats{
+		"int64_field":   {Min: 5, Max: 30, NullCount: 3},
+		"float64_field": {Min: -1.5, Max: -1.5},
+	}, stats)
+
+	assert.Error(t, channel.updateFieldStatistics(1, "int64_field", 2, 1, 0))
+	assert.Error(t, channel.updateFieldStatistics(1, "int64_field", math.NaN(), 1, 0))
+	assert.Error(t, channel.updateFieldStatistics(1, "int64_field", 1, 2, -1))
+	assert.Error(t, channel.updateFieldStatistics(1, "no_such_field", 1, 2, 0))
+	assert.Error(t, channel.updateFieldStatistics(2, "int64_field", 1, 2, 0))
+	stats, err = channel.getSegmentFieldStatistics(1)
+	require.NoError(t, err)
+	assert.Equal(t, FieldStats{Min: 5, Max: 30, NullCount: 3}, stats["int64_field"])
+}
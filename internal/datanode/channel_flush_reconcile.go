@@ -0,0 +1,113 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+)
+
+// unknownFlushedRows is passed to segmentFlushed to skip the reconciliation.
+const unknownFlushedRows int64 = -1
+
+// WithFlushReconcileTolerance sets the max difference between the rows flushed of a segment and the rows
+// the channel counted, for statistics updates still in flight when the segment is flushed.
+func WithFlushReconcileTolerance(rows int64) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.flushReconcileTolerance = rows
+	}
+}
+
+// reconcileFlushedRowsWithoutLock compares the rows flushed of a growing segment being flushed with the rows
+// counted, a mismatch beyond the tolerance means data loss or a double write and marks the segment.
+// Caller shall hold the segMu.
+func (c *ChannelMeta) reconcileFlushedRowsWithoutLock(seg *Segment, flushedRows int64) {
+	if flushedRows < 0 || !seg.notFlushed() {
+		return
+	}
+	diff := flushedRows - seg.numRows
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff <= c.flushReconcileTolerance {
+		return
+	}
+	seg.reconcileFailed = true
+	metrics.DataNodeFlushReconcileFailureCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Inc()
+	log.Error("flushed rows mismatch rows counted",
+		zap.Int64("segmentID", seg.segmentID),
+		zap.Int64("flushedRows", flushedRows),
+		zap.Int64("countedRows", seg.numRows),
+		zap.Int64("tolerance", c.flushReconcileTolerance),
+		zap.String("channel", c.channelName))
+}
+
+// addFlushedRows adds rows written to the insert binlogs of the segment by a flush, and returns the rows
+// written by all the flushes of it, or unknownFlushedRows if the segment is not in the channel.
+func (c *ChannelMeta) addFlushedRows(segID UniqueID, rows int64) int64 {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segID]
+	if !ok {
+		return unknownFlushedRows
+	}
+	seg.flushedRows += rows
+	return seg.flushedRows
+}
+
+// insertLogRows returns the rows of a flush written to the insert binlogs, every field binlog carries all of them.
+func insertLogRows(insertLogs map[UniqueID]*datapb.Binlog) int64 {
+	var rows int64
+	for _, binlog := range insertLogs {
+		if binlog.GetEntriesNum() > rows {
+			rows = binlog.GetEntriesNum()
+		}
+	}
+	return rows
+}
+
+// tallyFlushedRows adds the rows of flushes to the segment in the channel and returns the rows of all its flushes
+// for segmentFlushed, or unknownFlushedRows if the channel predates addFlushedRows.
+func tallyFlushedRows(channel Channel, segID UniqueID, rows int64) int64 {
+	if channel.InterfaceVersion() < channelVersionCumulativeFlushedRows {
+		return unknownFlushedRows
+	}
+	return channel.addFlushedRows(segID, rows)
+}
+
+// listUnreconciledSegments returns the IDs of the segments whose flushed rows mismatch the rows counted.
+func (c *ChannelMeta) listUnreconciledSegments() []UniqueID {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	var segIDs []UniqueID
+	for segID, seg := range c.segments {
+		if seg.reconcileFailed {
+			segIDs = append(segIDs, segID)
+		}
+	}
+	sort.Slice(segIDs, func(i, j int) bool { return segIDs[i] < segIDs[j] })
+	return segIDs
+}
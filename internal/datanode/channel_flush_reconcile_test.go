@@ -0,0 +1,57 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_reconcileFlushedRows(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil,
+		WithFlushReconcileTolerance(5))
+	for _, segID := range []UniqueID{1, 2, 3, 4} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}))
+		channel.updateStatistics(segID, 100)
+	}
+
+	// match
+	channel.segmentFlushed(1, 100)
+	// within tolerance
+	channel.segmentFlushed(2, 95)
+	// mismatch
+	channel.segmentFlushed(3, 110)
+	// unknown
+	channel.segmentFlushed(4, unknownFlushedRows)
+	assert.Equal(t, []UniqueID{3}, channel.listUnreconciledSegments())
+	for _, segID := range []UniqueID{1, 2, 3, 4} {
+		assert.False(t, channel.segments[segID].notFlushed())
+	}
+
+	// flushing a flushed segment again does not reconcile
+	channel.segmentFlushed(1, 0)
+	assert.Equal(t, []UniqueID{3}, channel.listUnreconciledSegments())
+}
@@ -0,0 +1,135 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// flushNotifyTimeout bounds a single call of the FlushNotifier.
+const flushNotifyTimeout = 10 * time.Second
+
+// FlushNotifier is notified when a segment is accepted for force flush, so the coordinator
+// stops assigning new rows to it.
+type FlushNotifier interface {
+	NotifySeal(ctx context.Context, segmentID UniqueID) error
+}
+
+// WithFlushNotifier sets the notifier called, asynchronously, for each accepted force flush request.
+func WithFlushNotifier(notifier FlushNotifier) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.flushNotifier = notifier
+	}
+}
+
+// FlushRequest is a pending force flush of a growing segment and everyone who asked for it.
+type FlushRequest struct {
+	SegmentID   UniqueID
+	Requesters  []string
+	RequestedAt time.Time
+}
+
+// requestFlush records a force flush request of a growing segment on behalf of requester.
+// Only the first request of a segment is accepted, later ones until the segment is flushed
+// only join the requester set, so the flusher flushes each segment once however many ask for it.
+// Segments are not sealed by the request, the insert buffer keeps accepting rows until the flusher runs.
+func (c *ChannelMeta) requestFlush(segID UniqueID, requester string) (accepted bool, err error) {
+	if requester == "" {
+		return false, errors.New("empty flush requester")
+	}
+
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		return false, fmt.Errorf("cannot find segment, id = %d", segID)
+	}
+	if !seg.notFlushed() {
+		return false, fmt.Errorf("segment %d is already %s", segID, seg.getType().String())
+	}
+
+	return c.requestFlushWithoutLock(segID, requester), nil
+}
+
+// requestFlushWithoutLock records the force flush request of a growing segment, see requestFlush,
+// caller shall hold the segMu.
+func (c *ChannelMeta) requestFlushWithoutLock(segID UniqueID, requester string) (accepted bool) {
+	if req, ok := c.flushRequests[segID]; ok {
+		for _, r := range req.Requesters {
+			if r == requester {
+				return false
+			}
+		}
+		req.Requesters = append(req.Requesters, requester)
+		return false
+	}
+
+	if c.flushRequests == nil {
+		c.flushRequests = make(map[UniqueID]*FlushRequest)
+	}
+	c.flushRequests[segID] = &FlushRequest{
+		SegmentID:   segID,
+		Requesters:  []string{requester},
+		RequestedAt: c.now(),
+	}
+	log.Info("force flush requested", zap.Int64("segmentID", segID), zap.String("requester", requester))
+	if c.flushNotifier != nil {
+		go c.notifySeal(segID)
+	}
+	return true
+}
+
+// notifySeal calls the FlushNotifier for a segment, failures are only logged.
+func (c *ChannelMeta) notifySeal(segID UniqueID) {
+	ctx, cancel := context.WithTimeout(context.Background(), flushNotifyTimeout)
+	defer cancel()
+	if err := c.flushNotifier.NotifySeal(ctx, segID); err != nil {
+		log.Warn("failed to notify segment seal", zap.Int64("segmentID", segID), zap.Error(err))
+	}
+}
+
+// listPendingFlushRequests returns copies of the flush requests not yet completed by segmentFlushed,
+// oldest first.
+func (c *ChannelMeta) listPendingFlushRequests() []FlushRequest {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	requests := make([]FlushRequest, 0, len(c.flushRequests))
+	for _, req := range c.flushRequests {
+		requests = append(requests, FlushRequest{
+			SegmentID:   req.SegmentID,
+			Requesters:  append([]string(nil), req.Requesters...),
+			RequestedAt: req.RequestedAt,
+		})
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].RequestedAt.Equal(requests[j].RequestedAt) {
+			return requests[i].SegmentID < requests[j].SegmentID
+		}
+		return requests[i].RequestedAt.Before(requests[j].RequestedAt)
+	})
+	return requests
+}
@@ -0,0 +1,131 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_requestFlush(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	now := time.Now()
+	channel.clock = func() time.Time { return now }
+	for _, segID := range []UniqueID{1, 2} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}))
+	}
+
+	t.Run("duplicate requests", func(t *testing.T) {
+		accepted, err := channel.requestFlush(1, "datacoord")
+		require.NoError(t, err)
+		assert.True(t, accepted)
+
+		now = now.Add(time.Second)
+		accepted, err = channel.requestFlush(1, "operator")
+		require.NoError(t, err)
+		assert.False(t, accepted)
+		accepted, err = channel.requestFlush(1, "datacoord")
+		require.NoError(t, err)
+		assert.False(t, accepted)
+
+		accepted, err = channel.requestFlush(2, "operator")
+		require.NoError(t, err)
+		assert.True(t, accepted)
+
+		requests := channel.listPendingFlushRequests()
+		require.Len(t, requests, 2)
+		assert.Equal(t, UniqueID(1), requests[0].SegmentID)
+		assert.Equal(t, []string{"datacoord", "operator"}, requests[0].Requesters)
+		assert.Equal(t, UniqueID(2), requests[1].SegmentID)
+		assert.Equal(t, []string{"operator"}, requests[1].Requesters)
+	})
+
+	t.Run("request after flushed", func(t *testing.T) {
+		channel.segmentFlushed(1, unknownFlushedRows)
+		requests := channel.listPendingFlushRequests()
+		require.Len(t, requests, 1)
+		assert.Equal(t, UniqueID(2), requests[0].SegmentID)
+
+		accepted, err := channel.requestFlush(1, "operator")
+		assert.Error(t, err)
+		assert.False(t, accepted)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, err := channel.requestFlush(100, "operator")
+		assert.Error(t, err)
+		_, err = channel.requestFlush(2, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("removed segment", func(t *testing.T) {
+		channel.removeSegments(2)
+		assert.Empty(t, channel.listPendingFlushRequests())
+	})
+}
+
+type mockFlushNotifier struct {
+	sealed chan UniqueID
+}
+
+func (n *mockFlushNotifier) NotifySeal(ctx context.Context, segmentID UniqueID) error {
+	n.sealed <- segmentID
+	return nil
+}
+
+func TestChannelMeta_flushNotifier(t *testing.T) {
+	notifier := &mockFlushNotifier{sealed: make(chan UniqueID, 10)}
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil,
+		WithFlushNotifier(notifier))
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+	}))
+
+	accepted, err := channel.requestFlush(1, "datacoord")
+	require.NoError(t, err)
+	require.True(t, accepted)
+	select {
+	case segID := <-notifier.sealed:
+		assert.Equal(t, UniqueID(1), segID)
+	case <-time.After(time.Second):
+		t.Fatal("seal not notified")
+	}
+
+	// duplicate requests are not notified again
+	accepted, err = channel.requestFlush(1, "operator")
+	require.NoError(t, err)
+	require.False(t, accepted)
+	select {
+	case segID := <-notifier.sealed:
+		t.Fatalf("unexpected notification of segment %d", segID)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,88 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxRowCountSamples is the number of latest row counts kept per segment.
+const maxRowCountSamples = 16
+
+// rowCountSample is the row count of a segment at a time.
+type rowCountSample struct {
+	Time    time.Time
+	NumRows int64
+}
+
+// rowCountHistory keeps the latest maxRowCountSamples row counts in a ring, guarded by segMu of the channel.
+type rowCountHistory struct {
+	ring  [maxRowCountSamples]rowCountSample
+	total int
+}
+
+func (h *rowCountHistory) record(s rowCountSample) {
+	h.ring[h.total%maxRowCountSamples] = s
+	h.total++
+}
+
+// span returns the oldest and the latest kept samples, ok is false if fewer than two are kept.
+func (h *rowCountHistory) span() (oldest, latest rowCountSample, ok bool) {
+	if h.total < 2 {
+		return oldest, latest, false
+	}
+	first := 0
+	if h.total > maxRowCountSamples {
+		first = h.total - maxRowCountSamples
+	}
+	return h.ring[first%maxRowCountSamples], h.ring[(h.total-1)%maxRowCountSamples], true
+}
+
+// forecastTimeToFull forecasts how long until a growing segment reaches the max row count of a segment,
+// at the insert rate over its latest row counts. It returns ErrInsufficientHistory if fewer than two
+// row counts are recorded, and an error if the segment is not growing by the recorded counts.
+func (c *ChannelMeta) forecastTimeToFull(segID UniqueID) (time.Duration, error) {
+	maxRows, err := c.maxRowCountPerSegment(0)
+	if err != nil {
+		return 0, err
+	}
+
+	c.segMu.RLock()
+	seg, ok := c.segments[segID]
+	if !ok || !seg.notFlushed() {
+		c.segMu.RUnlock()
+		return 0, fmt.Errorf("cannot find growing segment, id = %d", segID)
+	}
+	numRows := seg.numRows
+	oldest, latest, ok := seg.rowHistory.span()
+	c.segMu.RUnlock()
+
+	if !ok {
+		return 0, fmt.Errorf("%w, segment %d", ErrInsufficientHistory, segID)
+	}
+	if numRows >= maxRows {
+		return 0, nil
+	}
+	elapsed := latest.Time.Sub(oldest.Time)
+	if elapsed <= 0 || latest.NumRows <= oldest.NumRows {
+		return 0, fmt.Errorf("segment %d is not growing, %d rows at %v, %d rows at %v", segID,
+			oldest.NumRows, oldest.Time, latest.NumRows, latest.Time)
+	}
+	rate := float64(latest.NumRows-oldest.NumRows) / elapsed.Seconds()
+	return time.Duration(float64(maxRows-numRows) / rate * float64(time.Second)), nil
+}
@@ -0,0 +1,101 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_forecastTimeToFull(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	now := time.Now()
+	channel.clock = func() time.Time { return now }
+	maxRows, err := channel.maxRowCountPerSegment(0)
+	require.NoError(t, err)
+	require.Greater(t, maxRows, int64(1000))
+
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+	}))
+
+	t.Run("insufficient history", func(t *testing.T) {
+		_, err := channel.forecastTimeToFull(1)
+		assert.ErrorIs(t, err, ErrInsufficientHistory)
+
+		channel.updateStatistics(1, 100)
+		_, err = channel.forecastTimeToFull(1)
+		assert.ErrorIs(t, err, ErrInsufficientHistory)
+	})
+
+	t.Run("known rate", func(t *testing.T) {
+		// 100 rows per second
+		for i := 0; i < 10; i++ {
+			now = now.Add(time.Second)
+			channel.updateStatistics(1, 100)
+		}
+		d, err := channel.forecastTimeToFull(1)
+		require.NoError(t, err)
+		expected := time.Duration(float64(maxRows-1100) / 100 * float64(time.Second))
+		assert.InDelta(t, float64(expected), float64(d), float64(time.Millisecond))
+	})
+
+	t.Run("rate over latest samples", func(t *testing.T) {
+		// 1000 rows per second for a full history
+		for i := 0; i < maxRowCountSamples; i++ {
+			now = now.Add(time.Second)
+			channel.updateStatistics(1, 1000)
+		}
+		numRows := channel.segments[1].numRows
+		d, err := channel.forecastTimeToFull(1)
+		require.NoError(t, err)
+		expected := time.Duration(float64(maxRows-numRows) / 1000 * float64(time.Second))
+		assert.InDelta(t, float64(expected), float64(d), float64(time.Millisecond))
+	})
+
+	t.Run("not growing", func(t *testing.T) {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       2,
+			collID:      1,
+			partitionID: 10,
+		}))
+		channel.updateStatistics(2, 0)
+		channel.updateStatistics(2, 0)
+		_, err := channel.forecastTimeToFull(2)
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInsufficientHistory)
+
+		_, err = channel.forecastTimeToFull(3)
+		assert.Error(t, err)
+	})
+
+	t.Run("full segment", func(t *testing.T) {
+		channel.updateStatistics(1, maxRows)
+		d, err := channel.forecastTimeToFull(1)
+		require.NoError(t, err)
+		assert.Zero(t, d)
+	})
+}
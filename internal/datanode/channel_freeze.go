@@ -0,0 +1,68 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// errChannelFrozen stands for a Freeze of a channel already frozen.
+var errChannelFrozen = errors.New("channel already frozen")
+
+// Freeze pauses segment mutations for a consistent snapshot export, addSegment, addSegments, updateStatistics
+// and removeSegments block until Unfreeze while reads proceed. It fails if the channel is already frozen.
+// The caller shall not mutate the channel before Unfreeze, which would block forever.
+func (c *ChannelMeta) Freeze() error {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	if c.frozen {
+		return errChannelFrozen
+	}
+	if c.unfrozen == nil {
+		c.unfrozen = sync.NewCond(&c.segMu)
+	}
+	c.frozen = true
+	log.Info("channel frozen", zap.String("channel", c.channelName))
+	return nil
+}
+
+// Unfreeze resumes the mutations blocked by Freeze, it is a no-op if the channel is not frozen.
+func (c *ChannelMeta) Unfreeze() {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	if !c.frozen {
+		return
+	}
+	c.frozen = false
+	c.unfrozen.Broadcast()
+	log.Info("channel unfrozen", zap.String("channel", c.channelName))
+}
+
+// waitUnfrozenWithoutLock blocks until the channel is not frozen, caller shall hold the write lock of segMu,
+// which is released while waiting.
+func (c *ChannelMeta) waitUnfrozenWithoutLock() {
+	for c.frozen {
+		c.unfrozen.Wait()
+	}
+}
@@ -0,0 +1,82 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestChannelMeta_Freeze(t *testing.T) {
+	newReq := func(segID UniqueID) addSegmentReq {
+		return addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: 10}
+	}
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	require.NoError(t, channel.addSegment(newReq(1)))
+
+	require.NoError(t, channel.Freeze())
+	assert.ErrorIs(t, channel.Freeze(), errChannelFrozen)
+
+	mutations := map[string]func(){
+		"updateStatistics": func() { channel.updateStatistics(1, 10) },
+		"addSegment":       func() { assert.NoError(t, channel.addSegment(newReq(2))) },
+		"removeSegments":   func() { channel.removeSegments(3) },
+	}
+	done := make(chan string, len(mutations))
+	for name, fn := range mutations {
+		name, fn := name, fn
+		go func() {
+			fn()
+			done <- name
+		}()
+	}
+
+	select {
+	case name := <-done:
+		t.Fatalf("%s not blocked by the freeze", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// reads proceed while frozen
+	rows, err := channel.getCollectionRows(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), rows)
+	assert.False(t, channel.hasSegment(2, true))
+
+	channel.Unfreeze()
+	for range mutations {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("mutation not unblocked by Unfreeze")
+		}
+	}
+	rows, err = channel.getCollectionRows(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), rows)
+	assert.True(t, channel.hasSegment(2, true))
+
+	// unfreezing again is a no-op, and the channel can be frozen again
+	channel.Unfreeze()
+	assert.NoError(t, channel.Freeze())
+	channel.Unfreeze()
+}
@@ -0,0 +1,53 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// getGeneration returns the generation of the channel, which increases with every mutation of its segments.
+func (c *ChannelMeta) getGeneration() uint64 {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+	return c.version
+}
+
+// compareAndRemoveSegment removes a segment only if the channel is still at the expected generation,
+// so a decision made on a read of the channel is not applied after a concurrent mutation.
+// Unlike removeSegments, the removal is never debounced.
+func (c *ChannelMeta) compareAndRemoveSegment(segID UniqueID, expectedGen uint64) error {
+	c.segMu.Lock()
+	if c.version != expectedGen {
+		gen := c.version
+		c.segMu.Unlock()
+		return fmt.Errorf("%w, segment %d, expected %d, actual %d", ErrStaleGeneration, segID, expectedGen, gen)
+	}
+	if _, ok := c.segments[segID]; !ok {
+		c.segMu.Unlock()
+		return fmt.Errorf("cannot find segment, id = %d", segID)
+	}
+	c.logOperation(opRemoveSegments, "remove segment at generation",
+		zap.Int64("segmentID", segID), zap.Uint64("generation", expectedGen))
+	events := c.removedEventsWithoutLock(c.removeSegmentsWithoutLock(segID))
+	c.segMu.Unlock()
+
+	c.notifySegmentsRemoved(events)
+	return nil
+}
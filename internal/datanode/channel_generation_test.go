@@ -0,0 +1,67 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_compareAndRemoveSegment(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	gen := channel.getGeneration()
+	for _, segID := range []UniqueID{1, 2} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}))
+	}
+	assert.Greater(t, channel.getGeneration(), gen)
+
+	t.Run("matching generation", func(t *testing.T) {
+		gen := channel.getGeneration()
+		require.NoError(t, channel.compareAndRemoveSegment(1, gen))
+		assert.False(t, channel.hasSegment(1, true))
+		assert.Greater(t, channel.getGeneration(), gen)
+	})
+
+	t.Run("stale generation", func(t *testing.T) {
+		gen := channel.getGeneration()
+		channel.updateStatistics(2, 10)
+		err := channel.compareAndRemoveSegment(2, gen)
+		assert.ErrorIs(t, err, ErrStaleGeneration)
+		assert.True(t, channel.hasSegment(2, true))
+	})
+
+	t.Run("missing segment", func(t *testing.T) {
+		err := channel.compareAndRemoveSegment(1, channel.getGeneration())
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrStaleGeneration)
+	})
+
+	t.Run("transfer to normal is a mutation", func(t *testing.T) {
+		gen := channel.getGeneration()
+		channel.transferNewSegments([]UniqueID{2})
+		assert.Greater(t, channel.getGeneration(), gen)
+	})
+}
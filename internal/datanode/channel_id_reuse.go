@@ -0,0 +1,63 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+)
+
+// checkSegmentIDReuseWithoutLock refuses seg if its ID is taken by a flushed or dropped (compacted)
+// segment of the channel, which means datacoord reissued the ID, e.g. after losing its allocator state,
+// and adding it would mix unrelated data into the old segment. Re-adding a growing segment is not a reuse.
+// adopt skips the check for controlled migrations re-adopting the ID on purpose.
+func (c *ChannelMeta) checkSegmentIDReuseWithoutLock(seg *Segment, adopt bool) error {
+	existing, ok := c.segments[seg.segmentID]
+	if !ok || existing.notFlushed() {
+		return nil
+	}
+	state := metrics.FlushedSegmentLabel
+	if !existing.isValid() {
+		state = metrics.DropedSegmentLabel
+	}
+	if adopt {
+		log.Warn("adopt reused segment ID",
+			zap.Int64("segmentID", seg.segmentID),
+			zap.String("existing state", state))
+		return nil
+	}
+	metrics.DataNodeSegmentIDReuseCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), state).Inc()
+	log.Error("refuse to add segment reusing the ID of an existing segment",
+		zap.Int64("segmentID", seg.segmentID),
+		zap.String("existing state", state),
+		zap.Int64("existing collectionID", existing.getCollectionID()),
+		zap.Int64("existing partitionID", existing.getPartitionID()),
+		zap.Time("existing createTime", existing.allocationTime),
+		zap.Int64("collectionID", seg.getCollectionID()),
+		zap.Int64("partitionID", seg.getPartitionID()),
+		zap.Time("createTime", seg.allocationTime))
+	return fmt.Errorf("%w, id = %d, existing %s segment of collection %d partition %d created at %s, new %s segment of collection %d partition %d created at %s",
+		ErrSegmentIDReused, seg.segmentID,
+		state, existing.getCollectionID(), existing.getPartitionID(), existing.allocationTime.Format(time.RFC3339),
+		seg.getType(), seg.getCollectionID(), seg.getPartitionID(), seg.allocationTime.Format(time.RFC3339))
+}
@@ -0,0 +1,101 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_segmentIDReuse(t *testing.T) {
+	reuseCount := func(state string) float64 {
+		return testutil.ToFloat64(metrics.DataNodeSegmentIDReuseCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), state))
+	}
+	newReuseTestChannel := func(t *testing.T) *ChannelMeta {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		for segID, segType := range map[UniqueID]datapb.SegmentType{
+			1: datapb.SegmentType_Flushed,
+			2: datapb.SegmentType_Flushed,
+			3: datapb.SegmentType_Normal,
+		} {
+			require.NoError(t, channel.addSegment(addSegmentReq{
+				segType:     segType,
+				segID:       segID,
+				collID:      1,
+				partitionID: 10,
+				numOfRows:   100,
+			}))
+		}
+		// segment 2 is compacted into 4 and dropped
+		target := &Segment{segmentID: 4, collectionID: 1, partitionID: 10, numRows: 100}
+		target.setType(datapb.SegmentType_Flushed)
+		require.NoError(t, channel.mergeFlushedSegments(target, 100, []UniqueID{2}))
+		return channel
+	}
+	reused := func(segID UniqueID) addSegmentReq {
+		return addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: 11}
+	}
+
+	tests := []struct {
+		description string
+		segID       UniqueID
+		state       string
+	}{
+		{"flushed", 1, metrics.FlushedSegmentLabel},
+		{"dropped", 2, metrics.DropedSegmentLabel},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			channel := newReuseTestChannel(t)
+			before := reuseCount(test.state)
+
+			err := channel.addSegment(reused(test.segID))
+			assert.ErrorIs(t, err, ErrSegmentIDReused)
+			assert.Contains(t, err.Error(), fmt.Sprintf("existing %s segment of collection 1 partition 10", test.state))
+			assert.Contains(t, err.Error(), "new New segment of collection 1 partition 11")
+			assert.Equal(t, before+1, reuseCount(test.state))
+			// the existing segment is untouched
+			assert.Equal(t, UniqueID(10), channel.segments[test.segID].partitionID)
+			assert.Equal(t, int64(100), channel.segments[test.segID].numRows)
+
+			_, err = channel.addSegments([]addSegmentReq{reused(test.segID)})
+			assert.ErrorIs(t, err, ErrSegmentIDReused)
+			assert.Equal(t, before+2, reuseCount(test.state))
+
+			req := reused(test.segID)
+			req.adoptReusedID = true
+			require.NoError(t, channel.addSegment(req))
+			assert.Equal(t, UniqueID(11), channel.segments[test.segID].partitionID)
+			assert.True(t, channel.segments[test.segID].notFlushed())
+			assert.Equal(t, before+2, reuseCount(test.state))
+		})
+	}
+
+	t.Run("active", func(t *testing.T) {
+		channel := newReuseTestChannel(t)
+		require.NoError(t, channel.addSegment(reused(3)))
+		assert.Equal(t, UniqueID(11), channel.segments[3].partitionID)
+	})
+}
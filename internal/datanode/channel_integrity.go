@@ -0,0 +1,181 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+)
+
+// ClockSkewReport reports two segments of the same partition whose start positions go backwards,
+// i.e. the latter allocated segment starts earlier than the former one.
+type ClockSkewReport struct {
+	PartitionID   UniqueID
+	FormerSegment UniqueID
+	LatterSegment UniqueID
+	SkewMs        int64
+}
+
+// PositionGap reports a segment whose end position is further than the threshold from its start position,
+// which indicates messages of the channel were skipped.
+type PositionGap struct {
+	SegmentID UniqueID `json:"segment_id"`
+	Channel   string   `json:"channel"`
+	GapMs     int64    `json:"gap_ms"`
+}
+
+// defaultMaxPositionGapMs is the threshold of DetectPositionGaps run by integrityCheck.
+const defaultMaxPositionGapMs = int64(time.Hour / time.Millisecond)
+
+// IntegrityReport collects the problems found by integrityCheck.
+type IntegrityReport struct {
+	ClockSkews   []ClockSkewReport
+	PositionGaps []PositionGap
+}
+
+// isEmpty returns whether no problem is found.
+func (r *IntegrityReport) isEmpty() bool {
+	return len(r.ClockSkews) == 0 && len(r.PositionGaps) == 0
+}
+
+// integrityCheck runs all the integrity detectors of the collection and logs the problems found.
+func (c *ChannelMeta) integrityCheck(collectionID UniqueID) *IntegrityReport {
+	report := &IntegrityReport{
+		ClockSkews: c.DetectClockSkew(collectionID),
+	}
+	if c.validCollection(collectionID) {
+		report.PositionGaps = c.DetectPositionGaps(defaultMaxPositionGapMs)
+	}
+	if !report.isEmpty() {
+		log.Warn("channel integrity check found problems",
+			zap.Int64("collectionID", collectionID),
+			zap.String("channel", c.channelName),
+			zap.Any("clockSkews", report.ClockSkews),
+			zap.Any("positionGaps", report.PositionGaps))
+	}
+	return report
+}
+
+// DetectClockSkew reports the consecutive segments (ordered by segmentID) in a partition
+// whose start position timestamps decrease, which breaks the ordering of recovery.
+func (c *ChannelMeta) DetectClockSkew(collectionID UniqueID) []ClockSkewReport {
+	if !c.validCollection(collectionID) {
+		return nil
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	partSegs := make(map[UniqueID][]*Segment)
+	for _, seg := range c.segments {
+		if seg.isValid() && seg.hasStartPosition() {
+			partSegs[seg.getPartitionID()] = append(partSegs[seg.getPartitionID()], seg)
+		}
+	}
+
+	var reports []ClockSkewReport
+	for partID, segs := range partSegs {
+		sort.Slice(segs, func(i, j int) bool { return segs[i].segmentID < segs[j].segmentID })
+		for i := 1; i < len(segs); i++ {
+			former, latter := segs[i-1], segs[i]
+			if latter.startPos.GetTimestamp() < former.startPos.GetTimestamp() {
+				reports = append(reports, ClockSkewReport{
+					PartitionID:   partID,
+					FormerSegment: former.segmentID,
+					LatterSegment: latter.segmentID,
+					SkewMs:        tsoutil.CalculateDuration(former.startPos.GetTimestamp(), latter.startPos.GetTimestamp()),
+				})
+			}
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].FormerSegment < reports[j].FormerSegment })
+	return reports
+}
+
+// DetectPositionGaps reports the valid segments whose end position is more than maxGapMs after their
+// start position, ordered by segment ID. Non-positive maxGapMs reports nothing.
+func (c *ChannelMeta) DetectPositionGaps(maxGapMs int64) []PositionGap {
+	if maxGapMs <= 0 {
+		return nil
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	var gaps []PositionGap
+	for _, seg := range c.segments {
+		if !seg.isValid() || !seg.hasStartPosition() || !seg.hasEndPosition() {
+			continue
+		}
+		gapMs := tsoutil.CalculateDuration(seg.endPos.GetTimestamp(), seg.startPos.GetTimestamp())
+		if gapMs > maxGapMs {
+			gaps = append(gaps, PositionGap{
+				SegmentID: seg.segmentID,
+				Channel:   seg.startPos.GetChannelName(),
+				GapMs:     gapMs,
+			})
+		}
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].SegmentID < gaps[j].SegmentID })
+	return gaps
+}
+
+// validateInvariants checks every segment is owned by the collection of the channel and the partition index
+// agrees with the partition each segment records, i.e. each segment is indexed exactly once, under its own
+// partition. Unlike auditAggregates it only reports, violations are bugs rather than drift to repair.
+func (c *ChannelMeta) validateInvariants() error {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	var violations []string
+	indexed := make(map[UniqueID]int, len(c.segments))
+	for partID, partSegs := range c.partitionSegments {
+		for _, seg := range partSegs {
+			indexed[seg.segmentID]++
+			if seg.getPartitionID() != partID {
+				violations = append(violations, fmt.Sprintf("segment %d of partition %d indexed under partition %d",
+					seg.segmentID, seg.getPartitionID(), partID))
+			}
+			if c.segments[seg.segmentID] != seg {
+				violations = append(violations, fmt.Sprintf("segment %d indexed under partition %d is not in the channel",
+					seg.segmentID, partID))
+			}
+		}
+	}
+	for segID, seg := range c.segments {
+		if seg.getCollectionID() != c.collectionID {
+			violations = append(violations, fmt.Sprintf("segment %d of collection %d in channel of collection %d",
+				segID, seg.getCollectionID(), c.collectionID))
+		}
+		// ChannelMeta not created by newChannel has no partition index
+		if c.partitionSegments != nil && indexed[segID] != 1 {
+			violations = append(violations, fmt.Sprintf("segment %d indexed %d times", segID, indexed[segID]))
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	sort.Strings(violations)
+	return fmt.Errorf("channel %s invariants violated: %s", c.channelName, strings.Join(violations, "; "))
+}
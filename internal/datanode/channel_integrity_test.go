@@ -0,0 +1,167 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/common"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+)
+
+func TestChannelMeta_DetectClockSkew(t *testing.T) {
+	base := time.Now()
+	ts := func(offset time.Duration) Timestamp {
+		return tsoutil.ComposeTSByTime(base.Add(offset), 0)
+	}
+
+	channel := &ChannelMeta{collectionID: 1, segments: make(map[UniqueID]*Segment)}
+	segs := []struct {
+		segID  UniqueID
+		partID UniqueID
+		start  Timestamp
+	}{
+		// partition 10 goes backwards by 300ms between segment 2 and 3
+		{1, 10, ts(0)},
+		{2, 10, ts(time.Second)},
+		{3, 10, ts(700 * time.Millisecond)},
+		// partition 20 is monotonic, interleaved segmentIDs across partitions do not matter
+		{4, 20, ts(0)},
+		{5, 20, ts(time.Second)},
+		// segment without start position is ignored
+		{6, 20, 0},
+	}
+	for _, seg := range segs {
+		s := Segment{segmentID: seg.segID, partitionID: seg.partID, startPos: &internalpb.MsgPosition{Timestamp: seg.start}}
+		s.setType(datapb.SegmentType_Normal)
+		channel.segments[seg.segID] = &s
+	}
+
+	reports := channel.DetectClockSkew(1)
+	assert.Equal(t, []ClockSkewReport{
+		{PartitionID: 10, FormerSegment: 2, LatterSegment: 3, SkewMs: 300},
+	}, reports)
+
+	assert.Empty(t, channel.DetectClockSkew(2))
+
+	report := channel.integrityCheck(1)
+	assert.False(t, report.isEmpty())
+	assert.Equal(t, reports, report.ClockSkews)
+
+	channel.segments[3].startPos.Timestamp = ts(2 * time.Second)
+	assert.True(t, channel.integrityCheck(1).isEmpty())
+}
+
+func TestChannelMeta_DetectPositionGaps(t *testing.T) {
+	base := time.Now()
+	pos := func(offset time.Duration) *internalpb.MsgPosition {
+		return &internalpb.MsgPosition{ChannelName: "by-dev-dml-0", Timestamp: tsoutil.ComposeTSByTime(base.Add(offset), 0)}
+	}
+
+	channel := &ChannelMeta{collectionID: 1, segments: make(map[UniqueID]*Segment)}
+	segs := []struct {
+		segID UniqueID
+		start *internalpb.MsgPosition
+		end   *internalpb.MsgPosition
+	}{
+		{1, pos(0), pos(time.Second)},
+		// gapped
+		{2, pos(0), pos(2 * time.Hour)},
+		{3, pos(time.Minute), pos(time.Minute + 90*time.Minute)},
+		// without end position, or without start position, is ignored,
+		// start positions never go backwards so that no clock skew is reported along
+		{4, pos(2 * time.Minute), nil},
+		{5, nil, pos(3 * time.Hour)},
+	}
+	for _, seg := range segs {
+		s := Segment{segmentID: seg.segID, partitionID: 10, startPos: seg.start, endPos: seg.end}
+		s.setType(datapb.SegmentType_Normal)
+		channel.segments[seg.segID] = &s
+	}
+
+	gaps := channel.DetectPositionGaps(defaultMaxPositionGapMs)
+	assert.Equal(t, []PositionGap{
+		{SegmentID: 2, Channel: "by-dev-dml-0", GapMs: 2 * time.Hour.Milliseconds()},
+		{SegmentID: 3, Channel: "by-dev-dml-0", GapMs: 90 * time.Minute.Milliseconds()},
+	}, gaps)
+	assert.Len(t, channel.DetectPositionGaps(500), 3)
+	assert.Empty(t, channel.DetectPositionGaps(0))
+
+	report := channel.integrityCheck(1)
+	assert.False(t, report.isEmpty())
+	assert.Empty(t, report.ClockSkews)
+	assert.Equal(t, gaps, report.PositionGaps)
+	assert.Empty(t, channel.integrityCheck(2).PositionGaps)
+
+	// a dropped segment is not reported
+	channel.segments[2].setType(datapb.SegmentType_Compacted)
+	channel.segments[3].endPos = pos(2 * time.Minute)
+	assert.True(t, channel.integrityCheck(1).isEmpty())
+}
+
+func TestChannelMeta_validateInvariants(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < 2000; i++ {
+		segID := UniqueID(rnd.Intn(20) + 1)
+		partID := UniqueID(rnd.Intn(4) + 10)
+		switch rnd.Intn(5) {
+		case 0:
+			_ = channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: partID})
+		case 1:
+			channel.removeSegments(segID)
+		case 2:
+			_ = channel.moveSegmentToPartition(segID, partID)
+		case 3:
+			channel.updateStatistics(segID, 1)
+		default:
+			channel.segmentFlushed(segID, unknownFlushedRows)
+		}
+		require.NoError(t, channel.validateInvariants(), "after operation %d", i)
+	}
+
+	t.Run("move", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1, partitionID: 10}))
+		require.NoError(t, channel.moveSegmentToPartition(1, 11))
+		assert.Empty(t, channel.listPartitionSegments(10))
+		assert.Equal(t, []UniqueID{1}, channel.listPartitionSegments(11))
+		assert.NoError(t, channel.moveSegmentToPartition(1, 11))
+		assert.Error(t, channel.moveSegmentToPartition(2, 11))
+		assert.Error(t, channel.moveSegmentToPartition(1, common.InvalidPartitionID))
+	})
+
+	t.Run("violations", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1, partitionID: 10}))
+		// mutations bypassing moveSegmentToPartition
+		channel.segments[1].partitionID = 11
+		channel.segments[1].collectionID = 2
+		err := channel.validateInvariants()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "segment 1 of partition 11 indexed under partition 10")
+		assert.Contains(t, err.Error(), "segment 1 of collection 2 in channel of collection 1")
+	})
+}
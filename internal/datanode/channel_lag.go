@@ -0,0 +1,59 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+)
+
+// getChannelLag returns how far the checkpoint of the channel trails the latest end position of its segments,
+// in physical time of the hybrid timestamps. The checkpoint is the earliest start position of the unflushed
+// segments, so a channel without unflushed segments is caught up. A channel with no data reports zero lag.
+func (c *ChannelMeta) getChannelLag(channelName string) (time.Duration, error) {
+	if channelName == "" {
+		return 0, errors.New("empty channel name")
+	}
+	onChannel := func(pos *internalpb.MsgPosition) bool {
+		name := pos.GetChannelName()
+		return name == channelName || name == "" && c.channelName == channelName
+	}
+
+	c.segMu.RLock()
+	var latest, checkpoint Timestamp
+	for _, seg := range c.segments {
+		if !seg.isValid() {
+			continue
+		}
+		if seg.hasEndPosition() && onChannel(seg.endPos) && seg.endPos.GetTimestamp() > latest {
+			latest = seg.endPos.GetTimestamp()
+		}
+		if seg.notFlushed() && seg.hasStartPosition() && onChannel(seg.startPos) &&
+			(checkpoint == 0 || seg.startPos.GetTimestamp() < checkpoint) {
+			checkpoint = seg.startPos.GetTimestamp()
+		}
+	}
+	c.segMu.RUnlock()
+
+	if latest == 0 || checkpoint == 0 || checkpoint >= latest {
+		return 0, nil
+	}
+	return tsoutil.PhysicalTime(latest).Sub(tsoutil.PhysicalTime(checkpoint)), nil
+}
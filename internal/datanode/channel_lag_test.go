@@ -0,0 +1,83 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_getChannelLag(t *testing.T) {
+	channel := newChannel("ch-0", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	start := time.Now()
+	pos := func(channelName string, after time.Duration) *internalpb.MsgPosition {
+		return &internalpb.MsgPosition{ChannelName: channelName, Timestamp: tsoutil.ComposeTSByTime(start.Add(after), 0)}
+	}
+	lag := func(channelName string) time.Duration {
+		lag, err := channel.getChannelLag(channelName)
+		require.NoError(t, err)
+		return lag
+	}
+
+	t.Run("no data", func(t *testing.T) {
+		assert.Zero(t, lag("ch-0"))
+		_, err := channel.getChannelLag("")
+		assert.Error(t, err)
+	})
+
+	t.Run("growing lag", func(t *testing.T) {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       1,
+			collID:      1,
+			partitionID: 10,
+			startPos:    pos("ch-0", 0),
+		}))
+		assert.Zero(t, lag("ch-0"))
+
+		channel.updateSegmentEndPosition(1, pos("ch-0", 10*time.Second))
+		assert.Equal(t, 10*time.Second, lag("ch-0"))
+		channel.updateSegmentEndPosition(1, pos("ch-0", 30*time.Second))
+		assert.Equal(t, 30*time.Second, lag("ch-0"))
+		assert.Zero(t, lag("ch-1"))
+	})
+
+	t.Run("shrinking lag", func(t *testing.T) {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       2,
+			collID:      1,
+			partitionID: 10,
+			startPos:    pos("ch-0", 25*time.Second),
+			endPos:      pos("ch-0", 40*time.Second),
+		}))
+		assert.Equal(t, 40*time.Second, lag("ch-0"))
+
+		channel.segmentFlushed(1, unknownFlushedRows)
+		assert.Equal(t, 15*time.Second, lag("ch-0"))
+
+		channel.segmentFlushed(2, unknownFlushedRows)
+		assert.Zero(t, lag("ch-0"))
+	})
+}
@@ -0,0 +1,158 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+// defaultLazyLoadTTL is how long a flushed segment stays in memory in lazy-load mode.
+const defaultLazyLoadTTL = 10 * time.Minute
+
+// MetadataStore keeps the metadata of the flushed segments evicted from the channel in lazy-load mode.
+type MetadataStore interface {
+	// Get returns the metadata of the segment, nil without error if it is not stored.
+	Get(segmentID UniqueID) (*datapb.SegmentInfo, error)
+	Put(info *datapb.SegmentInfo) error
+	Delete(segmentID UniqueID) error
+}
+
+// WithLazyLoad enables the lazy-load mode, flushed segments are evicted from memory by evictFlushedSegments
+// once flushed for longer than the TTL, their metadata is put into store and read back by getSegmentByID.
+//
+// An evicted segment leaves every in-memory view of the channel, listings, statistics, the collection rows and
+// the pk statistics used to route deletes included, so only enable it for channels whose flushed segments
+// need no more deletes routed by this node.
+func WithLazyLoad(store MetadataStore) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.metaStore = store
+		c.lazyLoadTTL = defaultLazyLoadTTL
+	}
+}
+
+// flushedSince returns when seg was flushed by its latest transition to Flushed, zero if not recorded.
+func flushedSince(seg *Segment) time.Time {
+	transitions := seg.transitions.list()
+	for i := len(transitions) - 1; i >= 0; i-- {
+		if transitions[i].To == datapb.SegmentType_Flushed {
+			return transitions[i].Time
+		}
+	}
+	return time.Time{}
+}
+
+// evictFlushedSegments moves the segments flushed for longer than the TTL into the metadata store and
+// returns their IDs, nothing is evicted if the lazy-load mode is off.
+func (c *ChannelMeta) evictFlushedSegments() []UniqueID {
+	if c.metaStore == nil {
+		return nil
+	}
+	now := c.now()
+
+	c.segMu.RLock()
+	var candidates []*datapb.SegmentInfo
+	for _, seg := range c.segments {
+		if seg.getType() != datapb.SegmentType_Flushed {
+			continue
+		}
+		if since := flushedSince(seg); !since.IsZero() && now.Sub(since) >= c.lazyLoadTTL {
+			candidates = append(candidates, c.segmentInfo(seg))
+		}
+	}
+	c.segMu.RUnlock()
+
+	// the store is written outside the lock, the segments changed meanwhile are skipped below
+	stored := candidates[:0]
+	for _, info := range candidates {
+		if err := c.metaStore.Put(info); err != nil {
+			log.Warn("failed to store the metadata of flushed segment, keep it in memory",
+				zap.Int64("segmentID", info.GetID()), zap.Error(err))
+			continue
+		}
+		stored = append(stored, info)
+	}
+
+	var evicted []UniqueID
+	c.segMu.Lock()
+	for _, info := range stored {
+		seg, ok := c.segments[info.GetID()]
+		if !ok || seg.getType() != datapb.SegmentType_Flushed || seg.numRows != info.GetNumOfRows() {
+			if err := c.metaStore.Delete(info.GetID()); err != nil {
+				log.Warn("failed to delete the stale metadata of segment", zap.Int64("segmentID", info.GetID()), zap.Error(err))
+			}
+			continue
+		}
+		c.deleteSegmentWithoutLock(seg)
+		evicted = append(evicted, seg.segmentID)
+	}
+	c.segMu.Unlock()
+
+	if len(evicted) > 0 {
+		log.Info("flushed segments evicted to metadata store", zap.String("channel", c.channelName), zap.Int64s("segmentIDs", evicted))
+	}
+	return evicted
+}
+
+// getSegmentByID returns the metadata of a valid segment, from memory or, on a miss in lazy-load mode,
+// from the metadata store. Segments read from the store are not loaded back into memory.
+func (c *ChannelMeta) getSegmentByID(segID UniqueID) (*datapb.SegmentInfo, error) {
+	c.segMu.RLock()
+	if seg, ok := c.segments[segID]; ok && seg.isValid() && !c.collectionDroppedWithoutLock() {
+		info := c.segmentInfo(seg)
+		c.segMu.RUnlock()
+		return info, nil
+	}
+	c.segMu.RUnlock()
+
+	if c.metaStore == nil {
+		return nil, fmt.Errorf("cannot find segment, id = %d", segID)
+	}
+	info, err := c.metaStore.Get(segID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get segment %d from metadata store: %w", segID, markTransient(err))
+	}
+	if info == nil {
+		return nil, fmt.Errorf("cannot find segment, id = %d", segID)
+	}
+	return info, nil
+}
+
+// deleteStoredSegments deletes the metadata of the segments not in memory from the metadata store,
+// caller shall not hold the segMu.
+func (c *ChannelMeta) deleteStoredSegments(segIDs []UniqueID, removed []UniqueID) {
+	if c.metaStore == nil {
+		return
+	}
+	inMemory := make(map[UniqueID]struct{}, len(removed))
+	for _, segID := range removed {
+		inMemory[segID] = struct{}{}
+	}
+	for _, segID := range segIDs {
+		if _, ok := inMemory[segID]; ok {
+			continue
+		}
+		if err := c.metaStore.Delete(segID); err != nil {
+			log.Warn("failed to delete the metadata of evicted segment", zap.Int64("segmentID", segID), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,190 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+type mockMetadataStore struct {
+	mu     sync.Mutex
+	infos  map[UniqueID]*datapb.SegmentInfo
+	gets   int
+	getErr error
+	putErr error
+}
+
+func newMockMetadataStore() *mockMetadataStore {
+	return &mockMetadataStore{infos: make(map[UniqueID]*datapb.SegmentInfo)}
+}
+
+func (s *mockMetadataStore) Get(segmentID UniqueID) (*datapb.SegmentInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets++
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	return s.infos[segmentID], nil
+}
+
+func (s *mockMetadataStore) Put(info *datapb.SegmentInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.putErr != nil {
+		return s.putErr
+	}
+	s.infos[info.GetID()] = proto.Clone(info).(*datapb.SegmentInfo)
+	return nil
+}
+
+func (s *mockMetadataStore) Delete(segmentID UniqueID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.infos, segmentID)
+	return nil
+}
+
+func (s *mockMetadataStore) has(segmentID UniqueID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.infos[segmentID]
+	return ok
+}
+
+func TestChannelMeta_lazyLoad(t *testing.T) {
+	now := time.Now()
+	newLazyChannel := func(store MetadataStore) *ChannelMeta {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil, WithLazyLoad(store))
+		channel.clock = func() time.Time { return now }
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_Flushed, segID: 1, collID: 1, partitionID: 10, numOfRows: 100}))
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 2, collID: 1, partitionID: 10}))
+		return channel
+	}
+
+	t.Run("evicted after ttl", func(t *testing.T) {
+		store := newMockMetadataStore()
+		channel := newLazyChannel(store)
+
+		assert.Empty(t, channel.evictFlushedSegments())
+		channel.clock = func() time.Time { return now.Add(defaultLazyLoadTTL) }
+		assert.Equal(t, []UniqueID{1}, channel.evictFlushedSegments())
+		assert.False(t, channel.hasSegment(1, true))
+		assert.True(t, channel.hasSegment(2, true))
+		require.Contains(t, store.infos, UniqueID(1))
+		assert.Equal(t, int64(100), store.infos[1].GetNumOfRows())
+	})
+
+	t.Run("cache miss reads store", func(t *testing.T) {
+		store := newMockMetadataStore()
+		channel := newLazyChannel(store)
+		channel.clock = func() time.Time { return now.Add(defaultLazyLoadTTL) }
+		channel.evictFlushedSegments()
+
+		info, err := channel.getSegmentByID(2)
+		require.NoError(t, err)
+		assert.Equal(t, UniqueID(2), info.GetID())
+		assert.Equal(t, 0, store.gets)
+
+		info, err = channel.getSegmentByID(1)
+		require.NoError(t, err)
+		assert.Equal(t, UniqueID(1), info.GetID())
+		assert.Equal(t, int64(100), info.GetNumOfRows())
+		assert.Equal(t, "channel", info.GetInsertChannel())
+		assert.Equal(t, 1, store.gets)
+
+		_, err = channel.getSegmentByID(3)
+		assert.Error(t, err)
+
+		store.getErr = errors.New("mock")
+		_, err = channel.getSegmentByID(1)
+		assert.ErrorIs(t, err, store.getErr)
+	})
+
+	t.Run("debounced remove deletes stored", func(t *testing.T) {
+		store := newMockMetadataStore()
+		channel := newLazyChannel(store)
+		channel.removeDebounce = 50 * time.Millisecond
+
+		// evicted while the removal is pending
+		channel.removeSegments(1)
+		channel.clock = func() time.Time { return now.Add(defaultLazyLoadTTL) }
+		require.Equal(t, []UniqueID{1}, channel.evictFlushedSegments())
+		require.True(t, store.has(1))
+
+		assert.Eventually(t, func() bool {
+			return !store.has(1)
+		}, time.Second, 10*time.Millisecond)
+		_, err := channel.getSegmentByID(1)
+		assert.Error(t, err)
+	})
+
+	t.Run("debounced remove of stored only", func(t *testing.T) {
+		store := newMockMetadataStore()
+		channel := newLazyChannel(store)
+		channel.removeDebounce = time.Hour
+		channel.clock = func() time.Time { return now.Add(defaultLazyLoadTTL) }
+		channel.evictFlushedSegments()
+
+		// nothing in memory to debounce, deleted at once
+		channel.removeSegments(1, 2)
+		assert.False(t, store.has(1))
+		assert.True(t, channel.hasSegment(2, true))
+		assert.Contains(t, channel.pendingRemovals, UniqueID(2))
+	})
+
+	t.Run("remove deletes stored", func(t *testing.T) {
+		store := newMockMetadataStore()
+		channel := newLazyChannel(store)
+		channel.clock = func() time.Time { return now.Add(defaultLazyLoadTTL) }
+		channel.evictFlushedSegments()
+
+		channel.removeSegments(1)
+		assert.NotContains(t, store.infos, UniqueID(1))
+		_, err := channel.getSegmentByID(1)
+		assert.Error(t, err)
+	})
+
+	t.Run("put failure keeps segment", func(t *testing.T) {
+		store := newMockMetadataStore()
+		store.putErr = errors.New("mock")
+		channel := newLazyChannel(store)
+		channel.clock = func() time.Time { return now.Add(defaultLazyLoadTTL) }
+
+		assert.Empty(t, channel.evictFlushedSegments())
+		assert.True(t, channel.hasSegment(1, true))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_Flushed, segID: 1, collID: 1, partitionID: 10}))
+		channel.clock = func() time.Time { return time.Now().Add(time.Hour) }
+		assert.Empty(t, channel.evictFlushedSegments())
+		_, err := channel.getSegmentByID(3)
+		assert.Error(t, err)
+	})
+}
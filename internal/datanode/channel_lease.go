@@ -0,0 +1,92 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// WithLeaseOwner enables strict lease mode, updates to segments leased by a holder
+// other than nodeName are refused.
+func WithLeaseOwner(nodeName string) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.leaseOwner = nodeName
+	}
+}
+
+// leaseAllows returns whether this node may update the segment, caller shall hold the segMu.
+func (c *ChannelMeta) leaseAllows(seg *Segment) bool {
+	return c.leaseOwner == "" || seg.leaseHolder == "" || seg.leaseHolder == c.leaseOwner
+}
+
+// setSegmentLease grants the ownership of an unflushed segment to holder until expires.
+// A coordinator calls it again with a new holder to take over the segment.
+func (c *ChannelMeta) setSegmentLease(segID UniqueID, holder string, expires Timestamp) error {
+	if holder == "" {
+		return fmt.Errorf("empty lease holder for segment %d", segID)
+	}
+
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.notFlushed() {
+		return fmt.Errorf("no growing segment %d to lease", segID)
+	}
+	if seg.leaseHolder != holder {
+		log.Info("segment lease holder changed",
+			zap.Int64("segmentID", segID),
+			zap.String("from", seg.leaseHolder),
+			zap.String("to", holder),
+			zap.Uint64("expires", expires))
+	}
+	seg.leaseHolder = holder
+	seg.leaseExpire = expires
+	return nil
+}
+
+// getSegmentLease returns the lease holder and expire time of a segment.
+func (c *ChannelMeta) getSegmentLease(segID UniqueID) (string, Timestamp, error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		return "", 0, fmt.Errorf("error, there's no segment %d", segID)
+	}
+	return seg.leaseHolder, seg.leaseExpire, nil
+}
+
+// getExpiredLeases returns the unflushed segments whose lease expired at now, ordered by segmentID.
+func (c *ChannelMeta) getExpiredLeases(now Timestamp) []UniqueID {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	var segIDs []UniqueID
+	for segID, seg := range c.segments {
+		if seg.notFlushed() && seg.leaseHolder != "" && seg.leaseExpire <= now {
+			segIDs = append(segIDs, segID)
+		}
+	}
+	sort.Slice(segIDs, func(i, j int) bool { return segIDs[i] < segIDs[j] })
+	return segIDs
+}
@@ -0,0 +1,90 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestChannelMeta_segmentLease(t *testing.T) {
+	newLeaseChannel := func(opts ...ChannelMetaOption) *ChannelMeta {
+		channel := &ChannelMeta{segments: make(map[UniqueID]*Segment)}
+		for _, opt := range opts {
+			opt(channel)
+		}
+		for _, segID := range []UniqueID{1, 2} {
+			s := Segment{segmentID: segID}
+			s.setType(datapb.SegmentType_Normal)
+			channel.segments[segID] = &s
+		}
+		flushed := Segment{segmentID: 3}
+		flushed.setType(datapb.SegmentType_Flushed)
+		channel.segments[3] = &flushed
+		return channel
+	}
+
+	t.Run("expiry and takeover", func(t *testing.T) {
+		channel := newLeaseChannel()
+		require.NoError(t, channel.setSegmentLease(1, "node-a", 100))
+		require.NoError(t, channel.setSegmentLease(2, "node-a", 200))
+		assert.Error(t, channel.setSegmentLease(3, "node-a", 100))
+		assert.Error(t, channel.setSegmentLease(4, "node-a", 100))
+		assert.Error(t, channel.setSegmentLease(1, "", 100))
+
+		// fake clock
+		assert.Empty(t, channel.getExpiredLeases(99))
+		assert.Equal(t, []UniqueID{1}, channel.getExpiredLeases(100))
+		assert.Equal(t, []UniqueID{1, 2}, channel.getExpiredLeases(250))
+
+		// node-b takes over segment 1
+		require.NoError(t, channel.setSegmentLease(1, "node-b", 400))
+		assert.Equal(t, []UniqueID{2}, channel.getExpiredLeases(250))
+		holder, expires, err := channel.getSegmentLease(1)
+		assert.NoError(t, err)
+		assert.Equal(t, "node-b", holder)
+		assert.Equal(t, Timestamp(400), expires)
+	})
+
+	t.Run("strict mode refuses updates from other holder", func(t *testing.T) {
+		channel := newLeaseChannel(WithLeaseOwner("node-b"))
+		require.NoError(t, channel.setSegmentLease(1, "node-a", 100))
+		require.NoError(t, channel.setSegmentLease(2, "node-b", 100))
+
+		channel.updateStatistics(1, 10)
+		channel.updateStatistics(2, 10)
+		assert.Equal(t, int64(0), channel.segments[1].numRows)
+		assert.Equal(t, int64(10), channel.segments[2].numRows)
+		assert.Error(t, channel.recordSpill(1, 0, 0))
+
+		// after takeover by node-b, updates are accepted
+		require.NoError(t, channel.setSegmentLease(1, "node-b", 300))
+		channel.updateStatistics(1, 10)
+		assert.Equal(t, int64(10), channel.segments[1].numRows)
+	})
+
+	t.Run("non-strict mode ignores lease holder", func(t *testing.T) {
+		channel := newLeaseChannel()
+		require.NoError(t, channel.setSegmentLease(1, "node-a", 100))
+		channel.updateStatistics(1, 10)
+		assert.Equal(t, int64(10), channel.segments[1].numRows)
+	})
+}
@@ -0,0 +1,76 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// channelOperation names an operation on ChannelMeta whose log level can be set separately.
+type channelOperation string
+
+const (
+	opAddSegment     channelOperation = "addSegment"
+	opUpdateSegment  channelOperation = "updateSegment"
+	opRemoveSegments channelOperation = "removeSegments"
+	opMergeSegments  channelOperation = "mergeSegments"
+)
+
+// defaultOperationLogLevels keeps routine per-segment operations at debug,
+// while operations dropping data are logged at info.
+var defaultOperationLogLevels = map[channelOperation]zapcore.Level{
+	opAddSegment:     zapcore.DebugLevel,
+	opUpdateSegment:  zapcore.DebugLevel,
+	opRemoveSegments: zapcore.InfoLevel,
+	opMergeSegments:  zapcore.InfoLevel,
+}
+
+// WithLogger sets the logger of the channel, log.L() is used by default.
+func WithLogger(logger *zap.Logger) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.logger = logger
+	}
+}
+
+// setLogLevel sets the level operation logs at, it is safe to call concurrently with the operation.
+func (c *ChannelMeta) setLogLevel(op channelOperation, level zapcore.Level) {
+	c.logLevels.Store(op, level)
+}
+
+func (c *ChannelMeta) getLogLevel(op channelOperation) zapcore.Level {
+	if level, ok := c.logLevels.Load(op); ok {
+		return level.(zapcore.Level)
+	}
+	if level, ok := defaultOperationLogLevels[op]; ok {
+		return level
+	}
+	return zapcore.InfoLevel
+}
+
+// logOperation logs msg of op at the level set for op.
+func (c *ChannelMeta) logOperation(op channelOperation, msg string, fields ...zap.Field) {
+	logger := c.logger
+	if logger == nil {
+		logger = log.L()
+	}
+	if ce := logger.WithOptions(zap.AddCallerSkip(1)).Check(c.getLogLevel(op), msg); ce != nil {
+		ce.Write(append(fields, zap.String("operation", string(op)))...)
+	}
+}
@@ -0,0 +1,62 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestChannelMeta_logLevel(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil,
+		WithLogger(zap.New(core)))
+
+	levelOf := func(msg string) []zapcore.Level {
+		var levels []zapcore.Level
+		for _, entry := range logs.FilterMessage(msg).All() {
+			levels = append(levels, entry.Level)
+		}
+		return levels
+	}
+
+	require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1}))
+	channel.removeSegments(1)
+	assert.Equal(t, []zapcore.Level{zapcore.DebugLevel}, levelOf("adding segment"))
+	assert.Equal(t, []zapcore.Level{zapcore.InfoLevel}, levelOf("remove segments if exist"))
+
+	t.Run("setLogLevel", func(t *testing.T) {
+		logs.TakeAll()
+		channel.setLogLevel(opAddSegment, zapcore.WarnLevel)
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 2, collID: 1}))
+		assert.Equal(t, []zapcore.Level{zapcore.WarnLevel}, levelOf("adding segment"))
+
+		// levels below the logger level are dropped
+		core, logs := observer.New(zapcore.InfoLevel)
+		channel.logger = zap.New(core)
+		channel.setLogLevel(opAddSegment, zapcore.DebugLevel)
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 3, collID: 1}))
+		assert.Zero(t, logs.FilterMessage("adding segment").Len())
+	})
+}
@@ -19,9 +19,11 @@ package datanode
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/milvus-io/milvus-proto/go-api/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/schemapb"
 	"github.com/milvus-io/milvus/internal/common"
 	"github.com/milvus-io/milvus/internal/log"
@@ -31,6 +33,7 @@ import (
 	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/milvus-io/milvus/internal/types"
 	"github.com/milvus-io/milvus/internal/util/paramtable"
+	"github.com/milvus-io/milvus/internal/util/ratelimitutil"
 	"github.com/milvus-io/milvus/internal/util/typeutil"
 	"go.uber.org/zap"
 )
@@ -68,10 +71,34 @@ type Channel interface {
 	listCompactedSegmentIDs() map[UniqueID][]UniqueID
 
 	updateStatistics(segID UniqueID, numRows int64)
+	recordSpill(segID UniqueID, bytes int64, rows int64) error
 	InitPKstats(ctx context.Context, s *Segment, statsBinlogs []*datapb.FieldBinlog, ts Timestamp) error
 	RollPKstats(segID UniqueID, stats []*storage.PrimaryKeyStats)
 	getSegmentStatisticsUpdates(segID UniqueID) (*datapb.SegmentStats, error)
-	segmentFlushed(segID UniqueID)
+	batchGetSegmentStatisticsUpdates(segIDs []UniqueID) ([]*datapb.SegmentStats, []UniqueID)
+	segmentFlushed(segID UniqueID, flushedRows int64)
+	addFlushedRows(segID UniqueID, rows int64) int64
+	updateBufferedSize(segID UniqueID, bytes int64)
+	addBinlogSize(segID UniqueID, bytes int64)
+	getChannelSafeTimestamp() (Timestamp, bool)
+	isActive(within time.Duration) bool
+
+	// InterfaceVersion returns ChannelInterfaceVersion of the implementation
+	InterfaceVersion() int
+	auditAggregates() []string
+	getCheckpointBlockers(channelName string, topN int) []SegmentView
+	checkPartitionRemovable(partID UniqueID) error
+	detectPartitionSkew(collID UniqueID, skewThreshold float64) ([]PartitionSkewReport, error)
+	getChannelLag(channelName string) (time.Duration, error)
+	getCollectionStats(collID UniqueID) (*CollectionStats, error)
+	capabilities() ReplicaCapabilities
+	updateFieldStatistics(segmentID UniqueID, fieldName string, min, max float64, nullCount int64) error
+	addDeletedRows(segID UniqueID, n int64)
+	DetectPositionGaps(maxGapMs int64) []PositionGap
+	registerBinlogPaths(segID UniqueID, paths []string) error
+	getSegmentByBinlogPath(path string) (*Segment, error)
+	getSegmentsByCreateTimeRange(start, end Timestamp) []SegmentCreation
+	registerListener(filter ListenerFilter, fn func(ev ChannelEvent), opts ...ListenerOption) *ListenerRegistration
 }
 
 // ChannelMeta contains channel meta and the latest segments infos of the channel.
@@ -79,10 +106,102 @@ type ChannelMeta struct {
 	collectionID UniqueID
 	channelName  string
 	collSchema   *schemapb.CollectionSchema
+	shardNum     int // guarded by schemaMut, 0 means not fetched yet
 	schemaMut    sync.RWMutex
+	// schemaCache is derived from collSchema and guarded by schemaMut, see getSchemaCache
+	schemaCache       *schemaCache
+	schemaCacheBuilds int64
+	// defaultPartitionID is the partition never dropped, 0 means unknown
+	defaultPartitionID UniqueID
+	// namespace is the tenant of the collection, segments of other namespaces are refused if set
+	namespace string
+	// schemaProvider supplies the latest schema on refreshCollectionSchema, see channel_schema.go
+	schemaProvider SchemaProvider
+	// acl decides which actors may read and write the collection through asActor, nil allows everyone
+	acl ACLProvider
 
 	segMu    sync.RWMutex
 	segments map[UniqueID]*Segment
+	// version is bumped under segMu by every mutation visible in segment infos, it is the generation of the channel
+	version uint64
+	// partitionSegments indexes segments by partition ID, it is nil for
+	// ChannelMeta not created by newChannel, in which case lookups scan segments.
+	partitionSegments map[UniqueID][]*Segment
+	// channelSegments indexes segment IDs by the channel of their start position, in adding order.
+	channelSegments map[string][]UniqueID
+	// binlogSegments indexes segment IDs by the paths of their binlogs, segmentBinlogs the other way round,
+	// see registerBinlogPaths
+	binlogSegments map[string]UniqueID
+	segmentBinlogs map[UniqueID][]string
+	// collectionRows caches the rows of valid segments
+	collectionRows int64
+	// dirtySegments are segments modified since they were last synced to meta storage
+	dirtySegments map[UniqueID]bool
+	// flushRequests are the pending force flush requests of growing segments, see channel_flush_request.go
+	flushRequests map[UniqueID]*FlushRequest
+	flushNotifier FlushNotifier
+	// maxSegmentRows caps the rows of growing segments if positive, see channel_row_cap.go
+	maxSegmentRows int64
+
+	// segments removed within removeDebounce are kept in pendingRemovals,
+	// an addSegment of the same ID in the window cancels the removal.
+	removeDebounce  time.Duration
+	pendingRemovals map[UniqueID]*time.Timer
+
+	// segmentEvictedHook is called for each segment removed from the channel
+	segmentEvictedHook func(segmentID UniqueID)
+	events             channelEventHub
+	// segmentPurgedHook is called for each segment removed by the purge of the dropped collection
+	segmentPurgedHook func(segmentID, collectionID UniqueID)
+
+	// strictMemorySizeCheck rejects implausible memory size reports instead of only warning
+	strictMemorySizeCheck bool
+	// updateValidation rejects negative row deltas and end positions going backwards
+	updateValidation bool
+	// flushReconcileTolerance is the max difference between flushed and counted rows of a segment
+	flushReconcileTolerance int64
+	// leaseOwner is the name of this node, updates to segments leased by others are refused if set
+	leaseOwner string
+
+	// droppedAt is set when the collection is dropped logically, purged is set once its segments are removed
+	droppedAt time.Time
+	purged    bool
+
+	// memoryBudget is the heap size beyond which new segments are refused
+	memoryBudget uint64
+	// ageBase is the time segment age is measured from
+	ageBase SegmentAgeBase
+	// vchannels are the channel names segment positions may reference, empty means not validated
+	vchannels []string
+	// flushedUpdatePolicy decides what to do with statistics updates arriving after the segment is flushed,
+	// postFlushDeltas holds the redirected row counts until the flusher takes them
+	flushedUpdatePolicy FlushedUpdatePolicy
+	postFlushDeltas     map[UniqueID]int64
+
+	// lastAdvance is when an end position of the channel last moved forward, by clock
+	lastAdvance time.Time
+	clock       func() time.Time
+	// createdAt is when the channel is created by clock, receivedSegments whether a segment was ever added,
+	// guarded by segMu, see getEmptyCollections
+	createdAt        time.Time
+	receivedSegments bool
+
+	// logger and logLevels control how routine operations are logged, see channel_log.go
+	logger    *zap.Logger
+	logLevels sync.Map
+
+	// addLimiter limits new segments from the insert stream, nil means unlimited
+	addLimiter   *ratelimitutil.Limiter
+	addLimitWait time.Duration
+
+	// metaStore holds the flushed segments evicted after lazyLoadTTL, nil disables the lazy-load mode
+	metaStore   MetadataStore
+	lazyLoadTTL time.Duration
+	// segmentOwners is shared by the channels of the data node to keep segment IDs unique among them
+	segmentOwners *segmentOwners
+	// frozen pauses segment mutations until unfrozen is broadcast, both guarded by segMu, see channel_freeze.go
+	frozen   bool
+	unfrozen *sync.Cond
 
 	metaService  *metaService
 	chunkManager storage.ChunkManager
@@ -90,7 +209,62 @@ type ChannelMeta struct {
 
 var _ Channel = &ChannelMeta{}
 
-func newChannel(channelName string, collID UniqueID, schema *schemapb.CollectionSchema, rc types.RootCoord, cm storage.ChunkManager) *ChannelMeta {
+// ChannelMetaOption is the option to setup ChannelMeta.
+type ChannelMetaOption func(c *ChannelMeta)
+
+// WithRemoveDebounce delays segment removals by window, a remove followed by an add of
+// the same segment ID within the window cancels out. Zero window disables debouncing.
+func WithRemoveDebounce(window time.Duration) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.removeDebounce = window
+	}
+}
+
+// WithNamespace sets the tenant namespace of the collection, addSegment refuses segments of other namespaces.
+func WithNamespace(namespace string) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.namespace = namespace
+	}
+}
+
+// WithShardNum sets the shard number of the collection, which is otherwise fetched from rootcoord.
+func WithShardNum(shardNum int) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.shardNum = shardNum
+	}
+}
+
+// WithVChannels sets the vchannels of the collection, segment positions referencing other channels are rejected.
+func WithVChannels(vchannels ...string) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.vchannels = vchannels
+	}
+}
+
+// WithSegmentEvictedHook sets the hook called synchronously for each segment removed from the channel.
+func WithSegmentEvictedHook(fn func(segmentID UniqueID)) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.segmentEvictedHook = fn
+	}
+}
+
+// WithStrictMemorySizeCheck makes memory size reports below the schema lower bound an error, for test environments.
+func WithStrictMemorySizeCheck() ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.strictMemorySizeCheck = true
+	}
+}
+
+// WithUpdateValidation rejects statistics updates with negative row deltas and end positions going
+// backwards, which come from bugs or out-of-order messages. It is off by default, as end positions
+// legitimately go backwards when the channel replays messages from its checkpoint after recovery.
+func WithUpdateValidation() ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.updateValidation = true
+	}
+}
+
+func newChannel(channelName string, collID UniqueID, schema *schemapb.CollectionSchema, rc types.RootCoord, cm storage.ChunkManager, opts ...ChannelMetaOption) *ChannelMeta {
 	metaService := newMetaService(rc, collID)
 
 	channel := ChannelMeta{
@@ -98,22 +272,37 @@ func newChannel(channelName string, collID UniqueID, schema *schemapb.Collection
 		collSchema:   schema,
 		channelName:  channelName,
 
-		segments: make(map[UniqueID]*Segment),
+		segments:          make(map[UniqueID]*Segment),
+		partitionSegments: make(map[UniqueID][]*Segment),
+		pendingRemovals:   make(map[UniqueID]*time.Timer),
+		clock:             time.Now,
 
 		metaService:  metaService,
 		chunkManager: cm,
 	}
 
+	for _, opt := range opts {
+		opt(&channel)
+	}
+	channel.createdAt = channel.now()
+	collectionLabels.register(collID, schema.GetName())
+
 	return &channel
 }
 
-// segmentFlushed transfers a segment from *New* or *Normal* into *Flushed*.
-func (c *ChannelMeta) segmentFlushed(segID UniqueID) {
+// segmentFlushed transfers a segment from *New* or *Normal* into *Flushed*. flushedRows are the rows
+// written to the insert binlogs of the segment, unknownFlushedRows skips the reconciliation with the rows counted.
+func (c *ChannelMeta) segmentFlushed(segID UniqueID, flushedRows int64) {
 	c.segMu.Lock()
 	defer c.segMu.Unlock()
 
 	if seg, ok := c.segments[segID]; ok {
-		seg.setType(datapb.SegmentType_Flushed)
+		c.reconcileFlushedRowsWithoutLock(seg, flushedRows)
+		c.transitSegment(seg, datapb.SegmentType_Flushed, TransitionFlushed)
+		c.version++
+		c.markDirtyWithoutLock(segID)
+		delete(c.flushRequests, segID)
+		c.events.publish(ChannelEvent{Type: SegmentUpdated, CollectionID: seg.getCollectionID(), SegmentID: segID})
 	}
 	metrics.DataNodeNumUnflushedSegments.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Dec()
 }
@@ -123,7 +312,8 @@ func (c *ChannelMeta) segmentFlushed(segID UniqueID) {
 func (c *ChannelMeta) new2NormalSegment(segID UniqueID) {
 	seg := c.segments[segID]
 	if seg.getType() == datapb.SegmentType_New {
-		seg.setType(datapb.SegmentType_Normal)
+		c.transitSegment(seg, datapb.SegmentType_Normal, TransitionReported)
+		c.version++
 	}
 }
 
@@ -132,11 +322,64 @@ func (c *ChannelMeta) getCollectionAndPartitionID(segID UniqueID) (collID, parti
 	defer c.segMu.RUnlock()
 
 	if seg, ok := c.segments[segID]; ok && seg.isValid() {
-		return seg.collectionID, seg.partitionID, nil
+		return seg.getCollectionID(), seg.getPartitionID(), nil
 	}
 	return 0, 0, fmt.Errorf("cannot find segment, id = %d", segID)
 }
 
+// SegmentParseContext bundles what the insert parser needs for a segment.
+type SegmentParseContext struct {
+	SegmentID    UniqueID
+	CollectionID UniqueID
+	PartitionID  UniqueID
+
+	Schema          *schemapb.CollectionSchema
+	PrimaryKeyField *schemapb.FieldSchema
+	VectorFields    []*schemapb.FieldSchema
+}
+
+// getSegmentParseContext resolves the segment identity together with the latest collection schema,
+// its primary key field and vector fields, both read under the segMu and schemaMut read locks.
+func (c *ChannelMeta) getSegmentParseContext(segID UniqueID) (*SegmentParseContext, error) {
+	for {
+		// the cache is built outside the locks, fetching the schema may call the root coord
+		if _, err := c.getSchemaCache(c.collectionID, 0); err != nil {
+			return nil, err
+		}
+
+		c.segMu.RLock()
+		c.schemaMut.RLock()
+		cache := c.schemaCache
+		if cache == nil || cache.schema != c.collSchema {
+			// the schema is refreshed after the cache is built, build it again
+			c.schemaMut.RUnlock()
+			c.segMu.RUnlock()
+			continue
+		}
+		seg, ok := c.segments[segID]
+		if !ok || !seg.isValid() {
+			c.schemaMut.RUnlock()
+			c.segMu.RUnlock()
+			return nil, fmt.Errorf("cannot find segment, id = %d", segID)
+		}
+		pctx := &SegmentParseContext{
+			SegmentID:       seg.segmentID,
+			CollectionID:    seg.getCollectionID(),
+			PartitionID:     seg.getPartitionID(),
+			Schema:          cache.schema,
+			PrimaryKeyField: cache.pkField,
+			VectorFields:    cache.vectorFields,
+		}
+		c.schemaMut.RUnlock()
+		c.segMu.RUnlock()
+
+		if pctx.PrimaryKeyField == nil {
+			return nil, fmt.Errorf("no primary key field in schema of collection %d", c.collectionID)
+		}
+		return pctx, nil
+	}
+}
+
 func (c *ChannelMeta) getChannelName(segID UniqueID) string {
 	return c.channelName
 }
@@ -158,16 +401,126 @@ func (c *ChannelMeta) maxRowCountPerSegment(ts Timestamp) (int64, error) {
 	return int64(threshold / float64(sizePerRecord)), nil
 }
 
+// minRowSize returns the lower bound of row size summed from fixed-size scalar fields.
+// The bound is unknown and false is returned if the schema has variable-length fields.
+func minRowSize(schema *schemapb.CollectionSchema) (int64, bool) {
+	var size int64
+	for _, field := range schema.GetFields() {
+		switch field.GetDataType() {
+		case schemapb.DataType_Bool, schemapb.DataType_Int8:
+			size++
+		case schemapb.DataType_Int16:
+			size += 2
+		case schemapb.DataType_Int32, schemapb.DataType_Float:
+			size += 4
+		case schemapb.DataType_Int64, schemapb.DataType_Double:
+			size += 8
+		case schemapb.DataType_String, schemapb.DataType_VarChar:
+			return 0, false
+		}
+	}
+	return size, true
+}
+
+// validateMemorySize checks the reported memory size is no less than numRows x minRowSize.
+// Under-reports are logged and counted, and rejected only if strictMemorySizeCheck is enabled.
+// The check uses the loaded schema only and is skipped if the schema is not loaded yet.
+func (c *ChannelMeta) validateMemorySize(segID UniqueID, numRows, memorySize int64) error {
+	c.schemaMut.RLock()
+	schema := c.collSchema
+	c.schemaMut.RUnlock()
+	if schema == nil {
+		return nil
+	}
+
+	rowSize, ok := minRowSize(schema)
+	if !ok || memorySize >= numRows*rowSize {
+		return nil
+	}
+
+	log.Warn("reported memory size is smaller than the lower bound",
+		zap.Int64("segmentID", segID),
+		zap.Int64("numRows", numRows),
+		zap.Int64("memorySize", memorySize),
+		zap.Int64("lowerBound", numRows*rowSize))
+	metrics.DataNodeMemorySizeUnderReportCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Inc()
+	if c.strictMemorySizeCheck {
+		return fmt.Errorf("memory size under reported, segmentID=%d, numRows=%d, memorySize=%d, lowerBound=%d",
+			segID, numRows, memorySize, numRows*rowSize)
+	}
+	return nil
+}
+
 // addSegment adds the segment to current channel. Segments can be added as *new*, *normal* or *flushed*.
 // Make sure to verify `channel.hasSegment(segID)` == false before calling `channel.addSegment()`.
 func (c *ChannelMeta) addSegment(req addSegmentReq) error {
+	seg, err := c.prepareSegment(req)
+	if err != nil || seg == nil {
+		return err
+	}
+
+	c.segMu.Lock()
+	c.waitUnfrozenWithoutLock()
+	if err := c.checkNotPurgedWithoutLock(seg.segmentID); err != nil {
+		c.segMu.Unlock()
+		return err
+	}
+	if err := c.checkSegmentIDReuseWithoutLock(seg, req.adoptReusedID); err != nil {
+		c.segMu.Unlock()
+		return err
+	}
+	if err := c.claimSegmentIDWithoutLock(seg); err != nil {
+		c.segMu.Unlock()
+		return err
+	}
+	c.putSegmentWithoutLock(seg)
+	c.transitSegment(seg, req.segType, TransitionAdded)
+	c.markDirtyWithoutLock(seg.segmentID)
+	ev := c.events.stamp(ChannelEvent{Type: SegmentAdded, CollectionID: req.collID, SegmentID: req.segID})
+	c.segMu.Unlock()
+	c.segmentAdded(req, ev)
+	return nil
+}
+
+// prepareSegment validates req and builds the segment to add with its pk stats, without touching the segments.
+// It returns a nil segment without error if req re-adds a segment pending removal, which is kept instead.
+func (c *ChannelMeta) prepareSegment(req addSegmentReq) (*Segment, error) {
 	if req.collID != c.collectionID {
 		log.Warn("collection mismatch",
 			zap.Int64("current collection ID", req.collID),
 			zap.Int64("expected collection ID", c.collectionID))
-		return fmt.Errorf("mismatch collection, ID=%d", req.collID)
+		return nil, fmt.Errorf("%w, ID=%d", errMismatchCollection, req.collID)
 	}
-	log.Info("adding segment",
+	if c.namespace != "" && req.namespace != c.namespace {
+		log.Warn("namespace mismatch",
+			zap.Int64("segmentID", req.segID),
+			zap.String("segment namespace", req.namespace),
+			zap.String("collection namespace", c.namespace))
+		return nil, fmt.Errorf("%w, segment %d of namespace %q, collection %d of namespace %q",
+			ErrNamespaceMismatch, req.segID, req.namespace, c.collectionID, c.namespace)
+	}
+	if err := c.checkMemoryBudget(req.segID); err != nil {
+		return nil, err
+	}
+	if req.rateLimited() {
+		if err := c.waitAddSegmentRate(req.ctx, req.segID); err != nil {
+			return nil, err
+		}
+	}
+	for _, pos := range []*internalpb.MsgPosition{req.startPos, req.endPos} {
+		if err := c.validatePosition(pos); err != nil {
+			log.Warn("refuse to add segment with invalid position",
+				zap.Int64("segmentID", req.segID), zap.Error(err))
+			return nil, err
+		}
+	}
+	if c.cancelPendingRemoval(req.segID) {
+		log.Info("segment re-added within remove debounce window, keep the existing one",
+			zap.Int64("segmentID", req.segID),
+			zap.String("channel", c.channelName))
+		return nil, nil
+	}
+	c.logOperation(opAddSegment, "adding segment",
 		zap.String("type", req.segType.String()),
 		zap.Int64("segmentID", req.segID),
 		zap.Int64("collectionID", req.collID),
@@ -182,27 +535,41 @@ func (c *ChannelMeta) addSegment(req addSegmentReq) error {
 		collectionID: req.collID,
 		partitionID:  req.partitionID,
 		segmentID:    req.segID,
+		namespace:    req.namespace,
 		numRows:      req.numOfRows, // 0 if segType == NEW
+		flushedRows:  req.numOfRows,
+		binlogSize:   req.binlogSize,
 		startPos:     req.startPos,
 		endPos:       req.endPos,
+
+		allocationTime: c.now(),
 	}
-	seg.sType.Store(req.segType)
+	// the added transition is recorded once the segment is stored, a refused segment has no history
+	seg.setType(req.segType)
 	// Set up pk stats
 	err := c.InitPKstats(context.TODO(), seg, req.statsBinLogs, req.recoverTs)
 	if err != nil {
 		log.Error("failed to init bloom filter",
 			zap.Int64("segment ID", req.segID),
 			zap.Error(err))
-		return err
+		return nil, err
 	}
+	return seg, nil
+}
 
-	c.segMu.Lock()
-	c.segments[req.segID] = seg
-	c.segMu.Unlock()
+// segmentAdded counts a segment added by req and publishes ev stamped on adding, caller shall not hold the segMu.
+func (c *ChannelMeta) segmentAdded(req addSegmentReq, ev ChannelEvent) {
+	c.countSegmentAdded(req)
+	c.events.publish(ev)
+}
+
+// countSegmentAdded counts a segment added by req in the metrics.
+func (c *ChannelMeta) countSegmentAdded(req addSegmentReq) {
 	if req.segType == datapb.SegmentType_New || req.segType == datapb.SegmentType_Normal {
 		metrics.DataNodeNumUnflushedSegments.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Inc()
 	}
-	return nil
+	metrics.DataNodeSegmentAddCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()),
+		metrics.DataNodeCollectionLabels.Value(collectionLabels.label(req.collID))).Inc()
 }
 
 func (c *ChannelMeta) listCompactedSegmentIDs() map[UniqueID][]UniqueID {
@@ -225,10 +592,14 @@ func (c *ChannelMeta) filterSegments(partitionID UniqueID) []*Segment {
 	c.segMu.RLock()
 	defer c.segMu.RUnlock()
 
+	if c.collectionDroppedWithoutLock() {
+		return nil
+	}
+
 	var results []*Segment
 	for _, seg := range c.segments {
 		if seg.isValid() &&
-			partitionID == common.InvalidPartitionID || seg.partitionID == partitionID {
+			partitionID == common.InvalidPartitionID || seg.getPartitionID() == partitionID {
 			results = append(results, seg)
 		}
 	}
@@ -239,7 +610,7 @@ func (c *ChannelMeta) InitPKstats(ctx context.Context, s *Segment, statsBinlogs
 	startTs := time.Now()
 	log := log.With(zap.Int64("segmentID", s.segmentID))
 	log.Info("begin to init pk bloom filter", zap.Int("stats bin logs", len(statsBinlogs)))
-	schema, err := c.getCollectionSchema(s.collectionID, ts)
+	cache, err := c.getSchemaCache(s.getCollectionID(), ts)
 	if err != nil {
 		log.Warn("failed to initPKBloomFilter, get schema return error", zap.Error(err))
 		return err
@@ -247,11 +618,8 @@ func (c *ChannelMeta) InitPKstats(ctx context.Context, s *Segment, statsBinlogs
 
 	// get pkfield id
 	pkField := int64(-1)
-	for _, field := range schema.Fields {
-		if field.IsPrimaryKey {
-			pkField = field.FieldID
-			break
-		}
+	if cache.pkField != nil {
+		pkField = cache.pkField.GetFieldID()
 	}
 
 	// filter stats binlog files which is pk field stats log
@@ -275,7 +643,7 @@ func (c *ChannelMeta) InitPKstats(ctx context.Context, s *Segment, statsBinlogs
 	values, err := c.chunkManager.MultiRead(ctx, bloomFilterFiles)
 	if err != nil {
 		log.Warn("failed to load bloom filter files", zap.Error(err))
-		return err
+		return markTransient(err)
 	}
 	blobs := make([]*Blob, 0)
 	for i := 0; i < len(values); i++ {
@@ -356,6 +724,20 @@ func (c *ChannelMeta) transferNewSegments(segmentIDs []UniqueID) {
 	}
 }
 
+// clearSegmentIsNew transfers a single *New* segment to *Normal* once its start position is acknowledged.
+// getSegmentStatisticsUpdates never clears the *New* state, so stats can be read multiple times before the ack.
+func (c *ChannelMeta) clearSegmentIsNew(segID UniqueID) error {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		return fmt.Errorf("error, there's no segment %d", segID)
+	}
+	c.new2NormalSegment(segID)
+	return nil
+}
+
 // updateSegmentEndPosition updates *New* or *Normal* segment's end position.
 func (c *ChannelMeta) updateSegmentEndPosition(segID UniqueID, endPos *internalpb.MsgPosition) {
 	c.segMu.Lock()
@@ -363,13 +745,62 @@ func (c *ChannelMeta) updateSegmentEndPosition(segID UniqueID, endPos *internalp
 
 	seg, ok := c.segments[segID]
 	if ok && seg.notFlushed() {
+		if !c.leaseAllows(seg) {
+			log.Warn("refuse to update end position of segment leased by other holder",
+				zap.Int64("ID", segID), zap.String("holder", seg.leaseHolder), zap.String("node", c.leaseOwner))
+			return
+		}
+		if err := c.validatePosition(endPos); err != nil {
+			log.Warn("refuse to update end position", zap.Int64("ID", segID), zap.Error(err))
+			return
+		}
+		if err := c.validateEndPositionUpdate(seg, endPos); err != nil {
+			log.Warn("refuse to update end position", zap.Int64("ID", segID), zap.Error(err))
+			return
+		}
+		if endPos.GetTimestamp() > seg.endPos.GetTimestamp() {
+			c.lastAdvance = c.now()
+		}
 		seg.endPos = endPos
+		c.version++
 		return
 	}
 
 	log.Warn("No match segment", zap.Int64("ID", segID))
 }
 
+// validateRowsDelta checks the row count delta of a statistics update is not negative, if update validation is on.
+func (c *ChannelMeta) validateRowsDelta(segID UniqueID, numRows int64) error {
+	if !c.updateValidation || numRows >= 0 {
+		return nil
+	}
+	return fmt.Errorf("negative row count delta %d of segment %d, rows are never removed from a growing segment", numRows, segID)
+}
+
+// validateEndPositionUpdate checks the end position of seg does not go backwards, if update validation is on.
+func (c *ChannelMeta) validateEndPositionUpdate(seg *Segment, endPos *internalpb.MsgPosition) error {
+	if !c.updateValidation || !seg.hasEndPosition() || endPos.GetTimestamp() >= seg.endPos.GetTimestamp() {
+		return nil
+	}
+	return fmt.Errorf("end position of segment %d goes backwards from timestamp %d to %d, out-of-order message",
+		seg.segmentID, seg.endPos.GetTimestamp(), endPos.GetTimestamp())
+}
+
+// validatePosition checks the position references one of the vchannels of collection,
+// positions without channel name are not checked.
+func (c *ChannelMeta) validatePosition(pos *internalpb.MsgPosition) error {
+	if len(c.vchannels) == 0 || pos.GetChannelName() == "" {
+		return nil
+	}
+	for _, vchannel := range c.vchannels {
+		if pos.GetChannelName() == vchannel {
+			return nil
+		}
+	}
+	return fmt.Errorf("position on unknown channel %s, collectionID=%d, vchannels=%v",
+		pos.GetChannelName(), c.collectionID, c.vchannels)
+}
+
 func (c *ChannelMeta) updateSegmentPKRange(segID UniqueID, ids storage.FieldData) {
 	c.segMu.Lock()
 	defer c.segMu.Unlock()
@@ -384,21 +815,136 @@ func (c *ChannelMeta) updateSegmentPKRange(segID UniqueID, ids storage.FieldData
 }
 
 func (c *ChannelMeta) removeSegments(segIDs ...UniqueID) {
+	c.segMu.Lock()
+	c.waitUnfrozenWithoutLock()
+	c.logOperation(opRemoveSegments, "remove segments if exist", zap.Int64s("segmentIDs", segIDs))
+	if c.removeDebounce > 0 {
+		// segments not in memory have no removal to debounce, they are deleted from the store at once
+		var notInMemory []UniqueID
+		for _, segID := range segIDs {
+			if !c.delayRemoval(segID) {
+				notInMemory = append(notInMemory, segID)
+			}
+		}
+		if len(notInMemory) == 0 {
+			c.segMu.Unlock()
+			return
+		}
+		segIDs = notInMemory
+	}
+	removed := c.removeSegmentsWithoutLock(segIDs...)
+	events := c.removedEventsWithoutLock(removed)
+	c.segMu.Unlock()
+
+	c.deleteStoredSegments(segIDs, removed)
+	c.notifySegmentsRemoved(events)
+}
+
+// removedEventsWithoutLock stamps the removed events of segments, caller shall hold the segMu
+// and pass the events to notifySegmentsRemoved.
+func (c *ChannelMeta) removedEventsWithoutLock(segIDs []UniqueID) []ChannelEvent {
+	events := make([]ChannelEvent, 0, len(segIDs))
+	for _, segID := range segIDs {
+		events = append(events, ChannelEvent{Type: SegmentRemoved, CollectionID: c.collectionID, SegmentID: segID})
+	}
+	c.events.stampBatch(events)
+	return events
+}
+
+// notifySegmentsRemoved publishes the removed events as a batch and calls the evicted hook for each removed segment,
+// caller shall not hold the segMu.
+func (c *ChannelMeta) notifySegmentsRemoved(events []ChannelEvent) {
+	c.events.publishBatch(eventBatch{events: events})
+	for _, ev := range events {
+		if c.segmentEvictedHook != nil {
+			c.segmentEvictedHook(ev.SegmentID)
+		}
+	}
+}
+
+// subscribe returns a channel of segment events and the function to unsubscribe.
+// Publishing never blocks channel mutations, events are dropped if the subscriber falls behind.
+func (c *ChannelMeta) subscribe() (<-chan ChannelEvent, func()) {
+	return c.events.subscribe()
+}
+
+// registerListener calls fn with the segment events selected by filter, nil filter selects all.
+// Unlike subscribe no event is dropped, fn is called synchronously by the mutation, possibly with
+// segMu held, so it shall be quick and shall not call the channel. WithBatchListener takes the events
+// of bulk operations in one call, and WithAsyncDispatch moves the calls off the mutation at the cost
+// of dropping events when the listener falls behind.
+func (c *ChannelMeta) registerListener(filter ListenerFilter, fn func(ev ChannelEvent), opts ...ListenerOption) *ListenerRegistration {
+	return c.events.registerListener(filter, fn, opts...)
+}
+
+// delayRemoval schedules the removal of segment after removeDebounce, caller shall hold the segMu.
+// It returns false if the segment is not in memory, which is not scheduled.
+func (c *ChannelMeta) delayRemoval(segID UniqueID) bool {
+	if _, ok := c.segments[segID]; !ok {
+		return false
+	}
+	if _, ok := c.pendingRemovals[segID]; ok {
+		return true
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(c.removeDebounce, func() {
+		c.segMu.Lock()
+		// removal cancelled, or rescheduled by another remove
+		if c.pendingRemovals[segID] != timer {
+			c.segMu.Unlock()
+			return
+		}
+		delete(c.pendingRemovals, segID)
+		removed := c.removeSegmentsWithoutLock(segID)
+		events := c.removedEventsWithoutLock(removed)
+		c.segMu.Unlock()
+
+		// the segment may be evicted to the store while pending
+		c.deleteStoredSegments([]UniqueID{segID}, removed)
+		c.notifySegmentsRemoved(events)
+	})
+	c.pendingRemovals[segID] = timer
+	return true
+}
+
+// cancelPendingRemoval cancels the delayed removal of segment, returns false if there's none.
+func (c *ChannelMeta) cancelPendingRemoval(segID UniqueID) bool {
 	c.segMu.Lock()
 	defer c.segMu.Unlock()
 
-	log.Info("remove segments if exist", zap.Int64s("segmentIDs", segIDs))
+	timer, ok := c.pendingRemovals[segID]
+	if !ok {
+		return false
+	}
+	timer.Stop()
+	delete(c.pendingRemovals, segID)
+	return true
+}
+
+// removeSegmentsWithoutLock removes segments and returns the IDs actually removed, caller shall hold the segMu.
+func (c *ChannelMeta) removeSegmentsWithoutLock(segIDs ...UniqueID) []UniqueID {
 	cnt := 0
+	var removed []UniqueID
 	for _, segID := range segIDs {
+		// evicted segments, see channel_lazy.go, have their binlogs indexed as well
+		c.unregisterBinlogPathsWithoutLock(segID)
 		seg, ok := c.segments[segID]
-		if ok &&
-			(seg.getType() == datapb.SegmentType_New || seg.getType() == datapb.SegmentType_Normal) {
+		if !ok {
+			continue
+		}
+		if seg.getType() == datapb.SegmentType_New || seg.getType() == datapb.SegmentType_Normal {
 			cnt++
 		}
 
-		delete(c.segments, segID)
+		c.deleteSegmentWithoutLock(seg)
+		if c.segmentOwners != nil {
+			c.segmentOwners.release(segID, c.channelName)
+		}
+		removed = append(removed, segID)
 	}
 	metrics.DataNodeNumUnflushedSegments.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Sub(float64(cnt))
+	return removed
 }
 
 // hasSegment checks whether this channel has a segment according to segment ID.
@@ -407,7 +953,7 @@ func (c *ChannelMeta) hasSegment(segID UniqueID, countFlushed bool) bool {
 	defer c.segMu.RUnlock()
 
 	seg, ok := c.segments[segID]
-	if !ok {
+	if !ok || c.collectionDroppedWithoutLock() {
 		return false
 	}
 
@@ -423,18 +969,119 @@ func (c *ChannelMeta) hasSegment(segID UniqueID, countFlushed bool) bool {
 func (c *ChannelMeta) updateStatistics(segID UniqueID, numRows int64) {
 	c.segMu.Lock()
 	defer c.segMu.Unlock()
+	c.waitUnfrozenWithoutLock()
 
-	log.Info("updating segment", zap.Int64("Segment ID", segID), zap.Int64("numRows", numRows))
+	c.logOperation(opUpdateSegment, "updating segment", zap.Int64("Segment ID", segID), zap.Int64("numRows", numRows))
+	if err := c.validateRowsDelta(segID, numRows); err != nil {
+		log.Warn("refuse to update segment", zap.Int64("segID", segID), zap.Error(err))
+		return
+	}
 	seg, ok := c.segments[segID]
 	if ok && seg.notFlushed() {
+		if !c.leaseAllows(seg) {
+			log.Warn("refuse to update segment leased by other holder",
+				zap.Int64("segID", segID), zap.String("holder", seg.leaseHolder), zap.String("node", c.leaseOwner))
+			return
+		}
+		if c.maxSegmentRows > 0 {
+			c.checkSegmentRowCapWithoutLock(seg, numRows)
+		}
 		seg.memorySize = 0
 		seg.numRows += numRows
+		c.collectionRows += numRows
+		now := c.now()
+		seg.rowHistory.record(rowCountSample{Time: now, NumRows: seg.numRows})
+		c.version++
+		c.markDirtyWithoutLock(segID)
+		if seg.numRows > 0 && seg.firstRowTime.IsZero() {
+			seg.firstRowTime = now
+		}
+		metrics.DataNodeSegmentUpdateCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()),
+			metrics.DataNodeCollectionLabels.Value(collectionLabels.label(seg.getCollectionID()))).Inc()
+		c.events.publish(ChannelEvent{Type: SegmentUpdated, CollectionID: seg.getCollectionID(), SegmentID: segID})
+		return
+	}
+	if ok && seg.isValid() && c.flushedUpdatePolicy == RedirectFlushedUpdate {
+		c.redirectFlushedUpdate(segID, numRows)
 		return
 	}
 
 	log.Warn("update segment num row not exist", zap.Int64("segID", segID))
 }
 
+// recordSpill accumulates the spill counters of a growing segment whose insert buffer
+// is synced early because the buffer is full.
+func (c *ChannelMeta) recordSpill(segID UniqueID, bytes int64, rows int64) error {
+	if bytes < 0 || rows < 0 {
+		return fmt.Errorf("invalid spill record, segmentID=%d, bytes=%d, rows=%d", segID, bytes, rows)
+	}
+
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.notFlushed() {
+		return fmt.Errorf("no growing segment %d to record spill", segID)
+	}
+	if !c.leaseAllows(seg) {
+		return fmt.Errorf("segment %d is leased by %s", segID, seg.leaseHolder)
+	}
+	if err := c.validateMemorySize(segID, rows, bytes); err != nil {
+		return err
+	}
+	seg.spillCount++
+	seg.spilledBytes += bytes
+	seg.spilledRows += rows
+	metrics.DataNodeSegmentSpillCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Inc()
+	return nil
+}
+
+// getSegmentSpillStats returns the spill count, spilled bytes and spilled rows of a segment.
+func (c *ChannelMeta) getSegmentSpillStats(segID UniqueID) (count, bytes, rows int64, err error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	if seg, ok := c.segments[segID]; ok && seg.isValid() {
+		return seg.spillCount, seg.spilledBytes, seg.spilledRows, nil
+	}
+	return 0, 0, 0, fmt.Errorf("error, there's no segment %d", segID)
+}
+
+// getFlushCandidates returns the growing segments with rows, the candidates to flush, largest first and then
+// by segment ID. With positive maxSpills the segments spilled more than maxSpills times come after the others,
+// as their data is already synced in small binlogs and flushing them frees little of the insert buffer.
+func (c *ChannelMeta) getFlushCandidates(maxSpills int64) []SegmentView {
+	now := c.now()
+
+	c.segMu.RLock()
+	var candidates []*Segment
+	if !c.collectionDroppedWithoutLock() {
+		for _, seg := range c.segments {
+			if seg.notFlushed() && seg.numRows > 0 {
+				candidates = append(candidates, seg)
+			}
+		}
+	}
+	spilledOften := func(seg *Segment) bool {
+		return maxSpills > 0 && seg.spillCount > maxSpills
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if oi, oj := spilledOften(candidates[i]), spilledOften(candidates[j]); oi != oj {
+			return oj
+		}
+		if candidates[i].numRows != candidates[j].numRows {
+			return candidates[i].numRows > candidates[j].numRows
+		}
+		return candidates[i].segmentID < candidates[j].segmentID
+	})
+	views := make([]SegmentView, 0, len(candidates))
+	for _, seg := range candidates {
+		views = append(views, segmentViewWithoutLock(seg, now))
+	}
+	c.segMu.RUnlock()
+	return views
+}
+
 // getSegmentStatisticsUpdates gives current segment's statistics updates.
 func (c *ChannelMeta) getSegmentStatisticsUpdates(segID UniqueID) (*datapb.SegmentStats, error) {
 	c.segMu.RLock()
@@ -451,6 +1098,76 @@ func (c *ChannelMeta) getCollectionID() UniqueID {
 	return c.collectionID
 }
 
+// isActive returns whether an end position of the channel moved forward within the duration.
+func (c *ChannelMeta) isActive(within time.Duration) bool {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	return !c.lastAdvance.IsZero() && c.now().Sub(c.lastAdvance) <= within
+}
+
+func (c *ChannelMeta) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock()
+}
+
+// getChannelSafeTimestamp returns the max end position timestamp among valid segments, flushed or not,
+// which is the timestamp up to which data of the channel has been accounted.
+// It returns false if no segment has an end position yet.
+func (c *ChannelMeta) getChannelSafeTimestamp() (Timestamp, bool) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	var safeTs Timestamp
+	found := false
+	for _, seg := range c.segments {
+		if seg.isValid() && seg.hasEndPosition() && seg.endPos.GetTimestamp() > safeTs {
+			safeTs = seg.endPos.GetTimestamp()
+			found = true
+		}
+	}
+	return safeTs, found
+}
+
+// mergeSegmentPositions returns the max end position per channel among the segments,
+// segments without end position are skipped.
+func (c *ChannelMeta) mergeSegmentPositions(segmentIDs []UniqueID) (map[string]*internalpb.MsgPosition, error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	positions := make(map[string]*internalpb.MsgPosition)
+	for _, segID := range segmentIDs {
+		seg, ok := c.segments[segID]
+		if !ok || !seg.isValid() {
+			return nil, fmt.Errorf("cannot find segment, id = %d", segID)
+		}
+		if !seg.hasEndPosition() {
+			continue
+		}
+		channel := seg.endPos.GetChannelName()
+		if pos, ok := positions[channel]; !ok || seg.endPos.GetTimestamp() > pos.GetTimestamp() {
+			positions[channel] = seg.endPos
+		}
+	}
+	return positions, nil
+}
+
+// getSegmentChannelTimestamps returns the timestamp of the end position of the segment per channel,
+// finer-grained than the end time of the segment for lag dashboards. It is empty for segments without end position.
+func (c *ChannelMeta) getSegmentChannelTimestamps(segID UniqueID) (map[string]Timestamp, error) {
+	positions, err := c.mergeSegmentPositions([]UniqueID{segID})
+	if err != nil {
+		return nil, err
+	}
+	timestamps := make(map[string]Timestamp, len(positions))
+	for channel, pos := range positions {
+		timestamps[channel] = pos.GetTimestamp()
+	}
+	return timestamps, nil
+}
+
 // getCollectionSchema gets collection schema from rootcoord for a certain timestamp.
 //
 //	If you want the latest collection schema, ts should be 0.
@@ -468,9 +1185,10 @@ func (c *ChannelMeta) getCollectionSchema(collID UniqueID, ts Timestamp) (*schem
 		if c.collSchema == nil {
 			sch, err := c.metaService.getCollectionSchema(context.Background(), collID, ts)
 			if err != nil {
-				return nil, err
+				return nil, markTransient(err)
 			}
 			c.collSchema = sch
+			collectionLabels.register(collID, sch.GetName())
 		}
 	} else {
 		defer c.schemaMut.RUnlock()
@@ -479,6 +1197,49 @@ func (c *ChannelMeta) getCollectionSchema(collID UniqueID, ts Timestamp) (*schem
 	return c.collSchema, nil
 }
 
+// getUserFields returns the user defined fields of the collection schema, in schema order.
+// Field IDs below common.StartOfUserFieldID are reserved for system fields managed by Milvus,
+// such as common.RowIDField and common.TimeStampField, and are left out.
+func (c *ChannelMeta) getUserFields(collID UniqueID) ([]*schemapb.FieldSchema, error) {
+	sch, err := c.getCollectionSchema(collID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]*schemapb.FieldSchema, 0, len(sch.GetFields()))
+	for _, field := range sch.GetFields() {
+		if field.GetFieldID() >= common.StartOfUserFieldID {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// getCollectionShardNum returns the shard (vchannel) number of the collection.
+func (c *ChannelMeta) getCollectionShardNum(collID UniqueID) (int, error) {
+	if !c.validCollection(collID) {
+		return 0, fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+
+	c.schemaMut.RLock()
+	shardNum := c.shardNum
+	c.schemaMut.RUnlock()
+	if shardNum > 0 {
+		return shardNum, nil
+	}
+
+	c.schemaMut.Lock()
+	defer c.schemaMut.Unlock()
+	if c.shardNum == 0 {
+		info, err := c.metaService.getCollectionInfo(context.Background(), collID, 0)
+		if err != nil {
+			return 0, err
+		}
+		c.shardNum = int(info.GetShardsNum())
+	}
+	return c.shardNum, nil
+}
+
 func (c *ChannelMeta) validCollection(collID UniqueID) bool {
 	return collID == c.collectionID
 }
@@ -487,16 +1248,16 @@ func (c *ChannelMeta) mergeFlushedSegments(seg *Segment, planID UniqueID, compac
 
 	log := log.With(
 		zap.Int64("segment ID", seg.segmentID),
-		zap.Int64("collection ID", seg.collectionID),
-		zap.Int64("partition ID", seg.partitionID),
+		zap.Int64("collection ID", seg.getCollectionID()),
+		zap.Int64("partition ID", seg.getPartitionID()),
 		zap.Int64s("compacted from", compactedFrom),
 		zap.Int64("planID", planID),
 		zap.String("channel name", c.channelName))
 
-	if seg.collectionID != c.collectionID {
+	if seg.getCollectionID() != c.collectionID {
 		log.Warn("Mismatch collection",
 			zap.Int64("expected collectionID", c.collectionID))
-		return fmt.Errorf("mismatch collection, ID=%d", seg.collectionID)
+		return fmt.Errorf("%w, ID=%d", errMismatchCollection, seg.getCollectionID())
 	}
 
 	var inValidSegments []UniqueID
@@ -512,14 +1273,23 @@ func (c *ChannelMeta) mergeFlushedSegments(seg *Segment, planID UniqueID, compac
 		return fmt.Errorf("invalid compactedFrom segments: %v", inValidSegments)
 	}
 
-	log.Info("merge flushed segments")
+	c.logOperation(opMergeSegments, "merge flushed segments",
+		zap.Int64("segment ID", seg.segmentID),
+		zap.Int64s("compacted from", compactedFrom),
+		zap.Int64("planID", planID),
+		zap.String("channel name", c.channelName))
 	c.segMu.Lock()
 	defer c.segMu.Unlock()
 	for _, ID := range compactedFrom {
 		// the existent of the segments are already checked
 		s := c.segments[ID]
 		s.compactedTo = seg.segmentID
-		s.setType(datapb.SegmentType_Compacted)
+		if s.isValid() {
+			c.collectionRows -= s.numRows
+		}
+		c.transitSegment(s, datapb.SegmentType_Compacted, TransitionCompacted)
+		c.version++
+		c.markDirtyWithoutLock(ID)
 		// release bloom filter
 		s.currentStat = nil
 		s.historyStats = nil
@@ -527,8 +1297,9 @@ func (c *ChannelMeta) mergeFlushedSegments(seg *Segment, planID UniqueID, compac
 
 	// only store segments with numRows > 0
 	if seg.numRows > 0 {
-		seg.setType(datapb.SegmentType_Flushed)
-		c.segments[seg.segmentID] = seg
+		c.transitSegment(seg, datapb.SegmentType_Flushed, TransitionCompacted)
+		c.putSegmentWithoutLock(seg)
+		c.markDirtyWithoutLock(seg.segmentID)
 	}
 
 	return nil
@@ -540,10 +1311,10 @@ func (c *ChannelMeta) addFlushedSegmentWithPKs(segID, collID, partID UniqueID, n
 		log.Warn("Mismatch collection",
 			zap.Int64("input ID", collID),
 			zap.Int64("expected ID", c.collectionID))
-		return fmt.Errorf("mismatch collection, ID=%d", collID)
+		return fmt.Errorf("%w, ID=%d", errMismatchCollection, collID)
 	}
 
-	log.Info("Add Flushed segment",
+	c.logOperation(opAddSegment, "Add Flushed segment",
 		zap.Int64("segment ID", segID),
 		zap.Int64("collection ID", collID),
 		zap.Int64("partition ID", partID),
@@ -561,7 +1332,8 @@ func (c *ChannelMeta) addFlushedSegmentWithPKs(segID, collID, partID UniqueID, n
 	seg.setType(datapb.SegmentType_Flushed)
 
 	c.segMu.Lock()
-	c.segments[segID] = seg
+	c.putSegmentWithoutLock(seg)
+	c.transitSegment(seg, datapb.SegmentType_Flushed, TransitionAdded)
 	c.segMu.Unlock()
 
 	return nil
@@ -571,6 +1343,10 @@ func (c *ChannelMeta) listAllSegmentIDs() []UniqueID {
 	c.segMu.RLock()
 	defer c.segMu.RUnlock()
 
+	if c.collectionDroppedWithoutLock() {
+		return nil
+	}
+
 	var segIDs []UniqueID
 	for _, seg := range c.segments {
 		if seg.isValid() {
@@ -584,19 +1360,126 @@ func (c *ChannelMeta) listPartitionSegments(partID UniqueID) []UniqueID {
 	c.segMu.RLock()
 	defer c.segMu.RUnlock()
 
+	if c.collectionDroppedWithoutLock() {
+		return nil
+	}
+
 	var segIDs []UniqueID
+	if c.partitionSegments != nil {
+		for _, seg := range c.partitionSegments[partID] {
+			if seg.isValid() {
+				segIDs = append(segIDs, seg.segmentID)
+			}
+		}
+		return segIDs
+	}
+
 	for _, seg := range c.segments {
-		if seg.isValid() && seg.partitionID == partID {
+		if seg.isValid() && seg.getPartitionID() == partID {
 			segIDs = append(segIDs, seg.segmentID)
 		}
 	}
 	return segIDs
 }
 
+// getSegmentIDForChannel returns the ID of the segment whose start position is on channel.
+// When several segments share the channel, the most recently added valid one wins.
+func (c *ChannelMeta) getSegmentIDForChannel(collID UniqueID, channel string) (UniqueID, error) {
+	if !c.validCollection(collID) {
+		return 0, fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+	segIDs := c.channelSegments[channel]
+	for i := len(segIDs) - 1; i >= 0; i-- {
+		if seg, ok := c.segments[segIDs[i]]; ok && seg.isValid() {
+			return seg.segmentID, nil
+		}
+	}
+	return 0, fmt.Errorf("%w, channel=%s", ErrNoSegmentForChannel, channel)
+}
+
+// countedRows returns whether the rows of seg are counted into collectionRows, that is seg is valid.
+// Segments stored without a type, as some callers and tests do, are not counted.
+func countedRows(seg *Segment) bool {
+	t, ok := seg.loadType()
+	return ok && t != datapb.SegmentType_Compacted
+}
+
+// putSegmentWithoutLock stores seg and indexes it by partition and channel, replacing any segment of the same ID.
+func (c *ChannelMeta) putSegmentWithoutLock(seg *Segment) {
+	if old, ok := c.segments[seg.segmentID]; ok {
+		c.deleteSegmentWithoutLock(old)
+	}
+	c.segments[seg.segmentID] = seg
+	c.receivedSegments = true
+	if c.segmentOwners != nil {
+		c.segmentOwners.claim(seg.segmentID, c.channelName)
+	}
+	if countedRows(seg) {
+		c.collectionRows += seg.numRows
+	}
+	c.version++
+	if c.partitionSegments != nil {
+		c.partitionSegments[seg.getPartitionID()] = append(c.partitionSegments[seg.getPartitionID()], seg)
+	}
+	if channel := seg.startPos.GetChannelName(); channel != "" {
+		if c.channelSegments == nil {
+			c.channelSegments = make(map[string][]UniqueID)
+		}
+		c.channelSegments[channel] = append(c.channelSegments[channel], seg.segmentID)
+	}
+}
+
+// deleteSegmentWithoutLock removes seg from segments and the partition and channel indexes.
+func (c *ChannelMeta) deleteSegmentWithoutLock(seg *Segment) {
+	delete(c.segments, seg.segmentID)
+	delete(c.dirtySegments, seg.segmentID)
+	delete(c.flushRequests, seg.segmentID)
+	if countedRows(seg) {
+		c.collectionRows -= seg.numRows
+	}
+	c.version++
+	if channel := seg.startPos.GetChannelName(); channel != "" && c.channelSegments != nil {
+		segIDs := c.channelSegments[channel][:0]
+		for _, segID := range c.channelSegments[channel] {
+			if segID != seg.segmentID {
+				segIDs = append(segIDs, segID)
+			}
+		}
+		c.channelSegments[channel] = segIDs
+		if len(segIDs) == 0 {
+			delete(c.channelSegments, channel)
+		}
+	}
+	if c.partitionSegments == nil {
+		return
+	}
+	partSegs := c.partitionSegments[seg.getPartitionID()]
+	for i, s := range partSegs {
+		if s.segmentID == seg.segmentID {
+			partSegs[i] = partSegs[len(partSegs)-1]
+			partSegs[len(partSegs)-1] = nil
+			partSegs = partSegs[:len(partSegs)-1]
+			break
+		}
+	}
+	if len(partSegs) == 0 {
+		delete(c.partitionSegments, seg.getPartitionID())
+	} else {
+		c.partitionSegments[seg.getPartitionID()] = partSegs
+	}
+}
+
 func (c *ChannelMeta) listNotFlushedSegmentIDs() []UniqueID {
 	c.segMu.RLock()
 	defer c.segMu.RUnlock()
 
+	if c.collectionDroppedWithoutLock() {
+		return nil
+	}
+
 	var segIDs []UniqueID
 	for sID, seg := range c.segments {
 		if seg.notFlushed() {
@@ -606,3 +1489,91 @@ func (c *ChannelMeta) listNotFlushedSegmentIDs() []UniqueID {
 
 	return segIDs
 }
+
+// getSegmentsWithZeroRows returns the sorted IDs of the growing segments of the collection that never got any row,
+// the flusher may drop them rather than flush empty binlogs.
+func (c *ChannelMeta) getSegmentsWithZeroRows(collID UniqueID) []UniqueID {
+	if !c.validCollection(collID) {
+		return nil
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	if c.collectionDroppedWithoutLock() {
+		return nil
+	}
+
+	var segIDs []UniqueID
+	for sID, seg := range c.segments {
+		if seg.notFlushed() && seg.numRows == 0 {
+			segIDs = append(segIDs, sID)
+		}
+	}
+	sort.Slice(segIDs, func(i, j int) bool { return segIDs[i] < segIDs[j] })
+	return segIDs
+}
+
+// getSegmentsSummaryPaged returns a page of valid segments ordered by segmentID, and the total count of them.
+// An offset beyond the end returns an empty page.
+func (c *ChannelMeta) getSegmentsSummaryPaged(offset, limit int) ([]*datapb.SegmentInfo, int, error) {
+	if offset < 0 || limit < 0 {
+		return nil, 0, fmt.Errorf("invalid page, offset=%d, limit=%d", offset, limit)
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	if c.collectionDroppedWithoutLock() {
+		return []*datapb.SegmentInfo{}, 0, nil
+	}
+
+	segs := make([]*Segment, 0, len(c.segments))
+	for _, seg := range c.segments {
+		if seg.isValid() {
+			segs = append(segs, seg)
+		}
+	}
+	sort.Slice(segs, func(i, j int) bool { return segs[i].segmentID < segs[j].segmentID })
+
+	total := len(segs)
+	if offset >= total {
+		return []*datapb.SegmentInfo{}, total, nil
+	}
+	end := total
+	if offset+limit < total {
+		end = offset + limit
+	}
+
+	infos := make([]*datapb.SegmentInfo, 0, end-offset)
+	for _, seg := range segs[offset:end] {
+		infos = append(infos, c.segmentInfo(seg))
+	}
+	return infos, total, nil
+}
+
+// segmentInfo converts a segment into datapb.SegmentInfo, caller shall hold the segMu.
+//
+// Positions never set (nil or zero timestamp) are omitted rather than emitted with timestamp 0,
+// so that downstream never treats them as 1970.
+func (c *ChannelMeta) segmentInfo(seg *Segment) *datapb.SegmentInfo {
+	state := commonpb.SegmentState_Growing
+	if seg.getType() == datapb.SegmentType_Flushed {
+		state = commonpb.SegmentState_Flushed
+	}
+	info := &datapb.SegmentInfo{
+		ID:            seg.segmentID,
+		CollectionID:  seg.getCollectionID(),
+		PartitionID:   seg.getPartitionID(),
+		InsertChannel: c.channelName,
+		NumOfRows:     seg.numRows,
+		State:         state,
+	}
+	if seg.hasStartPosition() {
+		info.StartPosition = seg.startPos
+	}
+	if seg.hasEndPosition() {
+		info.DmlPosition = seg.endPos
+	}
+	return info
+}
@@ -22,9 +22,12 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/bits-and-blooms/bloom/v3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,9 +35,11 @@ import (
 
 	"github.com/milvus-io/milvus-proto/go-api/schemapb"
 	"github.com/milvus-io/milvus/internal/common"
+	"github.com/milvus-io/milvus/internal/metrics"
 	"github.com/milvus-io/milvus/internal/proto/datapb"
 	"github.com/milvus-io/milvus/internal/proto/internalpb"
 	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
 )
 
 var channelMetaNodeTestDir = "/tmp/milvus_test/channel_meta"
@@ -266,7 +271,7 @@ func TestChannelMeta_segmentFlushed(t *testing.T) {
 			// prepare case
 			newSeg(channel, test.inSegType, test.inSegID)
 
-			channel.segmentFlushed(test.inSegID)
+			channel.segmentFlushed(test.inSegID, unknownFlushedRows)
 			flushedSeg, ok := channel.segments[test.inSegID]
 			assert.True(t, ok)
 			assert.Equal(t, test.inSegID, flushedSeg.segmentID)
@@ -787,6 +792,859 @@ func TestChannelMeta_UpdatePKRange(t *testing.T) {
 
 }
 
+func TestChannelMeta_recordSpill(t *testing.T) {
+	channel := &ChannelMeta{segments: make(map[UniqueID]*Segment)}
+	segs := []struct {
+		segID   UniqueID
+		segType datapb.SegmentType
+	}{
+		{1, datapb.SegmentType_New},
+		{2, datapb.SegmentType_Normal},
+		{3, datapb.SegmentType_Flushed},
+		{4, datapb.SegmentType_Compacted},
+	}
+	for _, seg := range segs {
+		s := Segment{segmentID: seg.segID}
+		s.setType(seg.segType)
+		channel.segments[seg.segID] = &s
+	}
+
+	t.Run("growing segments accumulate counters", func(t *testing.T) {
+		for _, segID := range []UniqueID{1, 2} {
+			assert.NoError(t, channel.recordSpill(segID, 1024, 10))
+			assert.NoError(t, channel.recordSpill(segID, 2048, 20))
+
+			count, bytes, rows, err := channel.getSegmentSpillStats(segID)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(2), count)
+			assert.Equal(t, int64(3072), bytes)
+			assert.Equal(t, int64(30), rows)
+		}
+	})
+
+	t.Run("flushed, compacted or missing segments", func(t *testing.T) {
+		for _, segID := range []UniqueID{3, 4, 5} {
+			assert.Error(t, channel.recordSpill(segID, 1024, 10))
+		}
+		count, _, _, err := channel.getSegmentSpillStats(3)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+
+		_, _, _, err = channel.getSegmentSpillStats(4)
+		assert.Error(t, err)
+	})
+
+	t.Run("negative record", func(t *testing.T) {
+		assert.Error(t, channel.recordSpill(1, -1, 10))
+		assert.Error(t, channel.recordSpill(1, 1024, -1))
+	})
+}
+
+func TestChannelMeta_getFlushCandidates(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, seg := range []struct {
+		segID   UniqueID
+		segType datapb.SegmentType
+		rows    int64
+		spills  int
+	}{
+		{1, datapb.SegmentType_New, 100, 5},
+		{2, datapb.SegmentType_New, 50, 0},
+		{3, datapb.SegmentType_Normal, 80, 1},
+		{4, datapb.SegmentType_New, 0, 0},
+		{5, datapb.SegmentType_Flushed, 500, 0},
+		{6, datapb.SegmentType_New, 80, 0},
+	} {
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: seg.segType, segID: seg.segID, collID: 1, partitionID: 10}))
+		channel.segments[seg.segID].numRows = seg.rows
+		for i := 0; i < seg.spills; i++ {
+			require.NoError(t, channel.recordSpill(seg.segID, 1024, 10))
+		}
+	}
+	segIDs := func(views []SegmentView) []UniqueID {
+		ids := make([]UniqueID, 0, len(views))
+		for _, view := range views {
+			ids = append(ids, view.SegmentID)
+		}
+		return ids
+	}
+
+	// largest first, flushed and empty segments are no candidates
+	assert.Equal(t, []UniqueID{1, 3, 6, 2}, segIDs(channel.getFlushCandidates(0)))
+	// segment 1 spilled more than twice goes last
+	candidates := channel.getFlushCandidates(2)
+	assert.Equal(t, []UniqueID{3, 6, 2, 1}, segIDs(candidates))
+	assert.Equal(t, int64(5), candidates[3].SpillCount)
+	// segment 3 spilled no more than once, which is allowed
+	assert.Equal(t, []UniqueID{3, 6, 2, 1}, segIDs(channel.getFlushCandidates(1)))
+
+	require.NoError(t, channel.markCollectionDropped(1))
+	assert.Empty(t, channel.getFlushCandidates(0))
+}
+
+func TestChannelMeta_getSegmentsSummaryPaged(t *testing.T) {
+	channel := &ChannelMeta{
+		channelName: "insert-01",
+		segments:    make(map[UniqueID]*Segment),
+	}
+	for i := 1; i <= 10; i++ {
+		s := Segment{segmentID: UniqueID(i), numRows: int64(i)}
+		s.setType(datapb.SegmentType_Normal)
+		channel.segments[s.segmentID] = &s
+	}
+	compacted := Segment{segmentID: 100}
+	compacted.setType(datapb.SegmentType_Compacted)
+	channel.segments[compacted.segmentID] = &compacted
+
+	ids := func(infos []*datapb.SegmentInfo) []UniqueID {
+		return lo.Map(infos, func(info *datapb.SegmentInfo, _ int) UniqueID { return info.GetID() })
+	}
+
+	tests := []struct {
+		description string
+		offset      int
+		limit       int
+		expected    []UniqueID
+	}{
+		{"first page", 0, 4, []UniqueID{1, 2, 3, 4}},
+		{"middle page", 4, 4, []UniqueID{5, 6, 7, 8}},
+		{"last partial page", 8, 4, []UniqueID{9, 10}},
+		{"past the end", 20, 4, []UniqueID{}},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			infos, total, err := channel.getSegmentsSummaryPaged(test.offset, test.limit)
+			assert.NoError(t, err)
+			assert.Equal(t, 10, total)
+			assert.Equal(t, test.expected, ids(infos))
+			for _, info := range infos {
+				assert.Equal(t, "insert-01", info.GetInsertChannel())
+				assert.Equal(t, info.GetID(), info.GetNumOfRows())
+			}
+		})
+	}
+
+	_, _, err := channel.getSegmentsSummaryPaged(-1, 4)
+	assert.Error(t, err)
+}
+
+func TestChannelMeta_removeDebounce(t *testing.T) {
+	rc := &RootCoordFactory{
+		pkType: schemapb.DataType_Int64,
+	}
+	cm := storage.NewLocalChunkManager(storage.RootPath(channelMetaNodeTestDir))
+	defer cm.RemoveWithPrefix(context.Background(), "")
+
+	window := 50 * time.Millisecond
+	addReq := addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+	}
+
+	t.Run("remove without add", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm, WithRemoveDebounce(window))
+		require.NoError(t, channel.addSegment(addReq))
+
+		channel.removeSegments(1)
+		assert.True(t, channel.hasSegment(1, true))
+		assert.Eventually(t, func() bool {
+			return !channel.hasSegment(1, true)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("remove then add cancels out", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm, WithRemoveDebounce(window))
+		require.NoError(t, channel.addSegment(addReq))
+		channel.updateStatistics(1, 10)
+
+		channel.removeSegments(1)
+		// update arriving within the window applies to the surviving entry
+		channel.updateStatistics(1, 5)
+		require.NoError(t, channel.addSegment(addReq))
+
+		time.Sleep(2 * window)
+		assert.True(t, channel.hasSegment(1, true))
+		assert.Equal(t, int64(15), channel.segments[1].numRows)
+	})
+
+	t.Run("remove again after cancel", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm, WithRemoveDebounce(window))
+		require.NoError(t, channel.addSegment(addReq))
+
+		channel.removeSegments(1)
+		require.NoError(t, channel.addSegment(addReq))
+		channel.removeSegments(1)
+		assert.Eventually(t, func() bool {
+			return !channel.hasSegment(1, true)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("debounce disabled", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm)
+		require.NoError(t, channel.addSegment(addReq))
+
+		channel.removeSegments(1)
+		assert.False(t, channel.hasSegment(1, true))
+	})
+}
+
+func TestChannelMeta_segmentEvictedHook(t *testing.T) {
+	rc := &RootCoordFactory{
+		pkType: schemapb.DataType_Int64,
+	}
+	cm := storage.NewLocalChunkManager(storage.RootPath(channelMetaNodeTestDir))
+	defer cm.RemoveWithPrefix(context.Background(), "")
+
+	evicted := make(map[UniqueID]int)
+	channel := newChannel("channel", 1, nil, rc, cm, WithSegmentEvictedHook(func(segmentID UniqueID) {
+		evicted[segmentID]++
+	}))
+	for _, segID := range []UniqueID{1, 2} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}))
+	}
+
+	channel.removeSegments(1)
+	assert.Equal(t, map[UniqueID]int{1: 1}, evicted)
+
+	// removing again or removing a missing segment does not call the hook
+	channel.removeSegments(1, 3)
+	assert.Equal(t, map[UniqueID]int{1: 1}, evicted)
+
+	channel.removeSegments(2)
+	assert.Equal(t, map[UniqueID]int{1: 1, 2: 1}, evicted)
+}
+
+func TestChannelMeta_clearSegmentIsNew(t *testing.T) {
+	channel := &ChannelMeta{segments: make(map[UniqueID]*Segment)}
+	s := Segment{segmentID: 1, numRows: 10}
+	s.setType(datapb.SegmentType_New)
+	channel.segments[s.segmentID] = &s
+
+	// reading stats multiple times keeps the segment new
+	for i := 0; i < 3; i++ {
+		stats, err := channel.getSegmentStatisticsUpdates(1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), stats.GetNumRows())
+		assert.Equal(t, datapb.SegmentType_New, s.getType())
+	}
+	assert.Equal(t, 1, len(channel.listNewSegmentsStartPositions()))
+
+	assert.NoError(t, channel.clearSegmentIsNew(1))
+	assert.Equal(t, datapb.SegmentType_Normal, s.getType())
+	assert.Empty(t, channel.listNewSegmentsStartPositions())
+
+	// clearing again is a no-op
+	assert.NoError(t, channel.clearSegmentIsNew(1))
+	assert.Equal(t, datapb.SegmentType_Normal, s.getType())
+
+	assert.Error(t, channel.clearSegmentIsNew(2))
+}
+
+func TestChannelMeta_segmentInfoUnsetPositions(t *testing.T) {
+	channel := &ChannelMeta{segments: make(map[UniqueID]*Segment)}
+	unset := Segment{segmentID: 1, startPos: &internalpb.MsgPosition{}}
+	unset.setType(datapb.SegmentType_New)
+	set := Segment{
+		segmentID: 2,
+		startPos:  &internalpb.MsgPosition{Timestamp: 100},
+		endPos:    &internalpb.MsgPosition{Timestamp: 200},
+	}
+	set.setType(datapb.SegmentType_Normal)
+	channel.segments[1] = &unset
+	channel.segments[2] = &set
+
+	infos, _, err := channel.getSegmentsSummaryPaged(0, 10)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(infos))
+
+	assert.Nil(t, infos[0].GetStartPosition())
+	assert.Nil(t, infos[0].GetDmlPosition())
+	assert.Equal(t, Timestamp(100), infos[1].GetStartPosition().GetTimestamp())
+	assert.Equal(t, Timestamp(200), infos[1].GetDmlPosition().GetTimestamp())
+}
+
+func TestSegmentFullLifecycle(t *testing.T) {
+	rc := &RootCoordFactory{
+		pkType: schemapb.DataType_Int64,
+	}
+	cm := storage.NewLocalChunkManager(storage.RootPath(channelMetaNodeTestDir))
+	defer cm.RemoveWithPrefix(context.Background(), "")
+
+	nodeID := fmt.Sprint(paramtable.GetNodeID())
+	unflushed := func() float64 {
+		return testutil.ToFloat64(metrics.DataNodeNumUnflushedSegments.WithLabelValues(nodeID))
+	}
+	spills := func() float64 {
+		return testutil.ToFloat64(metrics.DataNodeSegmentSpillCount.WithLabelValues(nodeID))
+	}
+
+	var evicted []UniqueID
+	channel := newChannel("channel", 1, nil, rc, cm, WithSegmentEvictedHook(func(segmentID UniqueID) {
+		evicted = append(evicted, segmentID)
+	}))
+	baseUnflushed, baseSpills := unflushed(), spills()
+
+	// add
+	err := channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+		startPos:    &internalpb.MsgPosition{ChannelName: "channel", Timestamp: 100},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, baseUnflushed+1, unflushed())
+
+	// update x 100
+	for i := 0; i < 100; i++ {
+		channel.updateStatistics(1, 10)
+		channel.updateSegmentEndPosition(1, &internalpb.MsgPosition{ChannelName: "channel", Timestamp: Timestamp(200 + i)})
+	}
+	require.NoError(t, channel.recordSpill(1, 4096, 500))
+	assert.Equal(t, baseSpills+1, spills())
+
+	stats, err := channel.getSegmentStatisticsUpdates(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), stats.GetNumRows())
+
+	// start position acknowledged, new -> normal
+	require.NoError(t, channel.clearSegmentIsNew(1))
+	assert.Equal(t, datapb.SegmentType_Normal, channel.segments[1].getType())
+	assert.Equal(t, baseUnflushed+1, unflushed())
+
+	// flushed
+	channel.segmentFlushed(1, unknownFlushedRows)
+	assert.True(t, channel.hasSegment(1, true))
+	assert.False(t, channel.hasSegment(1, false))
+	assert.Equal(t, baseUnflushed, unflushed())
+
+	// evicted
+	channel.removeSegments(1)
+	assert.Empty(t, channel.listAllSegmentIDs())
+	assert.Equal(t, []UniqueID{1}, evicted)
+	assert.Equal(t, baseUnflushed, unflushed())
+}
+
+func TestChannelMeta_validateMemorySize(t *testing.T) {
+	fixedSchema := &schemapb.CollectionSchema{
+		Fields: []*schemapb.FieldSchema{
+			{FieldID: 100, DataType: schemapb.DataType_Int64},
+			{FieldID: 101, DataType: schemapb.DataType_Float},
+			{FieldID: 102, DataType: schemapb.DataType_FloatVector},
+		},
+	}
+	varSchema := &schemapb.CollectionSchema{
+		Fields: []*schemapb.FieldSchema{
+			{FieldID: 100, DataType: schemapb.DataType_Int64},
+			{FieldID: 101, DataType: schemapb.DataType_VarChar},
+		},
+	}
+
+	size, ok := minRowSize(fixedSchema)
+	assert.True(t, ok)
+	assert.Equal(t, int64(12), size)
+	_, ok = minRowSize(varSchema)
+	assert.False(t, ok)
+
+	newGrowing := func(schema *schemapb.CollectionSchema, opts ...ChannelMetaOption) *ChannelMeta {
+		channel := &ChannelMeta{collSchema: schema, segments: make(map[UniqueID]*Segment)}
+		for _, opt := range opts {
+			opt(channel)
+		}
+		s := Segment{segmentID: 1}
+		s.setType(datapb.SegmentType_Normal)
+		channel.segments[1] = &s
+		return channel
+	}
+
+	nodeID := fmt.Sprint(paramtable.GetNodeID())
+	underReports := func() float64 {
+		return testutil.ToFloat64(metrics.DataNodeMemorySizeUnderReportCount.WithLabelValues(nodeID))
+	}
+
+	t.Run("under report warns", func(t *testing.T) {
+		channel := newGrowing(fixedSchema)
+		base := underReports()
+		assert.NoError(t, channel.recordSpill(1, 1200, 100))
+		assert.Equal(t, base, underReports())
+		assert.NoError(t, channel.recordSpill(1, 1199, 100))
+		assert.Equal(t, base+1, underReports())
+	})
+
+	t.Run("under report rejected in strict mode", func(t *testing.T) {
+		channel := newGrowing(fixedSchema, WithStrictMemorySizeCheck())
+		assert.Error(t, channel.recordSpill(1, 1199, 100))
+		count, _, _, err := channel.getSegmentSpillStats(1)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("variable-length fields exempted", func(t *testing.T) {
+		channel := newGrowing(varSchema, WithStrictMemorySizeCheck())
+		assert.NoError(t, channel.recordSpill(1, 1, 100))
+	})
+
+	t.Run("schema not loaded", func(t *testing.T) {
+		channel := newGrowing(nil, WithStrictMemorySizeCheck())
+		assert.NoError(t, channel.recordSpill(1, 1, 100))
+	})
+}
+
+func TestChannelMeta_getSegmentParseContext(t *testing.T) {
+	rc := &RootCoordFactory{
+		pkType: schemapb.DataType_Int64,
+	}
+	cm := storage.NewLocalChunkManager(storage.RootPath(channelMetaNodeTestDir))
+	defer cm.RemoveWithPrefix(context.Background(), "")
+
+	collMeta := (&MetaFactory{}).GetCollectionMeta(1, "collection", schemapb.DataType_Int64)
+	channel := newChannel("channel", 1, collMeta.Schema, rc, cm)
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+	}))
+
+	pctx, err := channel.getSegmentParseContext(1)
+	require.NoError(t, err)
+	assert.Equal(t, UniqueID(1), pctx.SegmentID)
+	assert.Equal(t, UniqueID(1), pctx.CollectionID)
+	assert.Equal(t, UniqueID(10), pctx.PartitionID)
+	assert.Same(t, collMeta.Schema, pctx.Schema)
+
+	require.NotNil(t, pctx.PrimaryKeyField)
+	assert.True(t, pctx.PrimaryKeyField.GetIsPrimaryKey())
+	assert.Contains(t, pctx.Schema.GetFields(), pctx.PrimaryKeyField)
+	assert.NotEmpty(t, pctx.VectorFields)
+	for _, field := range pctx.VectorFields {
+		assert.Contains(t, pctx.Schema.GetFields(), field)
+		assert.True(t, field.GetDataType() == schemapb.DataType_FloatVector ||
+			field.GetDataType() == schemapb.DataType_BinaryVector)
+	}
+
+	_, err = channel.getSegmentParseContext(2)
+	assert.Error(t, err)
+
+	noPK := newChannel("channel", 1, &schemapb.CollectionSchema{
+		Fields: []*schemapb.FieldSchema{{FieldID: 100, DataType: schemapb.DataType_Int64}},
+	}, rc, cm)
+	s := Segment{segmentID: 1, collectionID: 1}
+	s.setType(datapb.SegmentType_New)
+	noPK.segments[1] = &s
+	_, err = noPK.getSegmentParseContext(1)
+	assert.Error(t, err)
+}
+
+func TestChannelMeta_getSegmentParseContextRace(t *testing.T) {
+	provider := &fakeSchemaProvider{}
+	channel := newChannel("channel", 1, newSchemaTestSchema(), nil, nil, WithSchemaProvider(provider))
+	addReq := addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1, partitionID: 10}
+	require.NoError(t, channel.addSegment(addReq))
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				pctx, err := channel.getSegmentParseContext(1)
+				if err != nil {
+					continue
+				}
+				// the cached fields are of the schema returned
+				assert.Contains(t, pctx.Schema.GetFields(), pctx.PrimaryKeyField)
+				vectors := 0
+				for _, field := range pctx.Schema.GetFields() {
+					if field.GetDataType() == schemapb.DataType_FloatVector {
+						vectors++
+					}
+				}
+				assert.Len(t, pctx.VectorFields, vectors)
+			}
+		}()
+	}
+
+	fields := []*schemapb.FieldSchema{}
+	for i := 0; i < 50; i++ {
+		fields = append(fields, &schemapb.FieldSchema{FieldID: int64(102 + i), Name: fmt.Sprintf("vec%d", i), DataType: schemapb.DataType_FloatVector})
+		provider.schema = newSchemaTestSchema(fields...)
+		require.NoError(t, channel.refreshCollectionSchema(1))
+		if i%10 == 0 {
+			channel.removeSegments(1)
+			require.NoError(t, channel.addSegment(addReq))
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	pctx, err := channel.getSegmentParseContext(1)
+	require.NoError(t, err)
+	assert.Len(t, pctx.VectorFields, 51)
+}
+
+func TestChannelMeta_partitionIndex(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, seg := range []struct {
+		segID  UniqueID
+		partID UniqueID
+	}{{1, 10}, {2, 10}, {3, 20}} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       seg.segID,
+			collID:      1,
+			partitionID: seg.partID,
+		}))
+	}
+	assert.ElementsMatch(t, []UniqueID{1, 2}, channel.listPartitionSegments(10))
+	assert.ElementsMatch(t, []UniqueID{3}, channel.listPartitionSegments(20))
+
+	// compacted segments are indexed but not listed
+	channel.segmentFlushed(1, unknownFlushedRows)
+	channel.segmentFlushed(2, unknownFlushedRows)
+	merged := &Segment{segmentID: 4, collectionID: 1, partitionID: 10, numRows: 10}
+	require.NoError(t, channel.mergeFlushedSegments(merged, 100, []UniqueID{1, 2}))
+	assert.ElementsMatch(t, []UniqueID{4}, channel.listPartitionSegments(10))
+
+	channel.removeSegments(1, 2, 3)
+	assert.ElementsMatch(t, []UniqueID{4}, channel.listPartitionSegments(10))
+	assert.Empty(t, channel.listPartitionSegments(20))
+	assert.NotContains(t, channel.partitionSegments, UniqueID(20))
+	assert.Len(t, channel.partitionSegments[10], 1)
+}
+
+func TestChannelMeta_getSegmentIDForChannel(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, seg := range []struct {
+		segID   UniqueID
+		channel string
+	}{{1, "ch-0"}, {2, "ch-0"}, {3, "ch-1"}} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:  datapb.SegmentType_New,
+			segID:    seg.segID,
+			collID:   1,
+			startPos: &internalpb.MsgPosition{ChannelName: seg.channel, Timestamp: 100},
+		}))
+	}
+
+	segID, err := channel.getSegmentIDForChannel(1, "ch-0")
+	assert.NoError(t, err)
+	assert.Equal(t, UniqueID(2), segID)
+	segID, err = channel.getSegmentIDForChannel(1, "ch-1")
+	assert.NoError(t, err)
+	assert.Equal(t, UniqueID(3), segID)
+
+	_, err = channel.getSegmentIDForChannel(1, "ch-2")
+	assert.ErrorIs(t, err, ErrNoSegmentForChannel)
+	_, err = channel.getSegmentIDForChannel(2, "ch-0")
+	assert.ErrorIs(t, err, errMismatchCollection)
+
+	// falls back to the previous segment sharing the channel
+	channel.removeSegments(2)
+	segID, err = channel.getSegmentIDForChannel(1, "ch-0")
+	assert.NoError(t, err)
+	assert.Equal(t, UniqueID(1), segID)
+
+	channel.removeSegments(1, 3)
+	_, err = channel.getSegmentIDForChannel(1, "ch-0")
+	assert.ErrorIs(t, err, ErrNoSegmentForChannel)
+	assert.Empty(t, channel.channelSegments)
+
+	// ChannelMeta not created by newChannel has no index to remove from
+	literal := &ChannelMeta{collectionID: 1, segments: make(map[UniqueID]*Segment)}
+	literal.deleteSegmentWithoutLock(&Segment{
+		segmentID:    1,
+		collectionID: 1,
+		startPos:     &internalpb.MsgPosition{ChannelName: "ch-0"},
+	})
+	_, err = literal.getSegmentIDForChannel(1, "ch-0")
+	assert.ErrorIs(t, err, ErrNoSegmentForChannel)
+}
+
+func TestChannelMeta_mergeSegmentPositions(t *testing.T) {
+	channel := newChannel("channel", 1, nil, nil, nil)
+	for _, seg := range []struct {
+		segID   UniqueID
+		channel string
+		ts      Timestamp
+	}{{1, "ch-a", 100}, {2, "ch-a", 200}, {3, "ch-b", 150}, {4, "ch-b", 0}, {5, "ch-c", 300}} {
+		s := &Segment{
+			segmentID:    seg.segID,
+			collectionID: 1,
+			endPos:       &internalpb.MsgPosition{ChannelName: seg.channel, Timestamp: seg.ts},
+		}
+		s.setType(datapb.SegmentType_Normal)
+		channel.putSegmentWithoutLock(s)
+	}
+	timestamps := func(positions map[string]*internalpb.MsgPosition) map[string]Timestamp {
+		result := make(map[string]Timestamp)
+		for ch, pos := range positions {
+			result[ch] = pos.GetTimestamp()
+		}
+		return result
+	}
+
+	tests := []struct {
+		description string
+		segIDs      []UniqueID
+		expected    map[string]Timestamp
+	}{
+		{"overlapping channels", []UniqueID{2, 1}, map[string]Timestamp{"ch-a": 200}},
+		{"disjoint channels", []UniqueID{1, 3}, map[string]Timestamp{"ch-a": 100, "ch-b": 150}},
+		{"partially overlapping", []UniqueID{1, 2, 3, 5}, map[string]Timestamp{"ch-a": 200, "ch-b": 150, "ch-c": 300}},
+		{"no end position", []UniqueID{4}, map[string]Timestamp{}},
+		{"empty", nil, map[string]Timestamp{}},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			positions, err := channel.mergeSegmentPositions(test.segIDs)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, timestamps(positions))
+		})
+	}
+
+	_, err := channel.mergeSegmentPositions([]UniqueID{1, 6})
+	assert.Error(t, err)
+	channel.segments[1].setType(datapb.SegmentType_Compacted)
+	_, err = channel.mergeSegmentPositions([]UniqueID{1})
+	assert.Error(t, err)
+}
+
+func TestChannelMeta_getSegmentChannelTimestamps(t *testing.T) {
+	channel := newChannel("channel", 1, nil, nil, nil)
+	for _, seg := range []struct {
+		segID  UniqueID
+		endPos *internalpb.MsgPosition
+	}{
+		{1, &internalpb.MsgPosition{ChannelName: "ch-a", Timestamp: 100}},
+		{2, &internalpb.MsgPosition{ChannelName: "ch-b", Timestamp: 200}},
+		{3, &internalpb.MsgPosition{ChannelName: "ch-b", Timestamp: 0}},
+		{4, nil},
+	} {
+		s := &Segment{segmentID: seg.segID, collectionID: 1, endPos: seg.endPos}
+		s.setType(datapb.SegmentType_Normal)
+		channel.putSegmentWithoutLock(s)
+	}
+
+	timestamps, err := channel.getSegmentChannelTimestamps(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Timestamp{"ch-a": 100}, timestamps)
+	timestamps, err = channel.getSegmentChannelTimestamps(2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Timestamp{"ch-b": 200}, timestamps)
+
+	for _, segID := range []UniqueID{3, 4} {
+		timestamps, err = channel.getSegmentChannelTimestamps(segID)
+		require.NoError(t, err)
+		assert.NotNil(t, timestamps)
+		assert.Empty(t, timestamps)
+	}
+
+	_, err = channel.getSegmentChannelTimestamps(5)
+	assert.Error(t, err)
+}
+
+func benchmarkListPartitionSegments(b *testing.B, channel *ChannelMeta) {
+	const (
+		numSegments   = 100000
+		numPartitions = 1000
+	)
+	for i := 0; i < numSegments; i++ {
+		seg := &Segment{
+			collectionID: 1,
+			partitionID:  UniqueID(i % numPartitions),
+			segmentID:    UniqueID(i),
+		}
+		seg.setType(datapb.SegmentType_Flushed)
+		channel.putSegmentWithoutLock(seg)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		channel.listPartitionSegments(UniqueID(i % numPartitions))
+	}
+}
+
+func BenchmarkChannelMeta_listPartitionSegments(b *testing.B) {
+	b.Run("indexed", func(b *testing.B) {
+		benchmarkListPartitionSegments(b, newChannel("channel", 1, nil, nil, nil))
+	})
+	b.Run("scan", func(b *testing.B) {
+		benchmarkListPartitionSegments(b, &ChannelMeta{segments: make(map[UniqueID]*Segment)})
+	})
+}
+
+func TestChannelMeta_validatePosition(t *testing.T) {
+	channel := newChannel("by-dev-dml_0_1v0", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil,
+		WithVChannels("by-dev-dml_0_1v0", "by-dev-dml_1_1v1"))
+
+	valid := &internalpb.MsgPosition{ChannelName: "by-dev-dml_1_1v1", Timestamp: 100}
+	unknown := &internalpb.MsgPosition{ChannelName: "by-dev-dml_2_2v0", Timestamp: 200}
+
+	t.Run("addSegment", func(t *testing.T) {
+		assert.NoError(t, channel.addSegment(addSegmentReq{
+			segType:  datapb.SegmentType_New,
+			segID:    1,
+			collID:   1,
+			startPos: valid,
+		}))
+		assert.Error(t, channel.addSegment(addSegmentReq{
+			segType:  datapb.SegmentType_New,
+			segID:    2,
+			collID:   1,
+			startPos: unknown,
+		}))
+		assert.False(t, channel.hasSegment(2, true))
+
+		// positions without channel name are not validated
+		assert.NoError(t, channel.addSegment(addSegmentReq{
+			segType:  datapb.SegmentType_New,
+			segID:    3,
+			collID:   1,
+			startPos: &internalpb.MsgPosition{Timestamp: 100},
+		}))
+	})
+
+	t.Run("updateSegmentEndPosition", func(t *testing.T) {
+		channel.updateSegmentEndPosition(1, valid)
+		assert.Equal(t, valid, channel.segments[1].endPos)
+
+		channel.updateSegmentEndPosition(1, unknown)
+		assert.Equal(t, valid, channel.segments[1].endPos)
+	})
+
+	t.Run("not validated without vchannels", func(t *testing.T) {
+		channel := newChannel("by-dev-dml_0_1v0", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		assert.NoError(t, channel.addSegment(addSegmentReq{
+			segType:  datapb.SegmentType_New,
+			segID:    1,
+			collID:   1,
+			startPos: unknown,
+		}))
+	})
+}
+
+func TestChannelMeta_flushedUpdatePolicy(t *testing.T) {
+	newFlushRaceChannel := func(t *testing.T, opts ...ChannelMetaOption) *ChannelMeta {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil, opts...)
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1}))
+		return channel
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		channel := newFlushRaceChannel(t)
+		channel.updateStatistics(1, 10)
+		channel.segmentFlushed(1, unknownFlushedRows)
+		channel.updateStatistics(1, 5)
+
+		assert.Equal(t, int64(10), channel.segments[1].numRows)
+		assert.Zero(t, channel.takePostFlushDeltas(1))
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		channel := newFlushRaceChannel(t, WithFlushedUpdatePolicy(RedirectFlushedUpdate))
+		channel.updateStatistics(1, 10)
+		channel.segmentFlushed(1, unknownFlushedRows)
+		channel.updateStatistics(1, 5)
+		channel.updateStatistics(1, 3)
+
+		assert.Equal(t, int64(10), channel.segments[1].numRows)
+		assert.Equal(t, int64(8), channel.takePostFlushDeltas(1))
+		assert.Zero(t, channel.takePostFlushDeltas(1))
+
+		// unknown segments are not redirected
+		channel.updateStatistics(2, 5)
+		assert.Zero(t, channel.takePostFlushDeltas(2))
+	})
+
+	t.Run("redirect loses no rows under race", func(t *testing.T) {
+		channel := newFlushRaceChannel(t, WithFlushedUpdatePolicy(RedirectFlushedUpdate))
+		const (
+			workers   = 8
+			perWorker = 100
+		)
+		var wg sync.WaitGroup
+		for i := 0; i < workers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < perWorker; j++ {
+					channel.updateStatistics(1, 1)
+				}
+			}()
+		}
+		channel.segmentFlushed(1, unknownFlushedRows)
+		wg.Wait()
+
+		channel.segMu.RLock()
+		numRows := channel.segments[1].numRows
+		channel.segMu.RUnlock()
+		assert.Equal(t, int64(workers*perWorker), numRows+channel.takePostFlushDeltas(1))
+	})
+}
+
+func TestChannelMeta_getCollectionShardNum(t *testing.T) {
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+
+	t.Run("set by option", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil, WithShardNum(4))
+		shardNum, err := channel.getCollectionShardNum(1)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, shardNum)
+	})
+
+	t.Run("fetched from rootcoord", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil)
+		shardNum, err := channel.getCollectionShardNum(1)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, shardNum)
+	})
+
+	t.Run("missing collection", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil)
+		_, err := channel.getCollectionShardNum(2)
+		assert.True(t, errors.Is(err, errMismatchCollection))
+	})
+
+	t.Run("rootcoord error", func(t *testing.T) {
+		channel := newChannel("channel", -2, nil, &RootCoordFactory{collectionID: -2}, nil)
+		_, err := channel.getCollectionShardNum(-2)
+		assert.Error(t, err)
+	})
+}
+
+func TestChannelMeta_collectionMetrics(t *testing.T) {
+	const collID = 7601
+	nodeID := fmt.Sprint(paramtable.GetNodeID())
+	collLabel := metrics.DataNodeCollectionLabels.Value(fmt.Sprint(collID))
+	addCounter := metrics.DataNodeSegmentAddCount.WithLabelValues(nodeID, collLabel)
+	updateCounter := metrics.DataNodeSegmentUpdateCount.WithLabelValues(nodeID, collLabel)
+	adds, updates := testutil.ToFloat64(addCounter), testutil.ToFloat64(updateCounter)
+
+	channel := newChannel("channel", collID, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: collID}))
+	channel.updateStatistics(1, 10)
+	channel.updateStatistics(1, 10)
+	// updates of unknown segments are not counted
+	channel.updateStatistics(2, 10)
+
+	assert.Equal(t, adds+1, testutil.ToFloat64(addCounter))
+	assert.Equal(t, updates+2, testutil.ToFloat64(updateCounter))
+}
+
 // ChannelMetaSuite setup test suite for ChannelMeta
 type ChannelMetaSuite struct {
 	suite.Suite
@@ -916,3 +1774,129 @@ func (s *ChannelMetaSuite) getSegmentByID(id UniqueID) (*Segment, bool) {
 func TestChannelMetaSuite(t *testing.T) {
 	suite.Run(t, new(ChannelMetaSuite))
 }
+
+func TestChannelMeta_updateValidation(t *testing.T) {
+	newValidationChannel := func(opts ...ChannelMetaOption) *ChannelMeta {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil, opts...)
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType: datapb.SegmentType_New,
+			segID:   1,
+			collID:  1,
+			endPos:  &internalpb.MsgPosition{Timestamp: 200},
+		}))
+		channel.updateStatistics(1, 10)
+		return channel
+	}
+
+	t.Run("negative rows", func(t *testing.T) {
+		channel := newValidationChannel(WithUpdateValidation())
+		err := channel.validateRowsDelta(1, -5)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "negative row count delta -5 of segment 1")
+
+		channel.updateStatistics(1, -5)
+		assert.Equal(t, int64(10), channel.segments[1].numRows)
+		channel.updateStatistics(1, 0)
+		assert.Equal(t, int64(10), channel.segments[1].numRows)
+	})
+
+	t.Run("time regression", func(t *testing.T) {
+		channel := newValidationChannel(WithUpdateValidation())
+		err := channel.validateEndPositionUpdate(channel.segments[1], &internalpb.MsgPosition{Timestamp: 100})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "goes backwards from timestamp 200 to 100")
+
+		channel.updateSegmentEndPosition(1, &internalpb.MsgPosition{Timestamp: 100})
+		assert.Equal(t, Timestamp(200), channel.segments[1].endPos.GetTimestamp())
+		channel.updateSegmentEndPosition(1, &internalpb.MsgPosition{Timestamp: 200})
+		channel.updateSegmentEndPosition(1, &internalpb.MsgPosition{Timestamp: 300})
+		assert.Equal(t, Timestamp(300), channel.segments[1].endPos.GetTimestamp())
+	})
+
+	t.Run("not validated by default", func(t *testing.T) {
+		channel := newValidationChannel()
+		channel.updateStatistics(1, -5)
+		assert.Equal(t, int64(5), channel.segments[1].numRows)
+		channel.updateSegmentEndPosition(1, &internalpb.MsgPosition{Timestamp: 100})
+		assert.Equal(t, Timestamp(100), channel.segments[1].endPos.GetTimestamp())
+	})
+}
+
+func TestChannelMeta_getUserFields(t *testing.T) {
+	channel := newChannel("channel", 1, &schemapb.CollectionSchema{
+		Fields: []*schemapb.FieldSchema{
+			{FieldID: common.RowIDField, Name: common.RowIDFieldName},
+			{FieldID: common.TimeStampField, Name: common.TimeStampFieldName},
+			{FieldID: 100, Name: "pk", IsPrimaryKey: true},
+			{FieldID: 101, Name: "vector"},
+		},
+	}, nil, nil)
+
+	fields, err := channel.getUserFields(1)
+	require.NoError(t, err)
+	require.Len(t, fields, 2)
+	assert.Equal(t, "pk", fields[0].GetName())
+	assert.Equal(t, "vector", fields[1].GetName())
+
+	_, err = channel.getUserFields(2)
+	assert.Error(t, err)
+}
+
+func TestChannelMeta_namespace(t *testing.T) {
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+
+	t.Run("matching namespace", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil, WithNamespace("tenant-a"))
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:   datapb.SegmentType_New,
+			segID:     1,
+			collID:    1,
+			namespace: "tenant-a",
+		}))
+		assert.Equal(t, "tenant-a", channel.segments[1].namespace)
+	})
+
+	t.Run("cross namespace", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil, WithNamespace("tenant-a"))
+		for _, namespace := range []string{"tenant-b", ""} {
+			err := channel.addSegment(addSegmentReq{
+				segType:   datapb.SegmentType_New,
+				segID:     1,
+				collID:    1,
+				namespace: namespace,
+			})
+			assert.ErrorIs(t, err, ErrNamespaceMismatch)
+		}
+		assert.False(t, channel.hasSegment(1, true))
+	})
+
+	t.Run("not enforced", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil)
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:   datapb.SegmentType_New,
+			segID:     1,
+			collID:    1,
+			namespace: "tenant-b",
+		}))
+	})
+}
+
+func TestChannelMeta_getSegmentsWithZeroRows(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	assert.Empty(t, channel.getSegmentsWithZeroRows(1))
+
+	for _, req := range []addSegmentReq{
+		{segType: datapb.SegmentType_New, segID: 3, collID: 1, partitionID: 10},
+		{segType: datapb.SegmentType_New, segID: 1, collID: 1, partitionID: 11},
+		{segType: datapb.SegmentType_New, segID: 2, collID: 1, partitionID: 10},
+		{segType: datapb.SegmentType_Normal, segID: 4, collID: 1, partitionID: 10, numOfRows: 10},
+		// flushed segments are never dropped as empty
+		{segType: datapb.SegmentType_Flushed, segID: 5, collID: 1, partitionID: 10},
+	} {
+		require.NoError(t, channel.addSegment(req))
+	}
+	channel.updateStatistics(2, 5)
+
+	assert.Equal(t, []UniqueID{1, 3}, channel.getSegmentsWithZeroRows(1))
+	assert.Empty(t, channel.getSegmentsWithZeroRows(2))
+}
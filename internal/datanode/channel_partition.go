@@ -0,0 +1,125 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/common"
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// WithDefaultPartition sets the ID of the default partition of the collection. The default partition
+// exists as long as the collection does, it can be resolved by name and is never dropped.
+func WithDefaultPartition(partitionID UniqueID) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.defaultPartitionID = partitionID
+	}
+}
+
+// getPartitionIDByName resolves a partition name of the collection. DataNode only learns the other
+// partitions by ID from the insert stream, so only the default partition can be resolved by name.
+func (c *ChannelMeta) getPartitionIDByName(collID UniqueID, name string) (UniqueID, error) {
+	if !c.validCollection(collID) {
+		return 0, fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+	if name != Params.CommonCfg.DefaultPartitionName || c.defaultPartitionID == 0 {
+		return 0, fmt.Errorf("cannot resolve partition %s of collection %d", name, collID)
+	}
+	return c.defaultPartitionID, nil
+}
+
+// checkPartitionRemovable returns ErrDefaultPartitionNotRemovable for the default partition.
+func (c *ChannelMeta) checkPartitionRemovable(partID UniqueID) error {
+	if c.defaultPartitionID != 0 && partID == c.defaultPartitionID {
+		return fmt.Errorf("%w, collection %d, partition %d", ErrDefaultPartitionNotRemovable, c.collectionID, partID)
+	}
+	return nil
+}
+
+// getCollectionPartitionNum returns the number of partitions of the collection known to the channel,
+// i.e. the partitions with valid segments on the channel and the default partition if set.
+// Partitions without data on the channel are not known to datanode.
+func (c *ChannelMeta) getCollectionPartitionNum(collID UniqueID) (int, error) {
+	if !c.validCollection(collID) {
+		return 0, fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	if c.collectionDroppedWithoutLock() {
+		return 0, nil
+	}
+	partitions := make(map[UniqueID]struct{})
+	if c.defaultPartitionID != 0 {
+		partitions[c.defaultPartitionID] = struct{}{}
+	}
+	for _, seg := range c.segments {
+		if seg.isValid() {
+			partitions[seg.getPartitionID()] = struct{}{}
+		}
+	}
+	return len(partitions), nil
+}
+
+// moveSegmentToPartition moves a valid segment to another partition of the collection, the only change
+// of a segment's owner allowed. The partition index is updated under the same lock, so no aggregate is
+// ever computed with the segment half moved.
+func (c *ChannelMeta) moveSegmentToPartition(segID, partID UniqueID) error {
+	if partID == common.InvalidPartitionID {
+		return fmt.Errorf("invalid partition %d to move segment %d to", partID, segID)
+	}
+
+	c.segMu.Lock()
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() || c.collectionDroppedWithoutLock() {
+		c.segMu.Unlock()
+		return fmt.Errorf("cannot find segment, id = %d", segID)
+	}
+	if seg.getPartitionID() == partID {
+		c.segMu.Unlock()
+		return nil
+	}
+	from := seg.getPartitionID()
+	if c.partitionSegments != nil {
+		partSegs := c.partitionSegments[from][:0]
+		for _, s := range c.partitionSegments[from] {
+			if s != seg {
+				partSegs = append(partSegs, s)
+			}
+		}
+		if len(partSegs) == 0 {
+			delete(c.partitionSegments, from)
+		} else {
+			c.partitionSegments[from] = partSegs
+		}
+		c.partitionSegments[partID] = append(c.partitionSegments[partID], seg)
+	}
+	seg.partitionID = partID
+	c.version++
+	c.markDirtyWithoutLock(segID)
+	ev := c.events.stamp(ChannelEvent{Type: SegmentUpdated, CollectionID: c.collectionID, SegmentID: segID})
+	c.segMu.Unlock()
+
+	c.events.publish(ev)
+	log.Info("segment moved to partition", zap.Int64("segmentID", segID),
+		zap.Int64("from", from), zap.Int64("to", partID), zap.String("channel", c.channelName))
+	return nil
+}
@@ -0,0 +1,84 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_defaultPartition(t *testing.T) {
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	defaultName := Params.CommonCfg.DefaultPartitionName
+
+	t.Run("registered by option", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil, WithDefaultPartition(10))
+		partID, err := channel.getPartitionIDByName(1, defaultName)
+		require.NoError(t, err)
+		assert.Equal(t, UniqueID(10), partID)
+
+		_, err = channel.getPartitionIDByName(1, "p1")
+		assert.Error(t, err)
+		_, err = channel.getPartitionIDByName(2, defaultName)
+		assert.Error(t, err)
+	})
+
+	t.Run("removal guard", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil, WithDefaultPartition(10))
+		assert.ErrorIs(t, channel.checkPartitionRemovable(10), ErrDefaultPartitionNotRemovable)
+		assert.NoError(t, channel.checkPartitionRemovable(11))
+	})
+
+	t.Run("unknown default partition", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, nil)
+		_, err := channel.getPartitionIDByName(1, defaultName)
+		assert.Error(t, err)
+		assert.NoError(t, channel.checkPartitionRemovable(10))
+	})
+}
+
+func TestChannelMeta_getCollectionPartitionNum(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil,
+		WithDefaultPartition(10))
+	num, err := channel.getCollectionPartitionNum(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, num)
+
+	for _, seg := range []struct{ segID, partID UniqueID }{{1, 10}, {2, 11}, {3, 11}, {4, 12}} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       seg.segID,
+			collID:      1,
+			partitionID: seg.partID,
+		}))
+	}
+	num, err = channel.getCollectionPartitionNum(1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, num)
+
+	channel.removeSegments(4)
+	num, err = channel.getCollectionPartitionNum(1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, num)
+
+	_, err = channel.getCollectionPartitionNum(2)
+	assert.ErrorIs(t, err, errMismatchCollection)
+}
@@ -0,0 +1,65 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// FlushedUpdatePolicy decides how updateStatistics handles a segment already flushed,
+// which happens when the stats of the last insert batch arrive just after the flusher took the segment.
+type FlushedUpdatePolicy int32
+
+const (
+	// RejectFlushedUpdate drops the update with a warning, the caller is responsible for the rows.
+	RejectFlushedUpdate FlushedUpdatePolicy = iota
+	// RedirectFlushedUpdate keeps the update in a post flush delta, which the flusher must drain
+	// by takePostFlushDeltas before finalizing the segment.
+	RedirectFlushedUpdate
+)
+
+// WithFlushedUpdatePolicy sets how statistics updates of flushed segments are handled,
+// RejectFlushedUpdate by default.
+func WithFlushedUpdatePolicy(policy FlushedUpdatePolicy) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.flushedUpdatePolicy = policy
+	}
+}
+
+// redirectFlushedUpdate accumulates numRows into the post flush delta of segment, caller shall hold the segMu.
+func (c *ChannelMeta) redirectFlushedUpdate(segID UniqueID, numRows int64) {
+	if c.postFlushDeltas == nil {
+		c.postFlushDeltas = make(map[UniqueID]int64)
+	}
+	c.postFlushDeltas[segID] += numRows
+	log.Info("segment already flushed, redirect statistics update to post flush delta",
+		zap.Int64("segmentID", segID),
+		zap.Int64("numRows", numRows),
+		zap.Int64("delta", c.postFlushDeltas[segID]))
+}
+
+// takePostFlushDeltas returns and clears the rows redirected to the segment after it was flushed.
+func (c *ChannelMeta) takePostFlushDeltas(segID UniqueID) int64 {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	delta := c.postFlushDeltas[segID]
+	delete(c.postFlushDeltas, segID)
+	return delta
+}
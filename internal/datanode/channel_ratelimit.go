@@ -0,0 +1,96 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/util/ratelimitutil"
+)
+
+// ErrRateLimited is returned by addSegment when new segments arrive faster than the rate limit allows.
+var ErrRateLimited = errors.New("add segment rate limited")
+
+// WithAddSegmentRateLimit limits new segments created from the insert stream to rate per second with burst,
+// addSegment exceeding the limit waits up to maxWait before failing with ErrRateLimited, zero maxWait fails
+// without blocking. Segments recovered from datacoord or imported are not limited. Non-positive rate disables the limit.
+// The limiter refills by the clock of the channel.
+func WithAddSegmentRateLimit(rate float64, burst int, maxWait time.Duration) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		if rate <= 0 {
+			c.addLimiter = nil
+			return
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		// the limiter admits events while its tokens are not negative, so it holds burst-1 tokens to admit burst
+		c.addLimiter = ratelimitutil.NewLimiter(ratelimitutil.Limit(rate), float64(burst-1))
+		c.addLimitWait = maxWait
+	}
+}
+
+// rateLimited tells whether addSegment of req goes through the rate limiter,
+// only new segments from the insert stream do.
+func (req *addSegmentReq) rateLimited() bool {
+	return req.segType == datapb.SegmentType_New && !req.importing
+}
+
+// waitAddSegmentRate blocks until the rate limiter admits segID, ctx is cancelled or the max wait passes.
+func (c *ChannelMeta) waitAddSegmentRate(ctx context.Context, segID UniqueID) error {
+	if c.addLimiter == nil {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	interval := time.Duration(float64(time.Second) / float64(c.addLimiter.Limit()))
+	deadline := c.now().Add(c.addLimitWait)
+	for {
+		now := c.now()
+		if c.addLimiter.AllowN(now, 1) {
+			return nil
+		}
+		remaining := deadline.Sub(now)
+		if remaining <= 0 {
+			log.RatedWarn(1, "add segment rate limited",
+				zap.Int64("segmentID", segID),
+				zap.String("channel", c.channelName),
+				zap.Stringer("rate", c.addLimiter.Limit()))
+			return fmt.Errorf("%w, segmentID=%d", ErrRateLimited, segID)
+		}
+		if interval < remaining {
+			remaining = interval
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("wait for add segment rate limit, segmentID=%d: %w", segID, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
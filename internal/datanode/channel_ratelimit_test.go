@@ -0,0 +1,134 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_addSegmentRateLimit(t *testing.T) {
+	rc := &RootCoordFactory{
+		pkType: schemapb.DataType_Int64,
+	}
+	cm := storage.NewLocalChunkManager(storage.RootPath(channelMetaNodeTestDir))
+	defer cm.RemoveWithPrefix(context.Background(), "")
+
+	newReq := func(segID UniqueID) addSegmentReq {
+		return addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}
+	}
+
+	t.Run("burst then block", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm, WithAddSegmentRateLimit(10, 2, time.Second))
+		require.NoError(t, channel.addSegment(newReq(1)))
+		require.NoError(t, channel.addSegment(newReq(2)))
+
+		start := time.Now()
+		require.NoError(t, channel.addSegment(newReq(3)))
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("exceed max wait", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm, WithAddSegmentRateLimit(1, 1, 10*time.Millisecond))
+		require.NoError(t, channel.addSegment(newReq(1)))
+
+		err := channel.addSegment(newReq(2))
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.False(t, channel.hasSegment(2, true))
+	})
+
+	t.Run("context cancelled", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm, WithAddSegmentRateLimit(1, 1, time.Minute))
+		require.NoError(t, channel.addSegment(newReq(1)))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		req := newReq(2)
+		req.ctx = ctx
+		start := time.Now()
+		err := channel.addSegment(req)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 10*time.Second)
+		assert.False(t, channel.hasSegment(2, true))
+	})
+
+	t.Run("enforced over time", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm, WithAddSegmentRateLimit(2, 3, 0))
+		now := time.Now()
+		channel.clock = func() time.Time { return now }
+
+		admitted := 0
+		segID := UniqueID(1)
+		// 10 calls per second for 10 seconds
+		for i := 0; i < 100; i++ {
+			err := channel.addSegment(newReq(segID))
+			if err == nil {
+				admitted++
+			} else {
+				assert.ErrorIs(t, err, ErrRateLimited)
+			}
+			segID++
+			now = now.Add(100 * time.Millisecond)
+		}
+		// the burst plus 2 per second
+		assert.InDelta(t, 3+2*10, admitted, 1)
+		assert.Len(t, channel.listAllSegmentIDs(), admitted)
+
+		// idle time refills up to the burst only
+		now = now.Add(time.Minute)
+		admitted = 0
+		for i := 0; i < 10; i++ {
+			if channel.addSegment(newReq(segID)) == nil {
+				admitted++
+			}
+			segID++
+		}
+		assert.Equal(t, 3, admitted)
+	})
+
+	t.Run("restore and import are exempted", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, rc, cm, WithAddSegmentRateLimit(1, 1, 0))
+		require.NoError(t, channel.addSegment(newReq(1)))
+		assert.ErrorIs(t, channel.addSegment(newReq(2)), ErrRateLimited)
+
+		for i, segType := range []datapb.SegmentType{datapb.SegmentType_Normal, datapb.SegmentType_Flushed} {
+			require.NoError(t, channel.addSegment(addSegmentReq{
+				segType:     segType,
+				segID:       UniqueID(10 + i),
+				collID:      1,
+				partitionID: 10,
+				numOfRows:   100,
+			}))
+		}
+		imported := newReq(20)
+		imported.importing = true
+		require.NoError(t, channel.addSegment(imported))
+		assert.True(t, channel.hasSegment(20, true))
+	})
+}
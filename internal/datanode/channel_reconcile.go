@@ -0,0 +1,78 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"sort"
+
+	"github.com/milvus-io/milvus-proto/go-api/commonpb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+// reconcile diffs the valid segments of channel against the authoritative segment list of datacoord.
+// External segments of other collections or channels are ignored, and dropped ones are taken as absent.
+//
+//   - missingLocally: in the external list but not in channel
+//   - extraLocally: in channel but not in the external list
+//   - mismatched: in both, but with different partition, flushed state, or row count of flushed segments
+//
+// All results are sorted by segment ID.
+func (c *ChannelMeta) reconcile(external []*datapb.SegmentInfo) (missingLocally, extraLocally, mismatched []UniqueID) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	seen := make(map[UniqueID]struct{}, len(external))
+	for _, info := range external {
+		if info.GetCollectionID() != c.collectionID ||
+			(info.GetInsertChannel() != "" && info.GetInsertChannel() != c.channelName) ||
+			info.GetState() == commonpb.SegmentState_Dropped {
+			continue
+		}
+		seen[info.GetID()] = struct{}{}
+
+		seg, ok := c.segments[info.GetID()]
+		if !ok || !seg.isValid() {
+			missingLocally = append(missingLocally, info.GetID())
+			continue
+		}
+		if segmentMismatched(seg, info) {
+			mismatched = append(mismatched, info.GetID())
+		}
+	}
+
+	for segID, seg := range c.segments {
+		if _, ok := seen[segID]; !ok && seg.isValid() {
+			extraLocally = append(extraLocally, segID)
+		}
+	}
+
+	for _, ids := range [][]UniqueID{missingLocally, extraLocally, mismatched} {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	return missingLocally, extraLocally, mismatched
+}
+
+func segmentMismatched(seg *Segment, info *datapb.SegmentInfo) bool {
+	if seg.getPartitionID() != info.GetPartitionID() {
+		return true
+	}
+	externalFlushed := info.GetState() == commonpb.SegmentState_Flushed || info.GetState() == commonpb.SegmentState_Flushing
+	if externalFlushed != !seg.notFlushed() {
+		return true
+	}
+	return externalFlushed && seg.numRows != info.GetNumOfRows()
+}
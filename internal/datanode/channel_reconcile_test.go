@@ -0,0 +1,125 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/commonpb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestChannelMeta_reconcile(t *testing.T) {
+	newReconcileChannel := func() *ChannelMeta {
+		channel := newChannel("channel", 1, nil, nil, nil)
+		for _, s := range []struct {
+			segID   UniqueID
+			partID  UniqueID
+			segType datapb.SegmentType
+			numRows int64
+		}{
+			{1, 10, datapb.SegmentType_Normal, 100},
+			{2, 10, datapb.SegmentType_Flushed, 200},
+			{3, 20, datapb.SegmentType_Flushed, 300},
+			{4, 20, datapb.SegmentType_Compacted, 400},
+		} {
+			seg := &Segment{collectionID: 1, partitionID: s.partID, segmentID: s.segID, numRows: s.numRows}
+			seg.setType(s.segType)
+			channel.putSegmentWithoutLock(seg)
+		}
+		return channel
+	}
+	info := func(segID, partID UniqueID, state commonpb.SegmentState, numRows int64) *datapb.SegmentInfo {
+		return &datapb.SegmentInfo{
+			ID:            segID,
+			CollectionID:  1,
+			PartitionID:   partID,
+			InsertChannel: "channel",
+			State:         state,
+			NumOfRows:     numRows,
+		}
+	}
+
+	tests := []struct {
+		description string
+		external    []*datapb.SegmentInfo
+
+		missing    []UniqueID
+		extra      []UniqueID
+		mismatched []UniqueID
+	}{
+		{
+			"consistent",
+			[]*datapb.SegmentInfo{
+				info(1, 10, commonpb.SegmentState_Growing, 0),
+				info(2, 10, commonpb.SegmentState_Flushed, 200),
+				info(3, 20, commonpb.SegmentState_Flushing, 300),
+			},
+			nil, nil, nil,
+		},
+		{
+			"missing locally, compacted segments count as missing",
+			[]*datapb.SegmentInfo{
+				info(1, 10, commonpb.SegmentState_Growing, 0),
+				info(2, 10, commonpb.SegmentState_Flushed, 200),
+				info(3, 20, commonpb.SegmentState_Flushed, 300),
+				info(4, 20, commonpb.SegmentState_Flushed, 400),
+				info(5, 20, commonpb.SegmentState_Growing, 0),
+			},
+			[]UniqueID{4, 5}, nil, nil,
+		},
+		{
+			"extra locally, dropped and foreign segments are ignored",
+			[]*datapb.SegmentInfo{
+				info(1, 10, commonpb.SegmentState_Growing, 0),
+				info(2, 10, commonpb.SegmentState_Dropped, 200),
+				{ID: 3, CollectionID: 1, PartitionID: 20, InsertChannel: "other", State: commonpb.SegmentState_Flushed, NumOfRows: 300},
+				{ID: 6, CollectionID: 2, InsertChannel: "channel", State: commonpb.SegmentState_Growing},
+			},
+			nil, []UniqueID{2, 3}, nil,
+		},
+		{
+			"mismatched",
+			[]*datapb.SegmentInfo{
+				info(1, 10, commonpb.SegmentState_Flushed, 100),
+				info(2, 20, commonpb.SegmentState_Flushed, 200),
+				info(3, 20, commonpb.SegmentState_Flushed, 301),
+			},
+			nil, nil, []UniqueID{1, 2, 3},
+		},
+		{
+			"combination",
+			[]*datapb.SegmentInfo{
+				info(3, 20, commonpb.SegmentState_Growing, 0),
+				info(2, 10, commonpb.SegmentState_Flushed, 200),
+				info(7, 10, commonpb.SegmentState_Flushed, 700),
+			},
+			[]UniqueID{7}, []UniqueID{1}, []UniqueID{3},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			missing, extra, mismatched := newReconcileChannel().reconcile(test.external)
+			assert.Equal(t, test.missing, missing)
+			assert.Equal(t, test.extra, extra)
+			assert.Equal(t, test.mismatched, mismatched)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bits-and-blooms/bloom/v3"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+)
+
+// The applied message IDs of a segment are kept in a bloom filter of about 18KB, a false positive
+// skips an update of a message never applied, which gets likelier once a segment sees more messages.
+const (
+	appliedMsgsFilterSize          = 10000
+	appliedMsgsFilterFalsePositive = 0.001
+)
+
+// updateStatisticsIdempotent applies a statistics update carried by the message msgID at most once per segment,
+// so replaying a stream delivered at least once does not count rows twice. endTime is the end timestamp of
+// the message, the latest of positions not after it becomes the end position of the segment.
+// Applied message IDs are kept in a bloom filter per segment to bound memory, see appliedMsgsFilterSize.
+func (c *ChannelMeta) updateStatisticsIdempotent(segID UniqueID, numRows int64, endTime Timestamp,
+	positions []*internalpb.MsgPosition, msgID []byte) error {
+	if len(msgID) == 0 {
+		return errors.New("empty message ID")
+	}
+	var endPos *internalpb.MsgPosition
+	for _, pos := range positions {
+		if pos.GetTimestamp() > endTime {
+			return fmt.Errorf("position of channel %s at %d is after the message end time %d",
+				pos.GetChannelName(), pos.GetTimestamp(), endTime)
+		}
+		if endPos == nil || pos.GetTimestamp() > endPos.GetTimestamp() {
+			endPos = pos
+		}
+	}
+
+	c.segMu.Lock()
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		c.segMu.Unlock()
+		return fmt.Errorf("cannot find segment, id = %d", segID)
+	}
+	if seg.appliedMsgs == nil {
+		seg.appliedMsgs = bloom.NewWithEstimates(appliedMsgsFilterSize, appliedMsgsFilterFalsePositive)
+	}
+	if seg.appliedMsgs.TestAndAdd(msgID) {
+		c.segMu.Unlock()
+		log.Debug("skip statistics update of applied message", zap.Int64("segmentID", segID), zap.Binary("msgID", msgID))
+		return nil
+	}
+	c.segMu.Unlock()
+
+	c.updateStatistics(segID, numRows)
+	if endPos != nil {
+		c.updateSegmentEndPosition(segID, endPos)
+	}
+	return nil
+}
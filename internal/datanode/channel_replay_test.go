@@ -0,0 +1,72 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_updateStatisticsIdempotent(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+	}))
+	positions := func(ts ...Timestamp) []*internalpb.MsgPosition {
+		var result []*internalpb.MsgPosition
+		for _, t := range ts {
+			result = append(result, &internalpb.MsgPosition{ChannelName: "channel", Timestamp: t})
+		}
+		return result
+	}
+
+	t.Run("novel messages", func(t *testing.T) {
+		require.NoError(t, channel.updateStatisticsIdempotent(1, 10, 100, positions(90, 100), []byte("msg-1")))
+		require.NoError(t, channel.updateStatisticsIdempotent(1, 20, 200, positions(200), []byte("msg-2")))
+		assert.Equal(t, int64(30), channel.segments[1].numRows)
+		assert.Equal(t, Timestamp(200), channel.segments[1].endPos.GetTimestamp())
+	})
+
+	t.Run("repeated messages", func(t *testing.T) {
+		require.NoError(t, channel.updateStatisticsIdempotent(1, 10, 100, positions(100), []byte("msg-1")))
+		require.NoError(t, channel.updateStatisticsIdempotent(1, 20, 200, positions(200), []byte("msg-2")))
+		assert.Equal(t, int64(30), channel.segments[1].numRows)
+
+		require.NoError(t, channel.updateStatisticsIdempotent(1, 5, 300, nil, []byte("msg-3")))
+		assert.Equal(t, int64(35), channel.segments[1].numRows)
+		assert.Equal(t, Timestamp(200), channel.segments[1].endPos.GetTimestamp())
+	})
+
+	t.Run("invalid updates", func(t *testing.T) {
+		assert.Error(t, channel.updateStatisticsIdempotent(1, 10, 400, nil, nil))
+		assert.Error(t, channel.updateStatisticsIdempotent(2, 10, 400, nil, []byte("msg-4")))
+		assert.Error(t, channel.updateStatisticsIdempotent(1, 10, 400, positions(500), []byte("msg-4")))
+		assert.Equal(t, int64(35), channel.segments[1].numRows)
+
+		// refused messages are not marked applied
+		require.NoError(t, channel.updateStatisticsIdempotent(1, 10, 500, positions(500), []byte("msg-4")))
+		assert.Equal(t, int64(45), channel.segments[1].numRows)
+	})
+}
@@ -0,0 +1,145 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/util/retry"
+)
+
+// IsRetryable returns whether a failed channel mutation is worth retrying, that is it failed on a remote
+// service or the storage the channel depends on (see markTransient), and was not cancelled. Validation
+// failures of the channel, such as ErrSegmentIDReused or ErrChannelFull, never succeed on retry.
+func IsRetryable(err error) bool {
+	return errors.Is(err, errTransient) &&
+		!errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded)
+}
+
+// RetryingChannel wraps the mutating methods of a Channel, retrying them with
+// exponential backoff when they fail with a retryable error.
+type RetryingChannel struct {
+	Channel
+
+	maxAttempts uint
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	retryable   func(err error) bool
+}
+
+// RetryingChannelOption is the option to setup RetryingChannel.
+type RetryingChannelOption func(rc *RetryingChannel)
+
+// WithRetryConfig sets the max attempts and the backoff range of retries.
+func WithRetryConfig(maxAttempts int, baseDelay, maxDelay time.Duration) RetryingChannelOption {
+	return func(rc *RetryingChannel) {
+		if maxAttempts > 0 {
+			rc.maxAttempts = uint(maxAttempts)
+		}
+		rc.baseDelay = baseDelay
+		rc.maxDelay = maxDelay
+	}
+}
+
+// WithRetryablePredicate replaces IsRetryable as the predicate deciding whether to retry.
+func WithRetryablePredicate(fn func(err error) bool) RetryingChannelOption {
+	return func(rc *RetryingChannel) {
+		rc.retryable = fn
+	}
+}
+
+func newRetryingChannel(channel Channel, opts ...RetryingChannelOption) *RetryingChannel {
+	rc := &RetryingChannel{
+		Channel:     channel,
+		maxAttempts: 3,
+		baseDelay:   100 * time.Millisecond,
+		maxDelay:    time.Second,
+		retryable:   IsRetryable,
+	}
+	for _, opt := range opts {
+		opt(rc)
+	}
+	return rc
+}
+
+// do calls fn until it succeeds, fails with an error not retryable or runs out of attempts,
+// and returns the error of the last attempt as is, so that callers can match it with errors.Is.
+func (rc *RetryingChannel) do(ctx context.Context, fn func() error) error {
+	var lastErr error
+	err := retry.Do(ctx, func() error {
+		lastErr = fn()
+		if lastErr != nil && !rc.retryable(lastErr) {
+			return retry.Unrecoverable(lastErr)
+		}
+		return lastErr
+	}, retry.Attempts(rc.maxAttempts), retry.Sleep(rc.baseDelay), retry.MaxSleepTime(rc.maxDelay))
+	if err != nil {
+		return lastErr
+	}
+	return nil
+}
+
+// retryingChannelVersion is the Channel interface version RetryingChannel is written against, it is only
+// bumped once the methods added in a new version are reviewed for retries.
+const retryingChannelVersion = 16
+
+// InterfaceVersion returns the lower of the versions of RetryingChannel and the wrapped channel,
+// methods newer than either are not guaranteed to behave.
+func (rc *RetryingChannel) InterfaceVersion() int {
+	if v := rc.Channel.InterfaceVersion(); v < retryingChannelVersion {
+		return v
+	}
+	return retryingChannelVersion
+}
+
+// capabilities returns the features of the wrapped channel with the interface version of RetryingChannel.
+func (rc *RetryingChannel) capabilities() ReplicaCapabilities {
+	if rc.Channel.InterfaceVersion() < channelVersionCapabilities {
+		return ReplicaCapabilities{InterfaceVersion: rc.InterfaceVersion()}
+	}
+	caps := rc.Channel.capabilities()
+	caps.InterfaceVersion = rc.InterfaceVersion()
+	return caps
+}
+
+func (rc *RetryingChannel) addSegment(req addSegmentReq) error {
+	return rc.do(context.TODO(), func() error {
+		return rc.Channel.addSegment(req)
+	})
+}
+
+func (rc *RetryingChannel) mergeFlushedSegments(seg *Segment, planID UniqueID, compactedFrom []UniqueID) error {
+	return rc.do(context.TODO(), func() error {
+		return rc.Channel.mergeFlushedSegments(seg, planID, compactedFrom)
+	})
+}
+
+func (rc *RetryingChannel) recordSpill(segID UniqueID, bytes int64, rows int64) error {
+	return rc.do(context.TODO(), func() error {
+		return rc.Channel.recordSpill(segID, bytes, rows)
+	})
+}
+
+func (rc *RetryingChannel) InitPKstats(ctx context.Context, s *Segment, statsBinlogs []*datapb.FieldBinlog, ts Timestamp) error {
+	return rc.do(ctx, func() error {
+		return rc.Channel.InitPKstats(ctx, s, statsBinlogs, ts)
+	})
+}
@@ -0,0 +1,116 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyChannel fails addSegment with the injected errors before succeeding.
+type flakyChannel struct {
+	Channel
+
+	errs  []error
+	calls int
+}
+
+func (fc *flakyChannel) addSegment(req addSegmentReq) error {
+	fc.calls++
+	if fc.calls <= len(fc.errs) {
+		return fc.errs[fc.calls-1]
+	}
+	return nil
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.False(t, IsRetryable(nil))
+	assert.True(t, IsRetryable(markTransient(errors.New("mock etcd error"))))
+	assert.False(t, IsRetryable(errors.New("mock validation error")))
+	assert.False(t, IsRetryable(markTransient(context.Canceled)))
+	assert.False(t, IsRetryable(fmt.Errorf("%w, ID=%d", errMismatchCollection, 1)))
+	for _, err := range []error{ErrSegmentIDReused, ErrNamespaceMismatch, ErrCollectionPurged,
+		ErrSegmentIDConflict, ErrChannelFull, ErrRateLimited, ErrAccessDenied} {
+		assert.False(t, IsRetryable(fmt.Errorf("%w, segmentID=%d", err, 1)), err.Error())
+	}
+
+	cause := errors.New("mock storage error")
+	err := markTransient(cause)
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, cause.Error(), err.Error())
+	assert.Nil(t, markTransient(nil))
+
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{collectionID: -1}, nil)
+	_, err = channel.getCollectionSchema(1, 0)
+	assert.True(t, IsRetryable(err))
+}
+
+func TestRetryingChannel(t *testing.T) {
+	transient := markTransient(errors.New("mock transient error"))
+
+	t.Run("succeed on the third attempt", func(t *testing.T) {
+		fc := &flakyChannel{errs: []error{transient, transient}}
+		rc := newRetryingChannel(fc, WithRetryConfig(3, time.Millisecond, 4*time.Millisecond))
+
+		err := rc.addSegment(addSegmentReq{segID: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, 3, fc.calls)
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		fc := &flakyChannel{errs: []error{transient, transient, transient}}
+		rc := newRetryingChannel(fc, WithRetryConfig(2, time.Millisecond, 4*time.Millisecond))
+
+		err := rc.addSegment(addSegmentReq{segID: 1})
+		assert.Equal(t, transient, err)
+		assert.Equal(t, 2, fc.calls)
+	})
+
+	t.Run("non-retryable error", func(t *testing.T) {
+		fc := &flakyChannel{errs: []error{errMismatchCollection}}
+		rc := newRetryingChannel(fc, WithRetryConfig(3, time.Millisecond, 4*time.Millisecond))
+
+		err := rc.addSegment(addSegmentReq{segID: 1})
+		assert.Error(t, err)
+		assert.Equal(t, 1, fc.calls)
+	})
+
+	t.Run("validation error is not retried and stays matchable", func(t *testing.T) {
+		fc := &flakyChannel{errs: []error{transient, fmt.Errorf("%w, segmentID=%d", ErrSegmentIDReused, 1)}}
+		rc := newRetryingChannel(fc, WithRetryConfig(5, time.Millisecond, 4*time.Millisecond))
+
+		err := rc.addSegment(addSegmentReq{segID: 1})
+		assert.ErrorIs(t, err, ErrSegmentIDReused)
+		assert.Equal(t, 2, fc.calls)
+	})
+
+	t.Run("custom predicate", func(t *testing.T) {
+		fc := &flakyChannel{errs: []error{transient, transient}}
+		rc := newRetryingChannel(fc,
+			WithRetryConfig(3, time.Millisecond, 4*time.Millisecond),
+			WithRetryablePredicate(func(err error) bool { return false }))
+
+		err := rc.addSegment(addSegmentReq{segID: 1})
+		assert.Error(t, err)
+		assert.Equal(t, 1, fc.calls)
+	})
+}
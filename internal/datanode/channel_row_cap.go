@@ -0,0 +1,56 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+)
+
+// localRowCapRequester is the requester of the force flush requests made for segments hitting the row cap.
+const localRowCapRequester = "local-row-cap"
+
+// WithMaxSegmentRows caps the rows of growing segments at n, as a backstop in case the seal policy of datacoord
+// lags. An update pushing a segment past n still applies, as its rows are already in the insert buffer, and the
+// segment is requested to flush on behalf of localRowCapRequester, which notifies the FlushNotifier to seal it.
+// Non-positive n disables the cap.
+func WithMaxSegmentRows(n int64) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.maxSegmentRows = n
+	}
+}
+
+// checkSegmentRowCapWithoutLock requests the flush of seg if the update of numRows pushes it past the row cap
+// for the first time. Caller shall hold the segMu.
+func (c *ChannelMeta) checkSegmentRowCapWithoutLock(seg *Segment, numRows int64) {
+	// a segment already past the cap has hit it before
+	if numRows <= 0 || seg.numRows+numRows <= c.maxSegmentRows || seg.numRows > c.maxSegmentRows {
+		return
+	}
+	metrics.DataNodeSegmentRowCapCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Inc()
+	if c.requestFlushWithoutLock(seg.segmentID, localRowCapRequester) {
+		log.Warn("segment hits the row cap, sealed locally",
+			zap.Int64("segmentID", seg.segmentID),
+			zap.Int64("numRows", seg.numRows+numRows),
+			zap.Int64("maxSegmentRows", c.maxSegmentRows))
+	}
+}
@@ -0,0 +1,90 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestChannelMeta_maxSegmentRows(t *testing.T) {
+	newCappedChannel := func(t *testing.T, opts ...ChannelMetaOption) *ChannelMeta {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil, opts...)
+		for _, segID := range []UniqueID{1, 2} {
+			require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: 1, partitionID: 10}))
+		}
+		return channel
+	}
+	requesters := func(channel *ChannelMeta) map[UniqueID][]string {
+		result := make(map[UniqueID][]string)
+		for _, req := range channel.listPendingFlushRequests() {
+			result[req.SegmentID] = req.Requesters
+		}
+		return result
+	}
+
+	t.Run("cap crossing", func(t *testing.T) {
+		notifier := &mockFlushNotifier{sealed: make(chan UniqueID, 10)}
+		channel := newCappedChannel(t, WithMaxSegmentRows(100), WithFlushNotifier(notifier))
+
+		channel.updateStatistics(1, 60)
+		channel.updateStatistics(1, 40)
+		assert.Empty(t, requesters(channel), "reaching the cap is not crossing it")
+
+		channel.updateStatistics(1, 30)
+		assert.Equal(t, int64(130), channel.segments[1].numRows, "the update still applies")
+		assert.Equal(t, map[UniqueID][]string{1: {localRowCapRequester}}, requesters(channel))
+		select {
+		case segID := <-notifier.sealed:
+			assert.Equal(t, UniqueID(1), segID)
+		case <-time.After(time.Second):
+			t.Fatal("seal not notified")
+		}
+
+		// later updates of the sealed segment are applied without another request
+		channel.updateStatistics(1, 10)
+		assert.Equal(t, int64(140), channel.segments[1].numRows)
+		assert.Len(t, channel.listPendingFlushRequests(), 1)
+
+		// a single update crossing the cap from empty
+		channel.updateStatistics(2, 101)
+		assert.Equal(t, []string{localRowCapRequester}, requesters(channel)[2])
+		assert.Equal(t, int64(241), channel.collectionRows)
+	})
+
+	t.Run("joins a pending request", func(t *testing.T) {
+		channel := newCappedChannel(t, WithMaxSegmentRows(100))
+		accepted, err := channel.requestFlush(1, "datacoord")
+		require.NoError(t, err)
+		require.True(t, accepted)
+
+		channel.updateStatistics(1, 200)
+		assert.Equal(t, []string{"datacoord", localRowCapRequester}, requesters(channel)[1])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		channel := newCappedChannel(t, WithMaxSegmentRows(0))
+		channel.updateStatistics(1, 1<<40)
+		assert.Empty(t, channel.listPendingFlushRequests())
+	})
+}
@@ -0,0 +1,104 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// schemaRefreshTimeout bounds a single call of the SchemaProvider.
+const schemaRefreshTimeout = 10 * time.Second
+
+// SchemaProvider supplies the latest schema of a collection, for schema changes happening
+// out-of-band rather than pushed to datanode.
+type SchemaProvider interface {
+	GetCollectionSchema(ctx context.Context, collectionID UniqueID) (*schemapb.CollectionSchema, error)
+}
+
+// WithSchemaProvider sets the provider refreshCollectionSchema reloads the schema from.
+func WithSchemaProvider(provider SchemaProvider) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.schemaProvider = provider
+	}
+}
+
+// refreshCollectionSchema fetches the latest schema of the collection from the SchemaProvider and applies it.
+// The new schema must be compatible with the current one, see checkSchemaCompatible,
+// otherwise the current schema is kept and ErrIncompatibleSchema returned.
+func (c *ChannelMeta) refreshCollectionSchema(collID UniqueID) error {
+	if !c.validCollection(collID) {
+		return fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+	if c.schemaProvider == nil {
+		return ErrNoSchemaProvider
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), schemaRefreshTimeout)
+	defer cancel()
+	sch, err := c.schemaProvider.GetCollectionSchema(ctx, collID)
+	if err != nil {
+		return markTransient(err)
+	}
+
+	c.schemaMut.Lock()
+	defer c.schemaMut.Unlock()
+	if c.collSchema != nil {
+		if err := checkSchemaCompatible(c.collSchema, sch); err != nil {
+			log.Warn("refused incompatible collection schema", zap.Int64("collectionID", collID), zap.Error(err))
+			return err
+		}
+	}
+	c.collSchema = sch
+	collectionLabels.register(collID, sch.GetName())
+	log.Info("collection schema refreshed", zap.Int64("collectionID", collID), zap.Int("numFields", len(sch.GetFields())))
+	return nil
+}
+
+// checkSchemaCompatible checks the latest schema only adds fields to the old one, data already buffered
+// and flushed with the old schema is then still valid. Every old field must be kept with the same
+// name, data type and primary key flag.
+func checkSchemaCompatible(old, latest *schemapb.CollectionSchema) error {
+	if latest == nil {
+		return fmt.Errorf("%w: nil schema", ErrIncompatibleSchema)
+	}
+	fields := make(map[int64]*schemapb.FieldSchema, len(latest.GetFields()))
+	for _, field := range latest.GetFields() {
+		fields[field.GetFieldID()] = field
+	}
+	for _, field := range old.GetFields() {
+		f, ok := fields[field.GetFieldID()]
+		switch {
+		case !ok:
+			return fmt.Errorf("%w: field %d(%s) dropped", ErrIncompatibleSchema, field.GetFieldID(), field.GetName())
+		case f.GetName() != field.GetName():
+			return fmt.Errorf("%w: field %d renamed from %s to %s", ErrIncompatibleSchema, field.GetFieldID(), field.GetName(), f.GetName())
+		case f.GetDataType() != field.GetDataType():
+			return fmt.Errorf("%w: data type of field %d(%s) changed from %s to %s",
+				ErrIncompatibleSchema, field.GetFieldID(), field.GetName(), field.GetDataType(), f.GetDataType())
+		case f.GetIsPrimaryKey() != field.GetIsPrimaryKey():
+			return fmt.Errorf("%w: primary key of field %d(%s) changed", ErrIncompatibleSchema, field.GetFieldID(), field.GetName())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+)
+
+// schemaCache holds what is derived from a collection schema, so lookups by field do not scan the schema.
+type schemaCache struct {
+	schema       *schemapb.CollectionSchema
+	pkField      *schemapb.FieldSchema // nil if the schema has no primary key field
+	vectorFields []*schemapb.FieldSchema
+	fieldsByID   map[int64]*schemapb.FieldSchema
+	fieldsByName map[string]*schemapb.FieldSchema
+}
+
+func newSchemaCache(schema *schemapb.CollectionSchema) *schemaCache {
+	cache := &schemaCache{
+		schema:       schema,
+		fieldsByID:   make(map[int64]*schemapb.FieldSchema, len(schema.GetFields())),
+		fieldsByName: make(map[string]*schemapb.FieldSchema, len(schema.GetFields())),
+	}
+	for _, field := range schema.GetFields() {
+		if field.GetIsPrimaryKey() && cache.pkField == nil {
+			cache.pkField = field
+		}
+		if field.GetDataType() == schemapb.DataType_FloatVector ||
+			field.GetDataType() == schemapb.DataType_BinaryVector {
+			cache.vectorFields = append(cache.vectorFields, field)
+		}
+		cache.fieldsByID[field.GetFieldID()] = field
+		cache.fieldsByName[field.GetName()] = field
+	}
+	return cache
+}
+
+// getSchemaCache returns the cache of the current collection schema, fetching the schema as of ts if not yet,
+// see getCollectionSchema. The cache is rebuilt the first time it is asked for after the schema is replaced, e.g. by refreshCollectionSchema.
+func (c *ChannelMeta) getSchemaCache(collID UniqueID, ts Timestamp) (*schemaCache, error) {
+	schema, err := c.getCollectionSchema(collID, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	c.schemaMut.RLock()
+	cache := c.schemaCache
+	c.schemaMut.RUnlock()
+	if cache != nil && cache.schema == schema {
+		return cache, nil
+	}
+
+	c.schemaMut.Lock()
+	defer c.schemaMut.Unlock()
+	if c.schemaCache == nil || c.schemaCache.schema != c.collSchema {
+		c.schemaCache = newSchemaCache(c.collSchema)
+		c.schemaCacheBuilds++
+	}
+	return c.schemaCache, nil
+}
+
+// warmUp resolves the collection schema and builds the caches derived from it ahead of the first request.
+func (c *ChannelMeta) warmUp() error {
+	cache, err := c.getSchemaCache(c.collectionID, 0)
+	if err != nil {
+		return fmt.Errorf("failed to warm up channel %s: %w", c.channelName, err)
+	}
+	if cache.pkField == nil {
+		return fmt.Errorf("failed to warm up channel %s: no primary key field in schema of collection %d", c.channelName, c.collectionID)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestChannelMeta_warmUp(t *testing.T) {
+	t.Run("caches populated", func(t *testing.T) {
+		channel := newChannel("channel", 1, newSchemaTestSchema(), nil, nil)
+		seg := &Segment{segmentID: 1, collectionID: 1, partitionID: 10}
+		seg.setType(datapb.SegmentType_New)
+		channel.segments[1] = seg
+
+		require.NoError(t, channel.warmUp())
+		require.NotNil(t, channel.schemaCache)
+		assert.Equal(t, int64(1), channel.schemaCacheBuilds)
+		assert.Equal(t, "pk", channel.schemaCache.pkField.GetName())
+		require.Len(t, channel.schemaCache.vectorFields, 1)
+		assert.Equal(t, "vec", channel.schemaCache.vectorFields[0].GetName())
+		assert.Len(t, channel.schemaCache.fieldsByID, 2)
+		assert.Contains(t, channel.schemaCache.fieldsByName, "vec")
+
+		// first accesses are served by the caches
+		pctx, err := channel.getSegmentParseContext(1)
+		require.NoError(t, err)
+		assert.Equal(t, "pk", pctx.PrimaryKeyField.GetName())
+		assert.Len(t, pctx.VectorFields, 1)
+		require.NoError(t, channel.addFieldNulls(1, 101, 1))
+		require.NoError(t, channel.updateFieldStatistics(1, "pk", 0, 1, 0))
+		assert.Error(t, channel.updateFieldStatistics(1, "unknown", 0, 1, 0))
+		assert.Equal(t, int64(1), channel.schemaCacheBuilds)
+
+		// idempotent
+		require.NoError(t, channel.warmUp())
+		assert.Equal(t, int64(1), channel.schemaCacheBuilds)
+	})
+
+	t.Run("rebuilt after schema refreshed", func(t *testing.T) {
+		provider := &fakeSchemaProvider{}
+		channel := newChannel("channel", 1, newSchemaTestSchema(), nil, nil, WithSchemaProvider(provider))
+		require.NoError(t, channel.warmUp())
+
+		provider.schema = newSchemaTestSchema(&schemapb.FieldSchema{FieldID: 102, Name: "title", DataType: schemapb.DataType_VarChar})
+		require.NoError(t, channel.refreshCollectionSchema(1))
+		cache, err := channel.getSchemaCache(1, 0)
+		require.NoError(t, err)
+		assert.Contains(t, cache.fieldsByName, "title")
+		assert.Equal(t, int64(2), channel.schemaCacheBuilds)
+	})
+
+	t.Run("schema unavailable", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64, collectionID: -1}, nil)
+		assert.Error(t, channel.warmUp())
+		assert.Nil(t, channel.schemaCache)
+	})
+
+	t.Run("no primary key", func(t *testing.T) {
+		channel := newChannel("channel", 1, &schemapb.CollectionSchema{Fields: newSchemaTestSchema().GetFields()[1:]}, nil, nil)
+		assert.Error(t, channel.warmUp())
+	})
+}
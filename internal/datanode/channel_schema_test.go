@@ -0,0 +1,126 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSchemaProvider struct {
+	schema *schemapb.CollectionSchema
+	err    error
+	calls  int
+}
+
+func (p *fakeSchemaProvider) GetCollectionSchema(ctx context.Context, collectionID UniqueID) (*schemapb.CollectionSchema, error) {
+	p.calls++
+	return p.schema, p.err
+}
+
+func newSchemaTestSchema(fields ...*schemapb.FieldSchema) *schemapb.CollectionSchema {
+	return &schemapb.CollectionSchema{
+		Name: "books",
+		Fields: append([]*schemapb.FieldSchema{
+			{FieldID: 100, Name: "pk", DataType: schemapb.DataType_Int64, IsPrimaryKey: true},
+			{FieldID: 101, Name: "vec", DataType: schemapb.DataType_FloatVector},
+		}, fields...),
+	}
+}
+
+func TestChannelMeta_refreshCollectionSchema(t *testing.T) {
+	t.Run("no provider", func(t *testing.T) {
+		channel := newChannel("channel", 1, newSchemaTestSchema(), nil, nil)
+		assert.ErrorIs(t, channel.refreshCollectionSchema(1), ErrNoSchemaProvider)
+	})
+
+	t.Run("mismatch collection", func(t *testing.T) {
+		provider := &fakeSchemaProvider{schema: newSchemaTestSchema()}
+		channel := newChannel("channel", 1, newSchemaTestSchema(), nil, nil, WithSchemaProvider(provider))
+		assert.ErrorIs(t, channel.refreshCollectionSchema(2), errMismatchCollection)
+		assert.Zero(t, provider.calls)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		provider := &fakeSchemaProvider{err: errors.New("mock error")}
+		channel := newChannel("channel", 1, newSchemaTestSchema(), nil, nil, WithSchemaProvider(provider))
+		assert.Error(t, channel.refreshCollectionSchema(1))
+		sch, err := channel.getCollectionSchema(1, 0)
+		require.NoError(t, err)
+		assert.Len(t, sch.GetFields(), 2)
+	})
+
+	t.Run("field added", func(t *testing.T) {
+		provider := &fakeSchemaProvider{schema: newSchemaTestSchema()}
+		channel := newChannel("channel", 1, newSchemaTestSchema(), nil, nil, WithSchemaProvider(provider))
+
+		provider.schema = newSchemaTestSchema(&schemapb.FieldSchema{FieldID: 102, Name: "title", DataType: schemapb.DataType_VarChar})
+		require.NoError(t, channel.refreshCollectionSchema(1))
+		sch, err := channel.getCollectionSchema(1, 0)
+		require.NoError(t, err)
+		assert.Len(t, sch.GetFields(), 3)
+		fields, err := channel.getUserFields(1)
+		require.NoError(t, err)
+		assert.Equal(t, "title", fields[2].GetName())
+	})
+
+	t.Run("no schema yet", func(t *testing.T) {
+		provider := &fakeSchemaProvider{schema: newSchemaTestSchema()}
+		channel := newChannel("channel", 1, nil, nil, nil, WithSchemaProvider(provider))
+		require.NoError(t, channel.refreshCollectionSchema(1))
+		sch, err := channel.getCollectionSchema(1, 0)
+		require.NoError(t, err)
+		assert.Equal(t, "books", sch.GetName())
+	})
+
+	t.Run("incompatible", func(t *testing.T) {
+		tests := []struct {
+			description string
+			schema      *schemapb.CollectionSchema
+		}{
+			{"nil schema", nil},
+			{"field dropped", &schemapb.CollectionSchema{Fields: newSchemaTestSchema().GetFields()[:1]}},
+			{"field renamed", &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{
+				{FieldID: 100, Name: "pk", DataType: schemapb.DataType_Int64, IsPrimaryKey: true},
+				{FieldID: 101, Name: "embedding", DataType: schemapb.DataType_FloatVector},
+			}}},
+			{"data type changed", &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{
+				{FieldID: 100, Name: "pk", DataType: schemapb.DataType_VarChar, IsPrimaryKey: true},
+				{FieldID: 101, Name: "vec", DataType: schemapb.DataType_FloatVector},
+			}}},
+			{"primary key changed", &schemapb.CollectionSchema{Fields: []*schemapb.FieldSchema{
+				{FieldID: 100, Name: "pk", DataType: schemapb.DataType_Int64},
+				{FieldID: 101, Name: "vec", DataType: schemapb.DataType_FloatVector},
+			}}},
+		}
+		for _, test := range tests {
+			t.Run(test.description, func(t *testing.T) {
+				provider := &fakeSchemaProvider{schema: test.schema}
+				channel := newChannel("channel", 1, newSchemaTestSchema(), nil, nil, WithSchemaProvider(provider))
+				assert.ErrorIs(t, channel.refreshCollectionSchema(1), ErrIncompatibleSchema)
+				sch, err := channel.getCollectionSchema(1, 0)
+				require.NoError(t, err)
+				assert.Equal(t, newSchemaTestSchema().GetFields(), sch.GetFields())
+			})
+		}
+	})
+}
@@ -0,0 +1,77 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// PartitionSkewReport reports a partition whose row count deviates from the mean of the collection's partitions.
+// Deviation is relative to the mean, 1 means twice the mean rows and -0.5 half of them.
+type PartitionSkewReport struct {
+	PartitionID UniqueID `json:"partition_id"`
+	NumRows     int64    `json:"num_rows"`
+	Deviation   float64  `json:"deviation"`
+}
+
+// detectPartitionSkew reports the partitions whose rows in valid segments deviate from the mean
+// by more than skewThreshold, most deviating first. Only partitions with segments in the channel count.
+// The report is for the load balancer, the channel does not act on it.
+func (c *ChannelMeta) detectPartitionSkew(collID UniqueID, skewThreshold float64) ([]PartitionSkewReport, error) {
+	if !c.validCollection(collID) {
+		return nil, fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+	if math.IsNaN(skewThreshold) || skewThreshold < 0 {
+		return nil, fmt.Errorf("invalid skew threshold %v, must not be negative", skewThreshold)
+	}
+
+	c.segMu.RLock()
+	partitionRows := make(map[UniqueID]int64)
+	for _, seg := range c.segments {
+		if seg.isValid() {
+			partitionRows[seg.getPartitionID()] += seg.numRows
+		}
+	}
+	c.segMu.RUnlock()
+
+	var total int64
+	for _, rows := range partitionRows {
+		total += rows
+	}
+	if total == 0 {
+		return nil, nil
+	}
+	mean := float64(total) / float64(len(partitionRows))
+
+	var reports []PartitionSkewReport
+	for partID, rows := range partitionRows {
+		deviation := (float64(rows) - mean) / mean
+		if math.Abs(deviation) > skewThreshold {
+			reports = append(reports, PartitionSkewReport{PartitionID: partID, NumRows: rows, Deviation: deviation})
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		di, dj := math.Abs(reports[i].Deviation), math.Abs(reports[j].Deviation)
+		if di == dj {
+			return reports[i].PartitionID < reports[j].PartitionID
+		}
+		return di > dj
+	})
+	return reports, nil
+}
@@ -0,0 +1,85 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSkewedChannel(t *testing.T) *ChannelMeta {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	// partition 10 holds 700 rows, 11 and 12 hold 200 and 100, the mean is 333.3
+	for _, seg := range []struct {
+		segID   UniqueID
+		partID  UniqueID
+		numRows int64
+	}{
+		{1, 10, 400},
+		{2, 10, 300},
+		{3, 11, 200},
+		{4, 12, 100},
+	} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       seg.segID,
+			collID:      1,
+			partitionID: seg.partID,
+		}))
+		channel.updateStatistics(seg.segID, seg.numRows)
+	}
+	return channel
+}
+
+func TestChannelMeta_detectPartitionSkew(t *testing.T) {
+	channel := newSkewedChannel(t)
+
+	t.Run("skewed partitions", func(t *testing.T) {
+		reports, err := channel.detectPartitionSkew(1, 0.5)
+		require.NoError(t, err)
+		require.Len(t, reports, 2)
+		assert.Equal(t, UniqueID(10), reports[0].PartitionID)
+		assert.Equal(t, int64(700), reports[0].NumRows)
+		assert.InDelta(t, 1.1, reports[0].Deviation, 1e-9)
+		assert.Equal(t, UniqueID(12), reports[1].PartitionID)
+		assert.InDelta(t, -0.7, reports[1].Deviation, 1e-9)
+	})
+
+	t.Run("high threshold", func(t *testing.T) {
+		reports, err := channel.detectPartitionSkew(1, 2)
+		require.NoError(t, err)
+		assert.Empty(t, reports)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		_, err := channel.detectPartitionSkew(2, 0.5)
+		assert.Error(t, err)
+		_, err = channel.detectPartitionSkew(1, -1)
+		assert.Error(t, err)
+	})
+
+	t.Run("empty channel", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		reports, err := channel.detectPartitionSkew(1, 0)
+		require.NoError(t, err)
+		assert.Empty(t, reports)
+	})
+}
@@ -0,0 +1,91 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"sort"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+// snapshotMaxAttempts is the number of chunked scans tried before falling back to a single locked scan.
+const snapshotMaxAttempts = 3
+
+// snapshotSegments returns the infos of all valid segments ordered by segment ID, as of a single point
+// in the mutation history of channel.
+//
+// Segments are scanned in chunks of chunkSize, holding segMu only per chunk so mutations proceed
+// in between. A scan is only accepted if the version did not move from its start to its last chunk,
+// so every chunk saw the same state. If mutations keep interleaving, it falls back to scanning under
+// one read lock.
+func (c *ChannelMeta) snapshotSegments(chunkSize int) []*datapb.SegmentInfo {
+	if chunkSize > 0 {
+		for attempt := 0; attempt < snapshotMaxAttempts; attempt++ {
+			if infos, ok := c.snapshotSegmentsChunked(chunkSize); ok {
+				return infos
+			}
+		}
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+	infos := make([]*datapb.SegmentInfo, 0, len(c.segments))
+	for _, segID := range c.sortedSegmentIDsWithoutLock() {
+		if seg := c.segments[segID]; seg.isValid() {
+			infos = append(infos, c.segmentInfo(seg))
+		}
+	}
+	return infos
+}
+
+func (c *ChannelMeta) snapshotSegmentsChunked(chunkSize int) ([]*datapb.SegmentInfo, bool) {
+	c.segMu.RLock()
+	version := c.version
+	segIDs := c.sortedSegmentIDsWithoutLock()
+	c.segMu.RUnlock()
+
+	infos := make([]*datapb.SegmentInfo, 0, len(segIDs))
+	for start := 0; start < len(segIDs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(segIDs) {
+			end = len(segIDs)
+		}
+
+		c.segMu.RLock()
+		if c.version != version {
+			c.segMu.RUnlock()
+			return nil, false
+		}
+		for _, segID := range segIDs[start:end] {
+			if seg := c.segments[segID]; seg.isValid() {
+				infos = append(infos, c.segmentInfo(seg))
+			}
+		}
+		c.segMu.RUnlock()
+	}
+	return infos, true
+}
+
+// sortedSegmentIDsWithoutLock returns the IDs of all segments in ascending order, caller shall hold the segMu.
+func (c *ChannelMeta) sortedSegmentIDsWithoutLock() []UniqueID {
+	segIDs := make([]UniqueID, 0, len(c.segments))
+	for segID := range c.segments {
+		segIDs = append(segIDs, segID)
+	}
+	sort.Slice(segIDs, func(i, j int) bool { return segIDs[i] < segIDs[j] })
+	return segIDs
+}
@@ -0,0 +1,131 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+// snapshotModel mirrors the segments of channel as segmentID -> numRows.
+type snapshotModel map[UniqueID]int64
+
+func (m snapshotModel) fingerprint() string {
+	segIDs := make([]UniqueID, 0, len(m))
+	for segID := range m {
+		segIDs = append(segIDs, segID)
+	}
+	sort.Slice(segIDs, func(i, j int) bool { return segIDs[i] < segIDs[j] })
+	var sb strings.Builder
+	for _, segID := range segIDs {
+		fmt.Fprintf(&sb, "%d:%d,", segID, m[segID])
+	}
+	return sb.String()
+}
+
+func snapshotFingerprint(infos []*datapb.SegmentInfo) string {
+	var sb strings.Builder
+	for _, info := range infos {
+		fmt.Fprintf(&sb, "%d:%d,", info.GetID(), info.GetNumOfRows())
+	}
+	return sb.String()
+}
+
+func TestChannelMeta_snapshotSegments(t *testing.T) {
+	channel := newChannel("channel", 1, nil, nil, nil)
+	model := make(snapshotModel)
+	addSegment := func(segID UniqueID) {
+		seg := &Segment{collectionID: 1, segmentID: segID}
+		seg.setType(datapb.SegmentType_Normal)
+		channel.segMu.Lock()
+		channel.putSegmentWithoutLock(seg)
+		channel.segMu.Unlock()
+		model[segID] = 0
+	}
+	for i := 0; i < 100; i++ {
+		addSegment(UniqueID(i))
+	}
+
+	t.Run("quiescent", func(t *testing.T) {
+		assert.Equal(t, model.fingerprint(), snapshotFingerprint(channel.snapshotSegments(7)))
+		assert.Equal(t, model.fingerprint(), snapshotFingerprint(channel.snapshotSegments(0)))
+	})
+
+	t.Run("mutations during snapshot", func(t *testing.T) {
+		// every state the mutation history passes through
+		history := map[string]struct{}{model.fingerprint(): {}}
+		r := rand.New(rand.NewSource(0))
+		nextID := UniqueID(100)
+
+		var wg sync.WaitGroup
+		done := make(chan struct{})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done)
+			for i := 0; i < 2000; i++ {
+				op := r.Intn(4)
+				if len(model) == 0 {
+					op = 0
+				}
+				switch op {
+				case 0:
+					addSegment(nextID)
+					nextID++
+				case 1:
+					segIDs := channel.listAllSegmentIDs()
+					segID := segIDs[r.Intn(len(segIDs))]
+					channel.removeSegments(segID)
+					delete(model, segID)
+				default:
+					segIDs := channel.listAllSegmentIDs()
+					segID := segIDs[r.Intn(len(segIDs))]
+					channel.updateStatistics(segID, 1)
+					model[segID]++
+				}
+				history[model.fingerprint()] = struct{}{}
+			}
+		}()
+
+		var snapshots []string
+	loop:
+		for {
+			select {
+			case <-done:
+				break loop
+			default:
+				snapshots = append(snapshots, snapshotFingerprint(channel.snapshotSegments(4)))
+			}
+		}
+		wg.Wait()
+
+		require.NotEmpty(t, snapshots)
+		for _, snapshot := range snapshots {
+			assert.Contains(t, history, snapshot)
+		}
+		assert.Equal(t, model.fingerprint(), snapshotFingerprint(channel.snapshotSegments(4)))
+	})
+}
@@ -0,0 +1,312 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+// StatsBatch holds the segment statistics collected by collectStatsInto.
+//
+// The batch owns its backing slice and SegmentStats objects and reuses them across calls,
+// so Stats and every SegmentStats in it are only valid until the next collectStatsInto
+// with the same batch. Copy (proto.Clone) anything that must outlive the next call,
+// and never hand the stats to an asynchronous consumer.
+type StatsBatch struct {
+	Stats []*datapb.SegmentStats
+}
+
+// collectStatsInto refills batch with the statistics of all valid segments, in no particular order.
+// Only the growth of the channel allocates, a batch of sufficient capacity is refilled without allocation.
+func (c *ChannelMeta) collectStatsInto(batch *StatsBatch) error {
+	if batch == nil {
+		return errors.New("nil stats batch")
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	stats := batch.Stats[:0]
+	for _, seg := range c.segments {
+		if !seg.isValid() {
+			continue
+		}
+		n := len(stats)
+		if n < cap(stats) {
+			stats = stats[:n+1]
+		} else {
+			stats = append(stats, nil)
+		}
+		stat := stats[n]
+		if stat == nil {
+			stat = &datapb.SegmentStats{}
+			stats[n] = stat
+		} else {
+			stat.Reset()
+		}
+		stat.SegmentID = seg.segmentID
+		stat.NumRows = seg.numRows
+	}
+	batch.Stats = stats
+	return nil
+}
+
+// batchGetSegmentStatisticsUpdates gives the statistics updates of segments under a single
+// acquisition of segMu, and the IDs of the segments not found.
+func (c *ChannelMeta) batchGetSegmentStatisticsUpdates(segIDs []UniqueID) ([]*datapb.SegmentStats, []UniqueID) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	stats := make([]*datapb.SegmentStats, 0, len(segIDs))
+	var missing []UniqueID
+	for _, segID := range segIDs {
+		seg, ok := c.segments[segID]
+		if !ok || !seg.isValid() {
+			missing = append(missing, segID)
+			continue
+		}
+		stats = append(stats, &datapb.SegmentStats{SegmentID: segID, NumRows: seg.numRows})
+	}
+	return stats, missing
+}
+
+// collectStats returns the statistics of all valid segments ordered by segment ID, split into batches
+// of at most maxBatchBytes each, see splitStatsBatches.
+func (c *ChannelMeta) collectStats(maxBatchBytes int) [][]*datapb.SegmentStats {
+	c.segMu.RLock()
+	stats := make([]*datapb.SegmentStats, 0, len(c.segments))
+	for _, seg := range c.segments {
+		if seg.isValid() {
+			stats = append(stats, &datapb.SegmentStats{SegmentID: seg.segmentID, NumRows: seg.numRows})
+		}
+	}
+	c.segMu.RUnlock()
+
+	sort.Slice(stats, func(i, j int) bool { return stats[i].GetSegmentID() < stats[j].GetSegmentID() })
+	return splitStatsBatches(stats, maxBatchBytes)
+}
+
+// splitStatsBatches splits stats in order into batches whose encoded size stays within maxBatchBytes,
+// so each batch fits a message of the msgstream and is produced on its own. There is always one batch
+// at least, possibly empty. A single statistics larger than maxBatchBytes gets a batch of its own with
+// a warning rather than holding back the others. Non-positive maxBatchBytes disables the split.
+func splitStatsBatches(stats []*datapb.SegmentStats, maxBatchBytes int) [][]*datapb.SegmentStats {
+	if maxBatchBytes <= 0 || len(stats) == 0 {
+		return [][]*datapb.SegmentStats{stats}
+	}
+
+	var (
+		batches   [][]*datapb.SegmentStats
+		batch     []*datapb.SegmentStats
+		batchSize int
+	)
+	for _, stat := range stats {
+		// the size of the stat as an element of the repeated field, the tag and length prefix included
+		size := proto.Size(stat)
+		size += 1 + proto.SizeVarint(uint64(size))
+		if size > maxBatchBytes {
+			log.Warn("segment statistics exceed the batch size, sent alone",
+				zap.Int64("segmentID", stat.GetSegmentID()), zap.Int("size", size), zap.Int("maxBatchBytes", maxBatchBytes))
+		}
+		if len(batch) > 0 && batchSize+size > maxBatchBytes {
+			batches = append(batches, batch)
+			batch, batchSize = nil, 0
+		}
+		batch = append(batch, stat)
+		batchSize += size
+	}
+	return append(batches, batch)
+}
+
+// updateBufferedSize sets the bytes of the segment in the insert buffer, 0 once the buffer is synced.
+func (c *ChannelMeta) updateBufferedSize(segID UniqueID, bytes int64) {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	if seg, ok := c.segments[segID]; ok {
+		seg.bufferedSize = bytes
+	}
+}
+
+// addBinlogSize adds the bytes of the insert binlogs written by a flush of the segment.
+func (c *ChannelMeta) addBinlogSize(segID UniqueID, bytes int64) {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	if seg, ok := c.segments[segID]; ok {
+		seg.binlogSize += bytes
+	}
+}
+
+// insertBinlogSize returns the bytes of the insert binlogs.
+func insertBinlogSize(fieldBinlogs []*datapb.FieldBinlog) int64 {
+	var size int64
+	for _, fieldBinlog := range fieldBinlogs {
+		for _, binlog := range fieldBinlog.GetBinlogs() {
+			size += binlog.GetLogSize()
+		}
+	}
+	return size
+}
+
+// insertLogSize returns the bytes of the insert binlogs written by a flush.
+func insertLogSize(insertLogs map[UniqueID]*datapb.Binlog) int64 {
+	var size int64
+	for _, binlog := range insertLogs {
+		size += binlog.GetLogSize()
+	}
+	return size
+}
+
+// getSegmentSizePercentiles returns the requested percentiles, in [0, 100], of the sizes of valid segments
+// in the collection by the nearest-rank method. The size of a segment is its memory size, the bytes in the
+// insert buffer plus the bytes of its insert binlogs, recovered segments included.
+func (c *ChannelMeta) getSegmentSizePercentiles(collID UniqueID, percentiles []float64) (map[float64]int64, error) {
+	if !c.validCollection(collID) {
+		return nil, fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+	for _, p := range percentiles {
+		if math.IsNaN(p) || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid percentile %v, must be in [0, 100]", p)
+		}
+	}
+
+	c.segMu.RLock()
+	sizes := make([]int64, 0, len(c.segments))
+	for _, seg := range c.segments {
+		if seg.isValid() {
+			sizes = append(sizes, seg.bufferedSize+seg.binlogSize)
+		}
+	}
+	c.segMu.RUnlock()
+
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no segment of collection %d", collID)
+	}
+	sort.Slice(sizes, func(i, j int) bool { return sizes[i] < sizes[j] })
+
+	result := make(map[float64]int64, len(percentiles))
+	for _, p := range percentiles {
+		rank := int(math.Ceil(p / 100 * float64(len(sizes))))
+		if rank < 1 {
+			rank = 1
+		}
+		result[p] = sizes[rank-1]
+	}
+	return result, nil
+}
+
+// getCollectionRows returns the rows of the valid segments of the collection from the cached total.
+func (c *ChannelMeta) getCollectionRows(collID UniqueID) (int64, error) {
+	if !c.validCollection(collID) {
+		return 0, fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+	return c.collectionRows, nil
+}
+
+// CollectionStats is the roll-up of the valid segments of a collection on this node.
+// Collections without segments are rolled up as well, with zero counts.
+type CollectionStats struct {
+	CollectionID       UniqueID   `json:"collection_id"`
+	NumChannels        int        `json:"num_channels"`
+	NumSegments        int        `json:"num_segments"`
+	NumGrowingSegments int        `json:"num_growing_segments"`
+	NumFlushedSegments int        `json:"num_flushed_segments"`
+	NumRows            int64      `json:"num_rows"`
+	PartitionIDs       []UniqueID `json:"partition_ids"` // sorted, empty rather than nil
+	// CreatedAt is when the earliest channel of the collection is created on this node,
+	// ReceivedSegments whether any of its channels ever had a segment
+	CreatedAt        time.Time `json:"created_at"`
+	ReceivedSegments bool      `json:"received_segments"`
+	// the insert buffer syncs of the segments before they are flushed, see recordSpill
+	SpillCount   int64 `json:"spill_count"`
+	SpilledBytes int64 `json:"spilled_bytes"`
+}
+
+// getCollectionStats returns the roll-up of the valid segments of the collection in this channel, in one pass under segMu.
+func (c *ChannelMeta) getCollectionStats(collID UniqueID) (*CollectionStats, error) {
+	if !c.validCollection(collID) {
+		return nil, fmt.Errorf("%w, want %d, actual %d", errMismatchCollection, c.collectionID, collID)
+	}
+
+	stats := &CollectionStats{CollectionID: collID, NumChannels: 1, PartitionIDs: []UniqueID{}, CreatedAt: c.createdAt}
+	partitions := make(map[UniqueID]struct{})
+	c.segMu.RLock()
+	stats.ReceivedSegments = c.receivedSegments
+	if !c.collectionDroppedWithoutLock() {
+		for _, seg := range c.segments {
+			if !seg.isValid() {
+				continue
+			}
+			stats.NumSegments++
+			if seg.notFlushed() {
+				stats.NumGrowingSegments++
+			} else {
+				stats.NumFlushedSegments++
+			}
+			stats.NumRows += seg.numRows
+			stats.SpillCount += seg.spillCount
+			stats.SpilledBytes += seg.spilledBytes
+			partitions[seg.getPartitionID()] = struct{}{}
+		}
+	}
+	c.segMu.RUnlock()
+
+	for partID := range partitions {
+		stats.PartitionIDs = append(stats.PartitionIDs, partID)
+	}
+	sort.Slice(stats.PartitionIDs, func(i, j int) bool { return stats.PartitionIDs[i] < stats.PartitionIDs[j] })
+	return stats, nil
+}
+
+// merge adds the stats of another channel of the same collection.
+func (s *CollectionStats) merge(other *CollectionStats) {
+	s.NumChannels += other.NumChannels
+	s.NumSegments += other.NumSegments
+	s.NumGrowingSegments += other.NumGrowingSegments
+	s.NumFlushedSegments += other.NumFlushedSegments
+	s.NumRows += other.NumRows
+	s.SpillCount += other.SpillCount
+	s.SpilledBytes += other.SpilledBytes
+	if s.CreatedAt.IsZero() || (!other.CreatedAt.IsZero() && other.CreatedAt.Before(s.CreatedAt)) {
+		s.CreatedAt = other.CreatedAt
+	}
+	s.ReceivedSegments = s.ReceivedSegments || other.ReceivedSegments
+
+	partitions := make(map[UniqueID]struct{}, len(s.PartitionIDs)+len(other.PartitionIDs))
+	for _, partID := range append(s.PartitionIDs, other.PartitionIDs...) {
+		partitions[partID] = struct{}{}
+	}
+	s.PartitionIDs = s.PartitionIDs[:0]
+	for partID := range partitions {
+		s.PartitionIDs = append(s.PartitionIDs, partID)
+	}
+	sort.Slice(s.PartitionIDs, func(i, j int) bool { return s.PartitionIDs[i] < s.PartitionIDs[j] })
+}
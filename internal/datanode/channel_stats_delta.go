@@ -0,0 +1,82 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+)
+
+// StatsDelta is the statistics of a segment changed since its last report, the unchanged fields are nil.
+// Positions are large and rarely change, reporting the row count alone saves sending them every time.
+type StatsDelta struct {
+	SegmentID     UniqueID
+	NumRows       *int64
+	StartPosition *internalpb.MsgPosition
+	EndPosition   *internalpb.MsgPosition
+}
+
+// HasChanges returns whether any statistics changed since the last report.
+func (d *StatsDelta) HasChanges() bool {
+	return d.NumRows != nil || d.StartPosition != nil || d.EndPosition != nil
+}
+
+// getSegmentStatisticsDelta returns the statistics of the segment changed since the last call for it,
+// and records them as reported. The first call of a segment reports all its statistics.
+func (c *ChannelMeta) getSegmentStatisticsDelta(segID UniqueID) (*StatsDelta, error) {
+	c.segMu.Lock()
+	defer c.segMu.Unlock()
+
+	seg, ok := c.segments[segID]
+	if !ok || !seg.isValid() {
+		return nil, fmt.Errorf("error, there's no segment %d", segID)
+	}
+
+	last := seg.reported
+	if last == nil {
+		last = &StatsDelta{SegmentID: segID}
+	}
+	delta := &StatsDelta{SegmentID: segID}
+	if last.NumRows == nil || *last.NumRows != seg.numRows {
+		numRows := seg.numRows
+		delta.NumRows = &numRows
+	}
+	if seg.startPos != nil && !proto.Equal(last.StartPosition, seg.startPos) {
+		delta.StartPosition = proto.Clone(seg.startPos).(*internalpb.MsgPosition)
+	}
+	if seg.endPos != nil && !proto.Equal(last.EndPosition, seg.endPos) {
+		delta.EndPosition = proto.Clone(seg.endPos).(*internalpb.MsgPosition)
+	}
+
+	numRows := seg.numRows
+	seg.reported = &StatsDelta{
+		SegmentID:     segID,
+		NumRows:       &numRows,
+		StartPosition: last.StartPosition,
+		EndPosition:   last.EndPosition,
+	}
+	if delta.StartPosition != nil {
+		seg.reported.StartPosition = delta.StartPosition
+	}
+	if delta.EndPosition != nil {
+		seg.reported.EndPosition = delta.EndPosition
+	}
+	return delta, nil
+}
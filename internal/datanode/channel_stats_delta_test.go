@@ -0,0 +1,79 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_getSegmentStatisticsDelta(t *testing.T) {
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	startPos := &internalpb.MsgPosition{ChannelName: "channel", MsgID: []byte{1}, Timestamp: 100}
+	require.NoError(t, channel.addSegment(addSegmentReq{
+		segType:     datapb.SegmentType_New,
+		segID:       1,
+		collID:      1,
+		partitionID: 10,
+		startPos:    startPos,
+	}))
+
+	_, err := channel.getSegmentStatisticsDelta(2)
+	assert.Error(t, err)
+
+	// the first report has everything set
+	delta, err := channel.getSegmentStatisticsDelta(1)
+	require.NoError(t, err)
+	assert.True(t, delta.HasChanges())
+	require.NotNil(t, delta.NumRows)
+	assert.Equal(t, int64(0), *delta.NumRows)
+	assert.Equal(t, startPos.GetTimestamp(), delta.StartPosition.GetTimestamp())
+	assert.Nil(t, delta.EndPosition)
+
+	delta, err = channel.getSegmentStatisticsDelta(1)
+	require.NoError(t, err)
+	assert.False(t, delta.HasChanges())
+
+	// only the row count changed
+	channel.updateStatistics(1, 10)
+	delta, err = channel.getSegmentStatisticsDelta(1)
+	require.NoError(t, err)
+	assert.True(t, delta.HasChanges())
+	require.NotNil(t, delta.NumRows)
+	assert.Equal(t, int64(10), *delta.NumRows)
+	assert.Nil(t, delta.StartPosition)
+	assert.Nil(t, delta.EndPosition)
+
+	// the end position changed
+	channel.updateSegmentEndPosition(1, &internalpb.MsgPosition{ChannelName: "channel", MsgID: []byte{2}, Timestamp: 200})
+	delta, err = channel.getSegmentStatisticsDelta(1)
+	require.NoError(t, err)
+	assert.Nil(t, delta.NumRows)
+	assert.Nil(t, delta.StartPosition)
+	assert.Equal(t, Timestamp(200), delta.EndPosition.GetTimestamp())
+
+	// an equal end position set again is no change
+	channel.updateSegmentEndPosition(1, &internalpb.MsgPosition{ChannelName: "channel", MsgID: []byte{2}, Timestamp: 200})
+	delta, err = channel.getSegmentStatisticsDelta(1)
+	require.NoError(t, err)
+	assert.False(t, delta.HasChanges())
+}
@@ -0,0 +1,278 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func newStatsTestChannel(numSegments int) *ChannelMeta {
+	channel := newChannel("channel", 1, nil, nil, nil)
+	for i := 0; i < numSegments; i++ {
+		seg := &Segment{
+			collectionID: 1,
+			segmentID:    UniqueID(i),
+			numRows:      int64(i),
+		}
+		seg.setType(datapb.SegmentType_Normal)
+		channel.putSegmentWithoutLock(seg)
+	}
+	return channel
+}
+
+func TestChannelMeta_collectStatsInto(t *testing.T) {
+	channel := newStatsTestChannel(3)
+	channel.segments[2].setType(datapb.SegmentType_Compacted)
+
+	assert.Error(t, channel.collectStatsInto(nil))
+
+	batch := &StatsBatch{}
+	require.NoError(t, channel.collectStatsInto(batch))
+	require.Len(t, batch.Stats, 2)
+	for _, stat := range batch.Stats {
+		assert.Equal(t, stat.GetSegmentID(), stat.GetNumRows())
+	}
+
+	// the next call reuses the stats objects
+	first := map[*datapb.SegmentStats]struct{}{}
+	for _, stat := range batch.Stats {
+		first[stat] = struct{}{}
+	}
+	channel.updateStatistics(1, 10)
+	require.NoError(t, channel.collectStatsInto(batch))
+	require.Len(t, batch.Stats, 2)
+	for _, stat := range batch.Stats {
+		assert.Contains(t, first, stat)
+		if stat.GetSegmentID() == 1 {
+			assert.Equal(t, int64(11), stat.GetNumRows())
+		}
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		_ = channel.collectStatsInto(batch)
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestChannelMeta_batchGetSegmentStatisticsUpdates(t *testing.T) {
+	channel := newStatsTestChannel(3)
+	channel.segments[2].setType(datapb.SegmentType_Compacted)
+
+	stats, missing := channel.batchGetSegmentStatisticsUpdates([]UniqueID{0, 1, 2, 3})
+	require.Len(t, stats, 2)
+	assert.Equal(t, UniqueID(0), stats[0].GetSegmentID())
+	assert.Equal(t, UniqueID(1), stats[1].GetSegmentID())
+	assert.Equal(t, int64(1), stats[1].GetNumRows())
+	assert.Equal(t, []UniqueID{2, 3}, missing)
+
+	stats, missing = channel.batchGetSegmentStatisticsUpdates(nil)
+	assert.Empty(t, stats)
+	assert.Empty(t, missing)
+}
+
+func BenchmarkChannelMeta_getSegmentStatisticsUpdates(b *testing.B) {
+	channel := newStatsTestChannel(1000)
+	segIDs := channel.listAllSegmentIDs()
+
+	b.Run("per segment", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, segID := range segIDs {
+				_, _ = channel.getSegmentStatisticsUpdates(segID)
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = channel.batchGetSegmentStatisticsUpdates(segIDs)
+		}
+	})
+}
+
+func BenchmarkChannelMeta_collectStats(b *testing.B) {
+	channel := newStatsTestChannel(10000)
+
+	b.Run("allocating", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			segIDs := channel.listAllSegmentIDs()
+			stats := make([]*datapb.SegmentStats, 0, len(segIDs))
+			for _, segID := range segIDs {
+				stat, _ := channel.getSegmentStatisticsUpdates(segID)
+				stats = append(stats, stat)
+			}
+		}
+	})
+	b.Run("reusing", func(b *testing.B) {
+		b.ReportAllocs()
+		batch := &StatsBatch{}
+		for i := 0; i < b.N; i++ {
+			_ = channel.collectStatsInto(batch)
+		}
+	})
+}
+
+func TestChannelMeta_getSegmentSizePercentiles(t *testing.T) {
+	channel := newStatsTestChannel(100)
+	for i := 0; i < 100; i++ {
+		channel.segments[UniqueID(i)].bufferedSize = int64(i + 1)
+	}
+
+	result, err := channel.getSegmentSizePercentiles(1, []float64{0, 50, 99, 100})
+	require.NoError(t, err)
+	assert.Equal(t, map[float64]int64{0: 1, 50: 50, 99: 99, 100: 100}, result)
+
+	t.Run("skewed", func(t *testing.T) {
+		for i := 0; i < 100; i++ {
+			channel.segments[UniqueID(i)].bufferedSize = 0
+			channel.segments[UniqueID(i)].binlogSize = 10
+		}
+		channel.segments[0].binlogSize = 1000
+
+		result, err := channel.getSegmentSizePercentiles(1, []float64{50, 99, 100})
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), result[50])
+		assert.Equal(t, int64(10), result[99])
+		assert.Equal(t, int64(1000), result[100])
+
+		// compacted segments are not counted
+		channel.segments[0].setType(datapb.SegmentType_Compacted)
+		result, err = channel.getSegmentSizePercentiles(1, []float64{100})
+		require.NoError(t, err)
+		assert.Equal(t, int64(10), result[100])
+	})
+
+	t.Run("recovered and flushed", func(t *testing.T) {
+		channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		binlogs := []*datapb.FieldBinlog{
+			{FieldID: 0, Binlogs: []*datapb.Binlog{{LogSize: 100}, {LogSize: 50}}},
+			{FieldID: 100, Binlogs: []*datapb.Binlog{{LogSize: 250}}},
+		}
+		for _, req := range []addSegmentReq{
+			{segType: datapb.SegmentType_Flushed, segID: 1, collID: 1, partitionID: 10, numOfRows: 10, binlogSize: insertBinlogSize(binlogs)},
+			{segType: datapb.SegmentType_Normal, segID: 2, collID: 1, partitionID: 10, numOfRows: 10, binlogSize: insertBinlogSize(binlogs)},
+			{segType: datapb.SegmentType_New, segID: 3, collID: 1, partitionID: 10},
+		} {
+			require.NoError(t, channel.addSegment(req))
+		}
+		// recovered growing segment buffers more, new segment flushes once and buffers again
+		channel.updateBufferedSize(2, 600)
+		channel.addBinlogSize(3, insertLogSize(map[UniqueID]*datapb.Binlog{0: {LogSize: 20}, 100: {LogSize: 180}}))
+		channel.updateBufferedSize(3, 100)
+
+		result, err := channel.getSegmentSizePercentiles(1, []float64{0, 50, 100})
+		require.NoError(t, err)
+		assert.Equal(t, map[float64]int64{0: 300, 50: 400, 100: 1000}, result)
+
+		// synced buffer is counted by the binlogs
+		channel.updateBufferedSize(3, 0)
+		channel.addBinlogSize(3, 100)
+		result, err = channel.getSegmentSizePercentiles(1, []float64{0})
+		require.NoError(t, err)
+		assert.Equal(t, int64(300), result[0])
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := channel.getSegmentSizePercentiles(2, []float64{50})
+		assert.ErrorIs(t, err, errMismatchCollection)
+		_, err = channel.getSegmentSizePercentiles(1, []float64{101})
+		assert.Error(t, err)
+		_, err = channel.getSegmentSizePercentiles(1, []float64{-1})
+		assert.Error(t, err)
+
+		_, err = newStatsTestChannel(0).getSegmentSizePercentiles(1, []float64{50})
+		assert.Error(t, err)
+	})
+}
+
+func TestSplitStatsBatches(t *testing.T) {
+	stat := func(segID UniqueID) *datapb.SegmentStats {
+		return &datapb.SegmentStats{SegmentID: segID, NumRows: 100}
+	}
+	// each stat takes 2 bytes of tag and length plus 2+2 bytes of fields
+	const statSize = 6
+	stats := []*datapb.SegmentStats{stat(1), stat(2), stat(3), stat(4), stat(5)}
+	segIDs := func(batches [][]*datapb.SegmentStats) [][]UniqueID {
+		result := make([][]UniqueID, 0, len(batches))
+		for _, batch := range batches {
+			ids := make([]UniqueID, 0, len(batch))
+			for _, s := range batch {
+				ids = append(ids, s.GetSegmentID())
+			}
+			result = append(result, ids)
+		}
+		return result
+	}
+
+	tests := []struct {
+		description   string
+		stats         []*datapb.SegmentStats
+		maxBatchBytes int
+		expected      [][]UniqueID
+	}{
+		{"no split", stats, 0, [][]UniqueID{{1, 2, 3, 4, 5}}},
+		{"empty", nil, statSize, [][]UniqueID{{}}},
+		{"all fit exactly", stats, 5 * statSize, [][]UniqueID{{1, 2, 3, 4, 5}}},
+		{"one byte short", stats, 5*statSize - 1, [][]UniqueID{{1, 2, 3, 4}, {5}}},
+		{"two per batch", stats, 2*statSize + 1, [][]UniqueID{{1, 2}, {3, 4}, {5}}},
+		{"one per batch", stats, statSize, [][]UniqueID{{1}, {2}, {3}, {4}, {5}}},
+		{"each exceeds the bound", stats, 1, [][]UniqueID{{1}, {2}, {3}, {4}, {5}}},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			batches := splitStatsBatches(test.stats, test.maxBatchBytes)
+			assert.Equal(t, test.expected, segIDs(batches))
+			for _, batch := range batches {
+				// non-positive maxBatchBytes is unbounded
+				if test.maxBatchBytes > 0 && len(batch) > 1 {
+					size := proto.Size(&datapb.DataNodeTtMsg{SegmentsStats: batch})
+					assert.LessOrEqual(t, size, test.maxBatchBytes)
+				}
+			}
+		})
+	}
+
+	t.Run("oversized segment", func(t *testing.T) {
+		big := &datapb.SegmentStats{SegmentID: 3, NumRows: math.MaxInt64}
+		batches := splitStatsBatches([]*datapb.SegmentStats{stat(1), stat(2), big, stat(4)}, 2*statSize)
+		assert.Equal(t, [][]UniqueID{{1, 2}, {3}, {4}}, segIDs(batches))
+	})
+}
+
+func TestChannelMeta_collectStats(t *testing.T) {
+	channel := newStatsTestChannel(100)
+	batches := channel.collectStats(200)
+	require.Greater(t, len(batches), 1)
+
+	var next UniqueID
+	for _, batch := range batches {
+		assert.LessOrEqual(t, proto.Size(&datapb.DataNodeTtMsg{SegmentsStats: batch}), 200)
+		for _, stat := range batch {
+			assert.Equal(t, next, stat.GetSegmentID())
+			assert.Equal(t, int64(next), stat.GetNumRows())
+			next++
+		}
+	}
+	assert.Equal(t, UniqueID(100), next)
+}
@@ -0,0 +1,40 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go/ext"
+
+	"github.com/milvus-io/milvus/internal/util/trace"
+)
+
+// traceChannelMutation runs a channel mutation in a child span of the span in ctx, usually the
+// span the gRPC server interceptor started for the RPC, so that traces reach into the channel meta.
+func traceChannelMutation(ctx context.Context, operation string, segmentID UniqueID, fn func() error) error {
+	sp, _ := trace.StartSpanFromContextWithOperationNameWithSkip(ctx, operation, 3)
+	defer sp.Finish()
+	sp.SetTag("segmentID", segmentID)
+
+	err := fn()
+	if err != nil {
+		ext.Error.Set(sp, true)
+		trace.LogError(sp, err)
+	}
+	return err
+}
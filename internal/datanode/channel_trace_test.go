@@ -0,0 +1,98 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"testing"
+
+	grpc_opentracing "github.com/grpc-ecosystem/go-grpc-middleware/tracing/opentracing"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestTraceChannelMutation(t *testing.T) {
+	tracer := mocktracer.New()
+	origin := opentracing.GlobalTracer()
+	opentracing.SetGlobalTracer(tracer)
+	defer opentracing.SetGlobalTracer(origin)
+
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	const method = "/milvus.proto.data.DataNode/AddImportSegment"
+
+	// the server handler mutates the channel with the context from the interceptor
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		err := traceChannelMutation(ctx, "addSegment", 1, func() error {
+			return channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1})
+		})
+		require.NoError(t, err)
+		err = traceChannelMutation(ctx, "addSegment", 2, func() error {
+			return channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 2, collID: 2})
+		})
+		require.Error(t, err)
+		return nil, nil
+	}
+	serverInterceptor := grpc_opentracing.UnaryServerInterceptor(grpc_opentracing.WithTracer(tracer))
+
+	// the client interceptor injects the client span into outgoing metadata, which is handed to the server
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		_, err := serverInterceptor(metadata.NewIncomingContext(context.Background(), md), req,
+			&grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	clientInterceptor := grpc_opentracing.UnaryClientInterceptor(grpc_opentracing.WithTracer(tracer))
+
+	clientSpan := tracer.StartSpan("insert")
+	ctx := opentracing.ContextWithSpan(context.Background(), clientSpan)
+	require.NoError(t, clientInterceptor(ctx, method, nil, nil, nil, invoker))
+	clientSpan.Finish()
+
+	spans := make(map[string][]*mocktracer.MockSpan)
+	for _, sp := range tracer.FinishedSpans() {
+		spans[sp.OperationName] = append(spans[sp.OperationName], sp)
+	}
+	require.Len(t, spans["insert"], 1)
+	require.Len(t, spans[method], 2) // client and server spans
+	require.Len(t, spans["addSegment"], 2)
+
+	root := spans["insert"][0]
+	var serverSpan *mocktracer.MockSpan
+	for _, sp := range spans[method] {
+		assert.Equal(t, root.SpanContext.TraceID, sp.SpanContext.TraceID)
+		if sp.Tag(string(ext.SpanKind)) == ext.SpanKindRPCServerEnum {
+			serverSpan = sp
+		}
+	}
+	require.NotNil(t, serverSpan)
+
+	for _, sp := range spans["addSegment"] {
+		assert.Equal(t, root.SpanContext.TraceID, sp.SpanContext.TraceID)
+		assert.Equal(t, serverSpan.SpanContext.SpanID, sp.ParentID)
+	}
+	assert.Equal(t, int64(1), spans["addSegment"][0].Tag("segmentID"))
+	assert.Nil(t, spans["addSegment"][0].Tag("error"))
+	assert.Equal(t, true, spans["addSegment"][1].Tag("error"))
+}
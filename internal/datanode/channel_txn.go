@@ -0,0 +1,139 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+)
+
+// Collection is the view of a collection on this node given by withReadTransaction.
+type Collection struct {
+	ID     UniqueID
+	Schema *schemapb.CollectionSchema // nil if not fetched yet by any of its channels
+	// Channels are the sorted names of the channels of the collection on this node
+	Channels []string
+	// Rows are the rows of the valid segments of all the channels
+	Rows int64
+}
+
+// segmentReadLocker is implemented by the channels whose segments can take part in withReadTransaction.
+type segmentReadLocker interface {
+	// readLockSegments read-locks the segments of the channel and returns the valid ones,
+	// they stay unchanged until unlock is called. The schema is nil if not fetched yet.
+	readLockSegments() (schema *schemapb.CollectionSchema, segments []*Segment, unlock func())
+}
+
+var _ segmentReadLocker = &ChannelMeta{}
+
+func (c *ChannelMeta) readLockSegments() (*schemapb.CollectionSchema, []*Segment, func()) {
+	c.schemaMut.RLock()
+	schema := c.collSchema
+	c.schemaMut.RUnlock()
+
+	c.segMu.RLock()
+	if c.collectionDroppedWithoutLock() {
+		return schema, nil, c.segMu.RUnlock
+	}
+	segments := make([]*Segment, 0, len(c.segments))
+	for _, seg := range c.segments {
+		if seg.isValid() {
+			segments = append(segments, seg)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].segmentID < segments[j].segmentID })
+	return schema, segments, c.segMu.RUnlock
+}
+
+// withReadTransaction calls fn with a consistent view of the collections, no segment of their channels on
+// this node changes while fn runs. The segment locks of the channels are taken in the order of collection ID
+// and then channel name, so concurrent transactions over overlapping collections cannot deadlock.
+//
+// The segments are the live ones, sorted by ID, fn shall only read them and shall not call back into
+// the channels, which may block on the locks held. It fails without calling fn if a collection has no channel
+// on this node.
+func (fm *flowgraphManager) withReadTransaction(collectionIDs []UniqueID,
+	fn func(collections map[UniqueID]*Collection, segments map[UniqueID][]*Segment) error) error {
+	type txnChannel struct {
+		collID UniqueID
+		name   string
+		locker segmentReadLocker
+	}
+
+	wanted := make(map[UniqueID]struct{}, len(collectionIDs))
+	for _, collID := range collectionIDs {
+		wanted[collID] = struct{}{}
+	}
+	var channels []txnChannel
+	var err error
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		channel := value.(*dataSyncService).channel
+		if _, ok := wanted[channel.getCollectionID()]; !ok {
+			return true
+		}
+		locker, ok := channel.(segmentReadLocker)
+		if !ok {
+			err = fmt.Errorf("channel %s does not support read transactions", key.(string))
+			return false
+		}
+		channels = append(channels, txnChannel{collID: channel.getCollectionID(), name: key.(string), locker: locker})
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		if channels[i].collID != channels[j].collID {
+			return channels[i].collID < channels[j].collID
+		}
+		return channels[i].name < channels[j].name
+	})
+
+	collections := make(map[UniqueID]*Collection, len(wanted))
+	segments := make(map[UniqueID][]*Segment, len(wanted))
+	for _, ch := range channels {
+		coll, ok := collections[ch.collID]
+		if !ok {
+			coll = &Collection{ID: ch.collID}
+			collections[ch.collID] = coll
+		}
+		coll.Channels = append(coll.Channels, ch.name)
+	}
+	for collID := range wanted {
+		if _, ok := collections[collID]; !ok {
+			return fmt.Errorf("no channel of collection %d on this node", collID)
+		}
+	}
+
+	for _, ch := range channels {
+		schema, segs, unlock := ch.locker.readLockSegments()
+		defer unlock()
+		if collections[ch.collID].Schema == nil {
+			collections[ch.collID].Schema = schema
+		}
+		for _, seg := range segs {
+			collections[ch.collID].Rows += seg.numRows
+		}
+		segments[ch.collID] = append(segments[ch.collID], segs...)
+	}
+	for _, segs := range segments {
+		sort.Slice(segs, func(i, j int) bool { return segs[i].segmentID < segs[j].segmentID })
+	}
+	return fn(collections, segments)
+}
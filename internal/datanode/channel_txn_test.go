@@ -0,0 +1,144 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func newTxnTestManager(t *testing.T) (*flowgraphManager, map[string]*ChannelMeta) {
+	fm := newFlowgraphManager()
+	channels := make(map[string]*ChannelMeta)
+	for i, ch := range []struct {
+		name   string
+		collID UniqueID
+	}{{"ch-0", 1}, {"ch-1", 1}, {"ch-2", 2}} {
+		channel := newChannel(ch.name, ch.collID, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		segID := UniqueID(i + 1)
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: ch.collID, partitionID: 10}))
+		channel.updateStatistics(segID, 10)
+		channels[ch.name] = channel
+		fm.flowgraphs.Store(ch.name, &dataSyncService{channel: channel})
+	}
+	return fm, channels
+}
+
+func TestFlowgraphManager_withReadTransaction(t *testing.T) {
+	t.Run("views", func(t *testing.T) {
+		fm, _ := newTxnTestManager(t)
+		err := fm.withReadTransaction([]UniqueID{2, 1}, func(collections map[UniqueID]*Collection, segments map[UniqueID][]*Segment) error {
+			require.Len(t, collections, 2)
+			assert.Equal(t, []string{"ch-0", "ch-1"}, collections[1].Channels)
+			assert.Equal(t, int64(20), collections[1].Rows)
+			assert.Equal(t, []string{"ch-2"}, collections[2].Channels)
+			assert.Equal(t, int64(10), collections[2].Rows)
+			require.Len(t, segments[1], 2)
+			assert.Equal(t, UniqueID(1), segments[1][0].segmentID)
+			assert.Equal(t, UniqueID(2), segments[1][1].segmentID)
+			require.Len(t, segments[2], 1)
+			return nil
+		})
+		assert.NoError(t, err)
+
+		mockErr := errors.New("mock")
+		assert.ErrorIs(t, fm.withReadTransaction([]UniqueID{1}, func(map[UniqueID]*Collection, map[UniqueID][]*Segment) error {
+			return mockErr
+		}), mockErr)
+
+		called := false
+		assert.Error(t, fm.withReadTransaction([]UniqueID{1, 3}, func(map[UniqueID]*Collection, map[UniqueID][]*Segment) error {
+			called = true
+			return nil
+		}))
+		assert.False(t, called)
+	})
+
+	t.Run("writers wait for the transaction", func(t *testing.T) {
+		fm, channels := newTxnTestManager(t)
+		written := make(chan struct{})
+		err := fm.withReadTransaction([]UniqueID{1, 2}, func(collections map[UniqueID]*Collection, segments map[UniqueID][]*Segment) error {
+			go func() {
+				defer close(written)
+				channels["ch-1"].updateStatistics(2, 5)
+				channels["ch-2"].updateStatistics(3, 5)
+			}()
+			select {
+			case <-written:
+				t.Error("segment updated during the transaction")
+			case <-time.After(50 * time.Millisecond):
+			}
+			assert.Equal(t, int64(10), segments[1][1].numRows)
+			assert.Equal(t, int64(10), segments[2][0].numRows)
+			return nil
+		})
+		require.NoError(t, err)
+		select {
+		case <-written:
+		case <-time.After(5 * time.Second):
+			t.Fatal("writer blocked after the transaction")
+		}
+		rows, err := channels["ch-1"].getCollectionRows(1)
+		require.NoError(t, err)
+		assert.Equal(t, int64(15), rows)
+	})
+
+	t.Run("overlapping transactions do not deadlock", func(t *testing.T) {
+		fm, channels := newTxnTestManager(t)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				ids := []UniqueID{1, 2}
+				if i%2 == 1 {
+					ids = []UniqueID{2, 1}
+				}
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						assert.NoError(t, fm.withReadTransaction(ids, func(map[UniqueID]*Collection, map[UniqueID][]*Segment) error {
+							return nil
+						}))
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 100; j++ {
+						channels["ch-0"].updateStatistics(1, 1)
+						channels["ch-2"].updateStatistics(3, 1)
+					}
+				}()
+			}
+			wg.Wait()
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("deadlock between transactions")
+		}
+	})
+}
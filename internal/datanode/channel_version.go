@@ -0,0 +1,69 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+// ChannelInterfaceVersion is the version of the Channel interface, bumped whenever methods are added to it.
+//
+//	1: the interface before versioning
+//	2: InterfaceVersion, getChannelSafeTimestamp, isActive and batchGetSegmentStatisticsUpdates
+//	3: auditAggregates
+//	4: getCheckpointBlockers
+//	5: checkPartitionRemovable
+//	6: detectPartitionSkew
+//	7: segmentFlushed takes the flushed rows
+//	8: getChannelLag
+//	9: getCollectionStats
+//	10: capabilities
+//	11: updateFieldStatistics
+//	12: addDeletedRows
+//	13: DetectPositionGaps
+//	14: registerBinlogPaths, getSegmentByBinlogPath
+//	15: getSegmentsByCreateTimeRange
+//	16: registerListener
+//	17: addFlushedRows
+//	18: updateBufferedSize, addBinlogSize
+//
+// Code relying on methods added in a version shall check InterfaceVersion of the channel first against
+// the constant of the feature below, a wrapper may embed an implementation compiled against an older
+// interface. Methods of version 2 need no check, as InterfaceVersion came with them.
+const ChannelInterfaceVersion = channelVersionSegmentSize
+
+// The Channel interface version each feature is added in.
+const (
+	channelVersionSafeTimestamp         = 2
+	channelVersionAuditAggregates       = 3
+	channelVersionCheckpointBlockers    = 4
+	channelVersionPartitionRemovable    = 5
+	channelVersionPartitionSkew         = 6
+	channelVersionFlushedRows           = 7
+	channelVersionChannelLag            = 8
+	channelVersionCollectionStats       = 9
+	channelVersionCapabilities          = 10
+	channelVersionFieldStatistics       = 11
+	channelVersionDeletedRows           = 12
+	channelVersionPositionGaps          = 13
+	channelVersionBinlogIndex           = 14
+	channelVersionCreateTimeRange       = 15
+	channelVersionListeners             = 16
+	channelVersionCumulativeFlushedRows = 17
+	channelVersionSegmentSize           = 18
+)
+
+// InterfaceVersion returns the version of the Channel interface ChannelMeta implements.
+func (c *ChannelMeta) InterfaceVersion() int {
+	return ChannelInterfaceVersion
+}
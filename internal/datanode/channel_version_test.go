@@ -0,0 +1,74 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus-proto/go-api/commonpb"
+)
+
+// legacyChannel is an implementation compiled against the version 1 interface: it has every
+// other method of Channel, but not InterfaceVersion, which the field of the same name shadows.
+// Asserting `var _ Channel = &legacyChannel{}` does not compile.
+type legacyChannel struct {
+	Channel
+	InterfaceVersion struct{}
+}
+
+// olderChannel is an implementation reporting the version 1 interface.
+type olderChannel struct {
+	Channel
+}
+
+func (oc *olderChannel) InterfaceVersion() int {
+	return 1
+}
+
+// newerChannel is an implementation reporting a version newer than RetryingChannel is written against.
+type newerChannel struct {
+	Channel
+}
+
+func (nc *newerChannel) InterfaceVersion() int {
+	return retryingChannelVersion + 1
+}
+
+func TestChannelInterfaceVersion(t *testing.T) {
+	channelType := reflect.TypeOf((*Channel)(nil)).Elem()
+	assert.False(t, reflect.TypeOf(&legacyChannel{}).Implements(channelType))
+	assert.True(t, reflect.TypeOf(&ChannelMeta{}).Implements(channelType))
+
+	channel := &ChannelMeta{}
+	assert.Equal(t, ChannelInterfaceVersion, channel.InterfaceVersion())
+
+	t.Run("wrapper reports the lower version", func(t *testing.T) {
+		assert.Equal(t, retryingChannelVersion, newRetryingChannel(channel).InterfaceVersion())
+		assert.Equal(t, 1, newRetryingChannel(&olderChannel{Channel: channel}).InterfaceVersion())
+		assert.Equal(t, retryingChannelVersion, newRetryingChannel(&newerChannel{Channel: channel}).InterfaceVersion())
+	})
+
+	t.Run("metrics of features newer than the channel", func(t *testing.T) {
+		older := &olderChannel{Channel: channel}
+		resp := checkpointBlockersResponse(older, checkpointBlockersRequest{Channel: "channel"})
+		assert.Equal(t, commonpb.ErrorCode_UnexpectedError, resp.GetStatus().GetErrorCode())
+		assert.Contains(t, resp.GetStatus().GetReason(), "interface version 1")
+	})
+}
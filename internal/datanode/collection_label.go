@@ -0,0 +1,152 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NameLabelPolicy decides how a collection is identified in metric labels and logs.
+type NameLabelPolicy int32
+
+const (
+	// NameLabelIDOnly identifies a collection by its ID.
+	NameLabelIDOnly NameLabelPolicy = iota
+	// NameLabelFull identifies a collection by its name, or its ID if the name is unknown.
+	NameLabelFull
+	// NameLabelHashed identifies a collection by a stable 8 hex digits hash of its name,
+	// or its ID if the name is unknown.
+	NameLabelHashed
+)
+
+func (p NameLabelPolicy) String() string {
+	switch p {
+	case NameLabelFull:
+		return "full"
+	case NameLabelHashed:
+		return "hashed"
+	default:
+		return "idOnly"
+	}
+}
+
+// parseNameLabelPolicy parses the dataNode.metrics.collectionLabelPolicy config.
+func parseNameLabelPolicy(s string) (NameLabelPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "idonly":
+		return NameLabelIDOnly, nil
+	case "full":
+		return NameLabelFull, nil
+	case "hashed":
+		return NameLabelHashed, nil
+	default:
+		return NameLabelIDOnly, fmt.Errorf("unknown collection label policy %q", s)
+	}
+}
+
+// CollectionLabel is a mapping from a collection label back to the collection, as listed by the debug dump.
+type CollectionLabel struct {
+	Label          string   `json:"label"`
+	CollectionID   UniqueID `json:"collection_id"`
+	CollectionName string   `json:"collection_name,omitempty"`
+}
+
+// collectionLabeler renders collection identities into metric labels and log fields, every such site
+// of datanode goes through it so that the policy applies uniformly.
+type collectionLabeler struct {
+	mu     sync.RWMutex
+	policy NameLabelPolicy
+	names  map[UniqueID]string // collectionID -> collection name
+}
+
+// collectionLabels is the labeler of this datanode, its policy is set on DataNode.Init.
+var collectionLabels = newCollectionLabeler(NameLabelIDOnly)
+
+func newCollectionLabeler(policy NameLabelPolicy) *collectionLabeler {
+	return &collectionLabeler{
+		policy: policy,
+		names:  make(map[UniqueID]string),
+	}
+}
+
+func (l *collectionLabeler) setPolicy(policy NameLabelPolicy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.policy = policy
+}
+
+// register records the name of the collection, an empty name is ignored.
+func (l *collectionLabeler) register(collID UniqueID, name string) {
+	if name == "" {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.names[collID] = name
+}
+
+// unregister forgets the name of the collection once it has no channel on this datanode.
+func (l *collectionLabeler) unregister(collID UniqueID) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.names, collID)
+}
+
+// label returns the label of the collection by the policy.
+func (l *collectionLabeler) label(collID UniqueID) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.labelWithoutLock(collID)
+}
+
+func (l *collectionLabeler) labelWithoutLock(collID UniqueID) string {
+	name, ok := l.names[collID]
+	switch {
+	case !ok || l.policy == NameLabelIDOnly:
+		return fmt.Sprint(collID)
+	case l.policy == NameLabelHashed:
+		return hashCollectionName(name)
+	default:
+		return name
+	}
+}
+
+// mappings lists the labels of the registered collections, sorted by collection ID.
+func (l *collectionLabeler) mappings() []CollectionLabel {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	labels := make([]CollectionLabel, 0, len(l.names))
+	for collID, name := range l.names {
+		labels = append(labels, CollectionLabel{
+			Label:          l.labelWithoutLock(collID),
+			CollectionID:   collID,
+			CollectionName: name,
+		})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].CollectionID < labels[j].CollectionID })
+	return labels
+}
+
+func hashCollectionName(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
@@ -0,0 +1,105 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNameLabelPolicy(t *testing.T) {
+	for s, expected := range map[string]NameLabelPolicy{
+		"":       NameLabelIDOnly,
+		"idOnly": NameLabelIDOnly,
+		"full":   NameLabelFull,
+		"Hashed": NameLabelHashed,
+	} {
+		policy, err := parseNameLabelPolicy(s)
+		require.NoError(t, err)
+		assert.Equal(t, expected, policy)
+	}
+	_, err := parseNameLabelPolicy("name")
+	assert.Error(t, err)
+}
+
+func TestCollectionLabeler(t *testing.T) {
+	tests := []struct {
+		policy   NameLabelPolicy
+		expected string
+	}{
+		{NameLabelIDOnly, "1"},
+		{NameLabelFull, "books"},
+		{NameLabelHashed, hashCollectionName("books")},
+	}
+	for _, test := range tests {
+		t.Run(test.policy.String(), func(t *testing.T) {
+			l := newCollectionLabeler(test.policy)
+			l.register(1, "books")
+			assert.Equal(t, test.expected, l.label(1))
+			// unknown names fall back to the ID
+			assert.Equal(t, "2", l.label(2))
+			assert.Equal(t, []CollectionLabel{{Label: test.expected, CollectionID: 1, CollectionName: "books"}}, l.mappings())
+
+			l.unregister(1)
+			assert.Equal(t, "1", l.label(1))
+			assert.Empty(t, l.mappings())
+		})
+	}
+
+	t.Run("hashed", func(t *testing.T) {
+		assert.Regexp(t, "^[0-9a-f]{8}$", hashCollectionName("books"))
+		assert.Equal(t, hashCollectionName("books"), hashCollectionName("books"))
+		assert.NotEqual(t, hashCollectionName("books"), hashCollectionName("movies"))
+	})
+
+	t.Run("bounded cardinality", func(t *testing.T) {
+		for _, policy := range []NameLabelPolicy{NameLabelIDOnly, NameLabelHashed} {
+			l := newCollectionLabeler(policy)
+			labels := make(map[string]struct{})
+			for i := 0; i < 1000; i++ {
+				// long user chosen names of 10 collections, re-registered over and over
+				collID := UniqueID(i % 10)
+				l.register(collID, fmt.Sprintf("collection-with-a-long-name-%d-%s", collID, string(make([]byte, 256))))
+				label := l.label(collID)
+				assert.LessOrEqual(t, len(label), 8)
+				labels[label] = struct{}{}
+			}
+			assert.Len(t, labels, 10, policy.String())
+			assert.Len(t, l.mappings(), 10, policy.String())
+		}
+	})
+}
+
+func TestChannelMeta_registerCollectionLabel(t *testing.T) {
+	defer collectionLabels.setPolicy(NameLabelIDOnly)
+	collectionLabels.setPolicy(NameLabelFull)
+
+	// names registered by other tests of collection 1
+	collectionLabels.unregister(1)
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64, collectionName: "books"}, nil)
+	defer collectionLabels.unregister(1)
+	assert.Equal(t, "1", collectionLabels.label(1))
+
+	sch, err := channel.getCollectionSchema(1, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "books", sch.GetName())
+	assert.Equal(t, "books", collectionLabels.label(1))
+}
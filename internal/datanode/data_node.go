@@ -132,6 +132,8 @@ type DataNode struct {
 	rowIDAllocator *allocator2.IDAllocator
 
 	closer io.Closer
+	// pushes DataNode metrics to dataNode.metrics.remoteWrite.url, nil when it is not configured
+	remoteWrite *metrics.RemoteWriteExporter
 
 	factory dependency.Factory
 }
@@ -259,6 +261,13 @@ func (node *DataNode) Init() error {
 	}
 	node.rowIDAllocator = idAllocator
 
+	policy, err := parseNameLabelPolicy(Params.DataNodeCfg.CollectionLabelPolicy)
+	if err != nil {
+		log.Warn("DataNode server init with invalid collection label policy, use idOnly", zap.Error(err))
+	}
+	collectionLabels.setPolicy(policy)
+	metrics.DataNodeCollectionLabels.SetMaxLabelValues(Params.DataNodeCfg.MaxCollectionLabels)
+
 	node.factory.Init(Params)
 	log.Info("DataNode server init succeeded",
 		zap.String("MsgChannelSubName", Params.CommonCfg.DataNodeSubName))
@@ -517,9 +526,17 @@ func (node *DataNode) Start() error {
 
 	go node.compactionExecutor.start(node.ctx)
 
+	go node.flowgraphManager.auditAggregatesLoop(node.ctx, aggregateAuditInterval)
+
 	// Start node watch node
 	go node.StartWatchChannels(node.ctx)
 
+	if url := Params.DataNodeCfg.MetricsRemoteWriteURL; url != "" {
+		node.remoteWrite = metrics.NewRemoteWriteExporter(metrics.DataNodeGatherer(),
+			metrics.WithRemoteWriteURL(url, Params.DataNodeCfg.MetricsRemoteWriteInterval))
+		node.remoteWrite.Start()
+	}
+
 	Params.DataNodeCfg.CreatedTime = time.Now()
 	Params.DataNodeCfg.UpdatedTime = time.Now()
 
@@ -707,6 +724,10 @@ func (node *DataNode) Stop() error {
 	node.cancel()
 	node.flowgraphManager.dropAll()
 
+	if node.remoteWrite != nil {
+		node.remoteWrite.Stop()
+	}
+
 	if node.rowIDAllocator != nil {
 		log.Info("close id allocator", zap.String("role", typeutil.DataNodeRole))
 		node.rowIDAllocator.Close()
@@ -816,6 +837,30 @@ func (node *DataNode) GetMetrics(ctx context.Context, req *milvuspb.GetMetricsRe
 		return systemInfoMetrics, nil
 	}
 
+	if metricType == metricsinfo.CheckpointBlockersMetrics {
+		return node.getCheckpointBlockersMetrics(req), nil
+	}
+
+	if metricType == metricsinfo.PartitionSkewMetrics {
+		return node.getPartitionSkewMetrics(req), nil
+	}
+
+	if metricType == metricsinfo.CollectionLabelsMetrics {
+		return node.getCollectionLabelsMetrics(), nil
+	}
+
+	if metricType == metricsinfo.StartupReportMetrics {
+		return node.getStartupReportMetrics(), nil
+	}
+
+	if metricType == metricsinfo.PositionGapsMetrics {
+		return node.getPositionGapsMetrics(req), nil
+	}
+
+	if metricType == metricsinfo.CollectionStatsMetrics {
+		return node.getCollectionStatsMetrics(), nil
+	}
+
 	log.Debug("DataNode.GetMetrics failed, request metric type is not implemented yet",
 		zap.Int64("node_id", paramtable.GetNodeID()),
 		zap.String("req", req.Request),
@@ -829,6 +874,18 @@ func (node *DataNode) GetMetrics(ctx context.Context, req *milvuspb.GetMetricsRe
 	}, nil
 }
 
+// WatchReplicaEvents streams the segment changes of the channels on this node to the client in real time,
+// see WatchReplicaEventsRequest. Events are dropped rather than blocking the channels when the client falls
+// behind, the next event sent carries the number dropped.
+func (node *DataNode) WatchReplicaEvents(req *WatchReplicaEventsRequest, stream ReplicaEventStream) error {
+	if !node.isHealthy() {
+		return errDataNodeIsUnhealthy(paramtable.GetNodeID())
+	}
+	log.Info("DataNode.WatchReplicaEvents",
+		zap.Int64("collectionID", req.CollectionID), zap.String("channel", req.Channel), zap.Int("bufferSize", req.BufferSize))
+	return node.flowgraphManager.watchReplicaEvents(req, stream)
+}
+
 // Compaction handles compaction request from DataCoord
 // returns status as long as compaction task enqueued or invalid
 func (node *DataNode) Compaction(ctx context.Context, req *datapb.CompactionPlan) (*commonpb.Status, error) {
@@ -965,7 +1022,10 @@ func (node *DataNode) SyncSegments(ctx context.Context, req *datapb.SyncSegments
 	// block all flow graph so it's safe to remove segment
 	ds.fg.Blockall()
 	defer ds.fg.Unblock()
-	if err := channel.mergeFlushedSegments(targetSeg, req.GetPlanID(), req.GetCompactedFrom()); err != nil {
+	err = traceChannelMutation(ctx, "mergeFlushedSegments", targetSeg.segmentID, func() error {
+		return channel.mergeFlushedSegments(targetSeg, req.GetPlanID(), req.GetCompactedFrom())
+	})
+	if err != nil {
 		status.Reason = err.Error()
 		return status, nil
 	}
@@ -1166,8 +1226,8 @@ func (node *DataNode) AddImportSegment(ctx context.Context, req *datapb.AddImpor
 			zap.Int64("segment ID", req.GetSegmentId()))
 		// Add segment as a flushed segment, but set `importing` to true to add extra information of the segment.
 		// By 'extra information' we mean segment info while adding a `SegmentType_Flushed` typed segment.
-		if err := ds.channel.addSegment(
-			addSegmentReq{
+		if err := traceChannelMutation(ctx, "addSegment", req.GetSegmentId(), func() error {
+			return ds.channel.addSegment(addSegmentReq{
 				segType:      datapb.SegmentType_Flushed,
 				segID:        req.GetSegmentId(),
 				collID:       req.GetCollectionId(),
@@ -1186,7 +1246,8 @@ func (node *DataNode) AddImportSegment(ctx context.Context, req *datapb.AddImpor
 				},
 				recoverTs: req.GetBase().GetTimestamp(),
 				importing: true,
-			}); err != nil {
+			})
+		}); err != nil {
 			log.Error("failed to add segment to flow graph",
 				zap.Error(err))
 			return &datapb.AddImportSegmentResponse{
@@ -18,6 +18,7 @@ package datanode
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/rand"
@@ -42,10 +43,12 @@ import (
 	"github.com/milvus-io/milvus/internal/types"
 	"github.com/milvus-io/milvus/internal/util/dependency"
 	"github.com/milvus-io/milvus/internal/util/etcd"
+	"github.com/milvus-io/milvus/internal/util/flowgraph"
 	"github.com/milvus-io/milvus/internal/util/importutil"
 	"github.com/milvus-io/milvus/internal/util/metricsinfo"
 	"github.com/milvus-io/milvus/internal/util/paramtable"
 	"github.com/milvus-io/milvus/internal/util/sessionutil"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -435,6 +438,159 @@ func TestDataNode(t *testing.T) {
 			zap.String("response", resp.Response))
 	})
 
+	t.Run("Test GetMetrics checkpoint blockers", func(t *testing.T) {
+		node := &DataNode{}
+		node.session = &sessionutil.Session{ServerID: 1}
+		node.flowgraphManager = newFlowgraphManager()
+		node.stateCode.Store(commonpb.StateCode_Healthy)
+		node.flowgraphManager.flowgraphs.Store("ch-0", &dataSyncService{channel: newStuckCheckpointChannel(time.Now())})
+
+		request := func(channel string) *milvuspb.GetMetricsRequest {
+			req, err := json.Marshal(map[string]interface{}{
+				metricsinfo.MetricTypeKey: metricsinfo.CheckpointBlockersMetrics,
+				"channel":                 channel,
+				"top_n":                   2,
+			})
+			require.NoError(t, err)
+			return &milvuspb.GetMetricsRequest{Request: string(req)}
+		}
+
+		resp, err := node.GetMetrics(ctx, request("ch-0"))
+		assert.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+		var blockers []SegmentView
+		require.NoError(t, json.Unmarshal([]byte(resp.GetResponse()), &blockers))
+		require.Len(t, blockers, 2)
+		assert.Equal(t, UniqueID(2), blockers[0].SegmentID)
+
+		resp, err = node.GetMetrics(ctx, request("ch-unknown"))
+		assert.NoError(t, err)
+		assert.NotEqual(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+	})
+
+	t.Run("Test GetMetrics partition skew", func(t *testing.T) {
+		node := &DataNode{}
+		node.session = &sessionutil.Session{ServerID: 1}
+		node.flowgraphManager = newFlowgraphManager()
+		node.stateCode.Store(commonpb.StateCode_Healthy)
+		node.flowgraphManager.flowgraphs.Store("ch-0", &dataSyncService{channel: newSkewedChannel(t)})
+
+		request := func(channel string, threshold float64) *milvuspb.GetMetricsRequest {
+			req, err := json.Marshal(map[string]interface{}{
+				metricsinfo.MetricTypeKey: metricsinfo.PartitionSkewMetrics,
+				"channel":                 channel,
+				"threshold":               threshold,
+			})
+			require.NoError(t, err)
+			return &milvuspb.GetMetricsRequest{Request: string(req)}
+		}
+
+		resp, err := node.GetMetrics(ctx, request("ch-0", 1))
+		assert.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+		var reports []PartitionSkewReport
+		require.NoError(t, json.Unmarshal([]byte(resp.GetResponse()), &reports))
+		require.Len(t, reports, 1)
+		assert.Equal(t, UniqueID(10), reports[0].PartitionID)
+
+		resp, err = node.GetMetrics(ctx, request("ch-0", -1))
+		assert.NoError(t, err)
+		assert.NotEqual(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+
+		resp, err = node.GetMetrics(ctx, request("ch-unknown", 1))
+		assert.NoError(t, err)
+		assert.NotEqual(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+	})
+
+	t.Run("Test GetMetrics position gaps", func(t *testing.T) {
+		node := &DataNode{}
+		node.session = &sessionutil.Session{ServerID: 1}
+		node.flowgraphManager = newFlowgraphManager()
+		node.stateCode.Store(commonpb.StateCode_Healthy)
+		channel := newChannel("ch-0", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+		base := time.Now()
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       1,
+			collID:      1,
+			partitionID: 10,
+			startPos:    &internalpb.MsgPosition{ChannelName: "ch-0", Timestamp: tsoutil.ComposeTSByTime(base, 0)},
+			endPos:      &internalpb.MsgPosition{ChannelName: "ch-0", Timestamp: tsoutil.ComposeTSByTime(base.Add(time.Minute), 0)},
+		}))
+		node.flowgraphManager.flowgraphs.Store("ch-0", &dataSyncService{channel: channel})
+
+		request := func(channel string, maxGapMs int64) *milvuspb.GetMetricsRequest {
+			req, err := json.Marshal(map[string]interface{}{
+				metricsinfo.MetricTypeKey: metricsinfo.PositionGapsMetrics,
+				"channel":                 channel,
+				"max_gap_ms":              maxGapMs,
+			})
+			require.NoError(t, err)
+			return &milvuspb.GetMetricsRequest{Request: string(req)}
+		}
+
+		resp, err := node.GetMetrics(ctx, request("ch-0", 1000))
+		assert.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+		var gaps []PositionGap
+		require.NoError(t, json.Unmarshal([]byte(resp.GetResponse()), &gaps))
+		assert.Equal(t, []PositionGap{{SegmentID: 1, Channel: "ch-0", GapMs: time.Minute.Milliseconds()}}, gaps)
+
+		resp, err = node.GetMetrics(ctx, request("ch-0", 2*time.Minute.Milliseconds()))
+		assert.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+		assert.Equal(t, "[]", resp.GetResponse())
+
+		resp, err = node.GetMetrics(ctx, request("ch-0", 0))
+		assert.NoError(t, err)
+		assert.NotEqual(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+
+		resp, err = node.GetMetrics(ctx, request("ch-unknown", 1000))
+		assert.NoError(t, err)
+		assert.NotEqual(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+	})
+
+	t.Run("Test GetMetrics collection stats", func(t *testing.T) {
+		node := &DataNode{}
+		node.session = &sessionutil.Session{ServerID: 1}
+		node.flowgraphManager = newFlowgraphManager()
+		node.stateCode.Store(commonpb.StateCode_Healthy)
+		rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+		withData := newChannel("ch-0", 1, nil, rc, nil)
+		require.NoError(t, withData.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1, partitionID: 10}))
+		withData.updateStatistics(1, 5)
+		node.flowgraphManager.flowgraphs.Store("ch-0", &dataSyncService{channel: withData})
+		node.flowgraphManager.flowgraphs.Store("ch-1", &dataSyncService{channel: newChannel("ch-1", 2, nil, rc, nil)})
+
+		req, err := metricsinfo.ConstructRequestByMetricType(metricsinfo.CollectionStatsMetrics)
+		require.NoError(t, err)
+		resp, err := node.GetMetrics(ctx, req)
+		assert.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+		var stats []*CollectionStats
+		require.NoError(t, json.Unmarshal([]byte(resp.GetResponse()), &stats))
+		require.Len(t, stats, 2)
+		assert.Equal(t, int64(5), stats[0].NumRows)
+		assert.Equal(t, UniqueID(2), stats[1].CollectionID)
+		assert.Zero(t, stats[1].NumSegments)
+		assert.Zero(t, stats[1].NumRows)
+		assert.Contains(t, resp.GetResponse(), `"collection_id":2,"num_channels":1,"num_segments":0`)
+	})
+
+	t.Run("Test GetMetrics collection labels", func(t *testing.T) {
+		collectionLabels.register(1000, "books")
+		defer collectionLabels.unregister(1000)
+
+		req, err := metricsinfo.ConstructRequestByMetricType(metricsinfo.CollectionLabelsMetrics)
+		require.NoError(t, err)
+		resp, err := node.GetMetrics(ctx, req)
+		assert.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+		var labels []CollectionLabel
+		require.NoError(t, json.Unmarshal([]byte(resp.GetResponse()), &labels))
+		assert.Contains(t, labels, CollectionLabel{Label: "1000", CollectionID: 1000, CollectionName: "books"})
+	})
+
 	t.Run("Test Import", func(t *testing.T) {
 		node.rootCoord = &RootCoordFactory{
 			collectionID: 100,
@@ -1087,6 +1243,44 @@ func TestDataNode_GetComponentStates(t *testing.T) {
 	assert.Equal(t, commonpb.ErrorCode_Success, resp.Status.ErrorCode)
 }
 
+// TestDataNode_SyncSegmentsTransitions syncs a compaction into a channel, the target segment being
+// built by SyncSegments without a type.
+func TestDataNode_SyncSegmentsTransitions(t *testing.T) {
+	ctx := context.Background()
+	node := &DataNode{flowgraphManager: newFlowgraphManager()}
+	node.stateCode.Store(commonpb.StateCode_Healthy)
+	channel := newChannel("ch-sync", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	for _, segID := range []UniqueID{100, 200} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_Flushed,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+			numOfRows:   50,
+			recoverTs:   tsoutil.GetCurrentTime(),
+		}))
+	}
+	node.flowgraphManager.flowgraphs.Store("ch-sync", &dataSyncService{channel: channel, fg: flowgraph.NewTimeTickedFlowGraph(ctx)})
+
+	status, err := node.SyncSegments(ctx, &datapb.SyncSegmentsRequest{
+		PlanID:        1,
+		CompactedFrom: []int64{100, 200},
+		CompactedTo:   300,
+		NumOfRows:     100,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, commonpb.ErrorCode_Success, status.GetErrorCode(), status.GetReason())
+	assert.True(t, channel.hasSegment(300, true))
+	assert.False(t, channel.hasSegment(100, true))
+
+	transitions, err := channel.getSegmentTransitions(300)
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, datapb.SegmentType_Flushed, transitions[0].From)
+	assert.Equal(t, datapb.SegmentType_Flushed, transitions[0].To)
+	assert.Equal(t, TransitionCompacted, transitions[0].Reason)
+}
+
 func TestDataNode_ResendSegmentStats(t *testing.T) {
 	etcdCli, err := etcd.GetEtcdClient(&Params.EtcdCfg)
 	assert.Nil(t, err)
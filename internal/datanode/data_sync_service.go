@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -57,6 +58,8 @@ type dataSyncService struct {
 	flushManager     flushManager // flush manager handles flush process
 	chunkManager     storage.ChunkManager
 	compactor        *compactionExecutor // reference to compaction executor
+
+	startupReport StartupReport // how the channel was restored by initNodes
 }
 
 func newDataSyncService(ctx context.Context,
@@ -163,6 +166,8 @@ func (dsService *dataSyncService) initNodes(vchanInfo *datapb.VchannelInfo) erro
 		flushNotifyFunc(dsService), dropVirtualChannelFunc(dsService))
 
 	var err error
+	report := StartupReport{Channel: vchanInfo.GetChannelName(), Source: RestoreEmpty}
+	start := time.Now()
 	// recover segment checkpoints
 	unflushedSegmentInfos, err := dsService.getSegmentInfos(vchanInfo.GetUnflushedSegmentIds())
 	if err != nil {
@@ -172,6 +177,10 @@ func (dsService *dataSyncService) initNodes(vchanInfo *datapb.VchannelInfo) erro
 	if err != nil {
 		return err
 	}
+	report.FetchDuration = time.Since(start)
+	if len(unflushedSegmentInfos)+len(flushedSegmentInfos) > 0 {
+		report.Source = RestoreFromService
+	}
 
 	futures := make([]*concurrency.Future, 0, len(unflushedSegmentInfos)+len(flushedSegmentInfos))
 
@@ -184,8 +193,10 @@ func (dsService *dataSyncService) initNodes(vchanInfo *datapb.VchannelInfo) erro
 				zap.String("Wanted Channel Name", vchanInfo.ChannelName),
 				zap.String("Actual Channel Name", us.GetInsertChannel()),
 			)
+			report.SkippedSegments++
 			continue
 		}
+		report.GrowingSegments++
 
 		log.Info("recover growing segments form checkpoints",
 			zap.String("vChannelName", us.GetInsertChannel()),
@@ -202,11 +213,13 @@ func (dsService *dataSyncService) initNodes(vchanInfo *datapb.VchannelInfo) erro
 				collID:       segment.CollectionID,
 				partitionID:  segment.PartitionID,
 				numOfRows:    segment.GetNumOfRows(),
+				binlogSize:   insertBinlogSize(segment.GetBinlogs()),
 				statsBinLogs: segment.Statslogs,
 				endPos:       segment.GetDmlPosition(),
 				recoverTs:    vchanInfo.GetSeekPosition().GetTimestamp()}); err != nil {
 				return nil, err
 			}
+			dsService.registerBinlogPaths(segment)
 			return nil, nil
 		})
 		futures = append(futures, future)
@@ -221,8 +234,10 @@ func (dsService *dataSyncService) initNodes(vchanInfo *datapb.VchannelInfo) erro
 				zap.String("Wanted Channel Name", vchanInfo.ChannelName),
 				zap.String("Actual Channel Name", fs.GetInsertChannel()),
 			)
+			report.SkippedSegments++
 			continue
 		}
+		report.FlushedSegments++
 		log.Info("recover sealed segments form checkpoints",
 			zap.String("vChannelName", fs.GetInsertChannel()),
 			zap.Int64("segmentID", fs.GetID()),
@@ -237,11 +252,13 @@ func (dsService *dataSyncService) initNodes(vchanInfo *datapb.VchannelInfo) erro
 				collID:       segment.CollectionID,
 				partitionID:  segment.PartitionID,
 				numOfRows:    segment.GetNumOfRows(),
+				binlogSize:   insertBinlogSize(segment.GetBinlogs()),
 				statsBinLogs: segment.Statslogs,
 				recoverTs:    vchanInfo.GetSeekPosition().GetTimestamp(),
 			}); err != nil {
 				return nil, err
 			}
+			dsService.registerBinlogPaths(segment)
 			return nil, nil
 		})
 		futures = append(futures, future)
@@ -251,6 +268,9 @@ func (dsService *dataSyncService) initNodes(vchanInfo *datapb.VchannelInfo) erro
 	if err != nil {
 		return err
 	}
+	report.FinishedAt = time.Now()
+	report.RestoreDuration = report.FinishedAt.Sub(start) - report.FetchDuration
+	report.TotalDuration = report.FinishedAt.Sub(start)
 
 	c := &nodeConfig{
 		msFactory:    dsService.msFactory,
@@ -342,9 +362,27 @@ func (dsService *dataSyncService) initNodes(vchanInfo *datapb.VchannelInfo) erro
 		log.Error("set edges failed in node", zap.String("name", deleteNode.Name()), zap.Error(err))
 		return err
 	}
+
+	// the report is only kept for a channel started, the nodes being built
+	if dsService.channel.InterfaceVersion() >= channelVersionCapabilities {
+		report.Capabilities = dsService.channel.capabilities()
+	}
+	report.log()
+	dsService.startupReport = report
 	return nil
 }
 
+// registerBinlogPaths indexes the binlogs of a recovered segment in the channel, a failure is only logged.
+func (dsService *dataSyncService) registerBinlogPaths(info *datapb.SegmentInfo) {
+	if dsService.channel.InterfaceVersion() < channelVersionBinlogIndex {
+		return
+	}
+	paths := fieldBinlogPaths(info.GetBinlogs(), info.GetStatslogs(), info.GetDeltalogs())
+	if err := dsService.channel.registerBinlogPaths(info.GetID(), paths); err != nil {
+		log.Warn("failed to index binlog paths of recovered segment", zap.Int64("segmentID", info.GetID()), zap.Error(err))
+	}
+}
+
 // getSegmentInfos return the SegmentInfo details according to the given ids through RPC to datacoord
 func (dsService *dataSyncService) getSegmentInfos(segmentIDs []int64) ([]*datapb.SegmentInfo, error) {
 	infoResp, err := dsService.dataCoord.GetSegmentInfo(dsService.ctx, &datapb.GetSegmentInfoRequest{
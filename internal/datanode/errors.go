@@ -24,8 +24,75 @@ import (
 var (
 	// errSegmentStatsNotChanged error stands for segment stats not changed.
 	errSegmentStatsNotChanged = errors.New("segment stats not changed")
+
+	// errMismatchCollection error stands for a request targeting a collection other than the channel's.
+	errMismatchCollection = errors.New("mismatch collection")
+
+	// ErrNoSegmentForChannel stands for no valid segment starting on the requested channel.
+	ErrNoSegmentForChannel = errors.New("no segment for channel")
+
+	// ErrStaleGeneration stands for a conditional operation based on an outdated channel generation.
+	ErrStaleGeneration = errors.New("stale channel generation")
+
+	// ErrDefaultPartitionNotRemovable stands for an attempt to drop the default partition of a collection.
+	ErrDefaultPartitionNotRemovable = errors.New("default partition not removable")
+
+	// ErrSegmentExists stands for adding a segment already in the channel.
+	ErrSegmentExists = errors.New("segment already exists")
+
+	// ErrNamespaceMismatch stands for a segment of a namespace other than the channel's.
+	ErrNamespaceMismatch = errors.New("namespace mismatch")
+
+	// ErrInsufficientHistory stands for too few row count samples of a segment to forecast its growth.
+	ErrInsufficientHistory = errors.New("insufficient row count history")
+
+	// ErrSegmentIDReused stands for adding a segment with the ID of a flushed or dropped segment in the channel.
+	ErrSegmentIDReused = errors.New("segment ID reused")
+
+	// ErrSegmentIDConflict stands for a segment ID held by another channel of the data node, of any collection.
+	ErrSegmentIDConflict = errors.New("segment ID conflict")
+
+	// ErrAccessDenied stands for an actor reading or writing a collection it has no access to.
+	ErrAccessDenied = errors.New("access denied")
+
+	// ErrNoSchemaProvider stands for a schema refresh of a channel without SchemaProvider.
+	ErrNoSchemaProvider = errors.New("no schema provider")
+
+	// ErrCollectionPurged stands for adding a segment to a collection whose segments have been purged.
+	ErrCollectionPurged = errors.New("collection purged")
+
+	// ErrIncompatibleSchema stands for a refreshed schema changing or dropping the existing fields.
+	ErrIncompatibleSchema = errors.New("incompatible schema")
+
+	// errTransient stands for a failure of a remote service or the storage a channel depends on, see markTransient.
+	errTransient = errors.New("transient error")
 )
 
+// transientError marks the error it wraps as transient, keeping it in the chain for errors.Is and errors.As.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+func (e *transientError) Is(target error) bool {
+	return target == errTransient
+}
+
+// markTransient marks err of a remote service or the storage as transient, which IsRetryable retries.
+func markTransient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
 func msgDataNodeIsUnhealthy(nodeID UniqueID) string {
 	return fmt.Sprintf("DataNode %d is not ready", nodeID)
 }
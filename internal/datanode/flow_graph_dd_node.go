@@ -182,7 +182,7 @@ func (ddn *ddNode) Operate(in []Msg) []Msg {
 				Add(float64(proto.Size(&imsg.InsertRequest)))
 
 			metrics.DataNodeConsumeMsgCount.
-				WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), metrics.InsertLabel, fmt.Sprint(ddn.collectionID)).
+				WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), metrics.InsertLabel, collectionLabels.label(ddn.collectionID)).
 				Inc()
 
 			log.Debug("DDNode receive insert messages",
@@ -212,7 +212,7 @@ func (ddn *ddNode) Operate(in []Msg) []Msg {
 				Add(float64(proto.Size(&dmsg.DeleteRequest)))
 
 			metrics.DataNodeConsumeMsgCount.
-				WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), metrics.DeleteLabel, fmt.Sprint(ddn.collectionID)).
+				WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), metrics.DeleteLabel, collectionLabels.label(ddn.collectionID)).
 				Inc()
 			fgMsg.deleteMessages = append(fgMsg.deleteMessages, dmsg)
 		}
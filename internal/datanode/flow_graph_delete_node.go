@@ -237,6 +237,9 @@ func (dn *deleteNode) bufferDeleteMsg(msg *msgstream.DeleteMsg, tr TimeRange) ([
 
 		// store
 		delDataBuf.updateSize(int64(rows))
+		if dn.channel.InterfaceVersion() >= channelVersionDeletedRows {
+			dn.channel.addDeletedRows(segID, int64(rows))
+		}
 		metrics.DataNodeConsumeMsgRowsCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), metrics.DeleteLabel).Add(float64(rows))
 		delDataBuf.updateTimeRange(tr)
 		dn.delBuf.Store(segID, delDataBuf)
@@ -22,6 +22,7 @@ import (
 	"math"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/opentracing/opentracing-go"
@@ -70,6 +71,7 @@ type timeTickLogger struct {
 	start        atomic.Uint64
 	counter      atomic.Int32
 	vChannelName string
+	channel      Channel
 }
 
 func (l *timeTickLogger) LogTs(ts Timestamp) {
@@ -88,7 +90,18 @@ func (l *timeTickLogger) LogTs(ts Timestamp) {
 func (l *timeTickLogger) printLogs(start, end Timestamp) {
 	t1, _ := tsoutil.ParseTS(start)
 	t2, _ := tsoutil.ParseTS(end)
-	log.Debug("IBN timetick log", zap.Time("from", t1), zap.Time("to", t2), zap.Duration("elapsed", t2.Sub(t1)), zap.Uint64("start", start), zap.Uint64("end", end), zap.String("vChannelName", l.vChannelName))
+	var (
+		lag        time.Duration
+		collection string
+	)
+	if l.channel != nil {
+		if l.channel.InterfaceVersion() >= channelVersionChannelLag {
+			lag, _ = l.channel.getChannelLag(l.vChannelName)
+		}
+		collection = collectionLabels.label(l.channel.getCollectionID())
+	}
+	log.Debug("IBN timetick log", zap.Time("from", t1), zap.Time("to", t2), zap.Duration("elapsed", t2.Sub(t1)), zap.Uint64("start", start), zap.Uint64("end", end), zap.String("vChannelName", l.vChannelName),
+		zap.String("collection", collection), zap.Duration("checkpointLag", lag))
 }
 
 func (ibNode *insertBufferNode) Name() string {
@@ -365,6 +378,12 @@ func (ibNode *insertBufferNode) FillInSyncTasks(fgMsg *flowGraphMsg, seg2Upload
 
 	// process drop partition
 	for _, partitionDrop := range fgMsg.dropPartitions {
+		if ibNode.channel.InterfaceVersion() >= channelVersionPartitionRemovable {
+			if err := ibNode.channel.checkPartitionRemovable(partitionDrop); err != nil {
+				log.Warn("(Drop Partition) skip dropping partition", zap.String("channel", ibNode.channelName), zap.Error(err))
+				continue
+			}
+		}
 		segmentIDs := ibNode.channel.listPartitionSegments(partitionDrop)
 		log.Info("(Drop Partition) syncing all segments in the partition",
 			zap.Int64("collectionID", ibNode.channel.getCollectionID()),
@@ -422,8 +441,16 @@ func (ibNode *insertBufferNode) Sync(fgMsg *flowGraphMsg, seg2Upload []UniqueID,
 			log.Error(err.Error())
 			panic(err)
 		}
+		if task.auto && task.buffer != nil {
+			if err := ibNode.channel.recordSpill(task.segmentID, task.buffer.memorySize(), task.buffer.size); err != nil {
+				log.Warn("failed to record segment spill", zap.Int64("segmentID", task.segmentID), zap.Error(err))
+			}
+		}
 		segmentsToSync = append(segmentsToSync, task.segmentID)
 		ibNode.insertBuffer.Delete(task.segmentID)
+		if ibNode.channel.InterfaceVersion() >= channelVersionSegmentSize {
+			ibNode.channel.updateBufferedSize(task.segmentID, 0)
+		}
 		ibNode.channel.RollPKstats(task.segmentID, pkStats)
 		metrics.DataNodeFlushBufferCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), metrics.SuccessLabel).Inc()
 		metrics.DataNodeFlushBufferCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), metrics.TotalLabel).Inc()
@@ -456,6 +483,7 @@ func (ibNode *insertBufferNode) updateSegmentStates(insertMsgs []*msgstream.Inse
 					partitionID: partitionID,
 					startPos:    startPos,
 					endPos:      endPos,
+					ctx:         ibNode.ctx,
 				})
 			if err != nil {
 				log.Error("add segment wrong",
@@ -556,6 +584,9 @@ func (ibNode *insertBufferNode) bufferInsertMsg(msg *msgstream.InsertMsg, endPos
 
 	// store in buffer
 	ibNode.insertBuffer.Store(currentSegID, buffer)
+	if ibNode.channel.InterfaceVersion() >= channelVersionSegmentSize {
+		ibNode.channel.updateBufferedSize(currentSegID, buffer.memorySize())
+	}
 
 	// store current endPositions as Segment->EndPostion
 	ibNode.channel.updateSegmentEndPosition(currentSegID, endPos)
@@ -600,6 +631,15 @@ func (ibNode *insertBufferNode) getCollectionandPartitionIDbySegID(segmentID Uni
 	return ibNode.channel.getCollectionAndPartitionID(segmentID)
 }
 
+// maxMessageSize returns the max message size of the MQ of the msgstream factory, non-positive if unlimited.
+// The pulsar one is assumed if the factory does not tell.
+func maxMessageSize(factory msgstream.Factory) int {
+	if sizer, ok := factory.(msgstream.MaxMessageSizer); ok {
+		return sizer.MaxMessageSize()
+	}
+	return Params.PulsarCfg.MaxMessageSize
+}
+
 func newInsertBufferNode(ctx context.Context, collID UniqueID, flushCh <-chan flushMsg, resendTTCh <-chan resendTTMsg,
 	fm flushManager, flushingSegCache *Cache, config *nodeConfig) (*insertBufferNode, error) {
 
@@ -617,43 +657,53 @@ func newInsertBufferNode(ctx context.Context, collID UniqueID, flushCh <-chan fl
 	log.Info("datanode AsProducer", zap.String("TimeTickChannelName", Params.CommonCfg.DataCoordTimeTick))
 	var wTtMsgStream msgstream.MsgStream = wTt
 	wTtMsgStream.Start()
+	maxMsgSize := maxMessageSize(config.msFactory)
 
 	mt := newMergedTimeTickerSender(func(ts Timestamp, segmentIDs []int64) error {
-		stats := make([]*datapb.SegmentStats, 0, len(segmentIDs))
-		for _, sid := range segmentIDs {
-			stat, err := config.channel.getSegmentStatisticsUpdates(sid)
-			if err != nil {
-				log.Warn("failed to get segment statistics info", zap.Int64("segmentID", sid), zap.Error(err))
-				continue
-			}
-			stats = append(stats, stat)
+		stats, missing := config.channel.batchGetSegmentStatisticsUpdates(segmentIDs)
+		if len(missing) > 0 {
+			log.Warn("failed to get segment statistics info", zap.Int64s("segmentIDs", missing))
 		}
-		msgPack := msgstream.MsgPack{}
-		timeTickMsg := msgstream.DataNodeTtMsg{
-			BaseMsg: msgstream.BaseMsg{
-				BeginTimestamp: ts,
-				EndTimestamp:   ts,
-				HashValues:     []uint32{0},
-			},
-			DataNodeTtMsg: datapb.DataNodeTtMsg{
-				Base: commonpbutil.NewMsgBase(
-					commonpbutil.WithMsgType(commonpb.MsgType_DataNodeTt),
-					commonpbutil.WithMsgID(0),
-					commonpbutil.WithTimeStamp(ts),
-					commonpbutil.WithSourceID(paramtable.GetNodeID()),
-				),
-				ChannelName:   config.vChannelName,
-				Timestamp:     ts,
-				SegmentsStats: stats,
-			},
+		// statistics of many segments are split to fit the max message size, each batch is produced on its own
+		var produceErr error
+		for _, batch := range splitStatsBatches(stats, maxMsgSize) {
+			msgPack := msgstream.MsgPack{}
+			timeTickMsg := msgstream.DataNodeTtMsg{
+				BaseMsg: msgstream.BaseMsg{
+					BeginTimestamp: ts,
+					EndTimestamp:   ts,
+					HashValues:     []uint32{0},
+				},
+				DataNodeTtMsg: datapb.DataNodeTtMsg{
+					Base: commonpbutil.NewMsgBase(
+						commonpbutil.WithMsgType(commonpb.MsgType_DataNodeTt),
+						commonpbutil.WithMsgID(0),
+						commonpbutil.WithTimeStamp(ts),
+						commonpbutil.WithSourceID(paramtable.GetNodeID()),
+					),
+					ChannelName:   config.vChannelName,
+					Timestamp:     ts,
+					SegmentsStats: batch,
+				},
+			}
+			msgPack.Msgs = append(msgPack.Msgs, &timeTickMsg)
+			if err := wTtMsgStream.Produce(&msgPack); err != nil && produceErr == nil {
+				produceErr = err
+			}
 		}
-		msgPack.Msgs = append(msgPack.Msgs, &timeTickMsg)
 		sub := tsoutil.SubByNow(ts)
 		pChan := funcutil.ToPhysicalChannel(config.vChannelName)
 		metrics.DataNodeProduceTimeTickLag.
-			WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), fmt.Sprint(collID), pChan).
+			WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), collectionLabels.label(collID), pChan).
 			Set(float64(sub))
-		return wTtMsgStream.Produce(&msgPack)
+		if config.channel.InterfaceVersion() >= channelVersionChannelLag {
+			if lag, err := config.channel.getChannelLag(config.vChannelName); err == nil {
+				metrics.DataNodeChannelCheckpointLag.
+					WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), config.vChannelName).
+					Set(float64(lag.Milliseconds()))
+			}
+		}
+		return produceErr
 	})
 
 	return &insertBufferNode{
@@ -672,6 +722,6 @@ func newInsertBufferNode(ctx context.Context, collID UniqueID, flushCh <-chan fl
 		idAllocator: config.allocator,
 		channelName: config.vChannelName,
 		ttMerger:    mt,
-		ttLogger:    &timeTickLogger{vChannelName: config.vChannelName},
+		ttLogger:    &timeTickLogger{vChannelName: config.vChannelName, channel: config.channel},
 	}, nil
 }
@@ -35,6 +35,7 @@ import (
 	"github.com/milvus-io/milvus/internal/types"
 	"github.com/milvus-io/milvus/internal/util/dependency"
 	"github.com/milvus-io/milvus/internal/util/flowgraph"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
 	"github.com/milvus-io/milvus/internal/util/retry"
 	"github.com/milvus-io/milvus/internal/util/typeutil"
 	"github.com/samber/lo"
@@ -346,7 +347,7 @@ func TestFlowGraphInsertBufferNode_AutoFlush(t *testing.T) {
 			return pos.GetSegmentID()
 		}))
 		if pack.flushed || pack.dropped {
-			channel.segmentFlushed(pack.segmentID)
+			channel.segmentFlushed(pack.segmentID, unknownFlushedRows)
 		}
 		wg.Done()
 	}, emptyFlushAndDropFunc)
@@ -584,7 +585,7 @@ func TestRollBF(t *testing.T) {
 			return pos.GetSegmentID()
 		}))
 		if pack.flushed || pack.dropped {
-			channel.segmentFlushed(pack.segmentID)
+			channel.segmentFlushed(pack.segmentID, unknownFlushedRows)
 		}
 		wg.Done()
 	}, emptyFlushAndDropFunc)
@@ -802,6 +803,20 @@ func (s *InsertBufferNodeSuit) TestFillInSyncTasks() {
 		}
 	})
 
+	s.Run("drop default partition", func() {
+		fgMsg := flowGraphMsg{dropPartitions: []UniqueID{s.partID}}
+		s.channel.defaultPartitionID = s.partID
+		defer func() { s.channel.defaultPartitionID = 0 }()
+		node := &insertBufferNode{
+			channelName: s.channel.channelName,
+			channel:     s.channel,
+			flushChan:   make(chan flushMsg, 100),
+		}
+
+		syncTasks := node.FillInSyncTasks(&fgMsg, nil)
+		s.Assert().Empty(syncTasks)
+	})
+
 	s.Run("manual sync", func() {
 		flushCh := make(chan flushMsg, 100)
 		node := &insertBufferNode{
@@ -1087,3 +1102,13 @@ func TestInsertBufferNode_collectSegmentsToSync(t *testing.T) {
 		})
 	}
 }
+
+func TestMaxMessageSize(t *testing.T) {
+	assert.Equal(t, 1024, maxMessageSize(msgstream.NewPmsFactory(&paramtable.PulsarConfig{MaxMessageSize: 1024})))
+	assert.Equal(t, 0, maxMessageSize(&msgstream.RmsFactory{}))
+	assert.Equal(t, 2048, maxMessageSize(msgstream.NewKmsFactory(&paramtable.KafkaConfig{
+		ProducerExtraConfig: map[string]string{"message.max.bytes": "2048"},
+	})))
+	// the factory does not tell
+	assert.Equal(t, Params.PulsarCfg.MaxMessageSize, maxMessageSize(&mockMsgStreamFactory{}))
+}
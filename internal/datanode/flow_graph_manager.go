@@ -18,23 +18,35 @@ package datanode
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/milvus-io/milvus-proto/go-api/schemapb"
 	"github.com/milvus-io/milvus/internal/log"
 	"github.com/milvus-io/milvus/internal/metrics"
 	"github.com/milvus-io/milvus/internal/proto/datapb"
 	"github.com/milvus-io/milvus/internal/util/paramtable"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
 
 	"go.uber.org/zap"
 )
 
 type flowgraphManager struct {
-	flowgraphs sync.Map // vChannelName -> dataSyncService
+	flowgraphs    sync.Map // vChannelName -> dataSyncService
+	segmentOwners *segmentOwners
+	clock         func() time.Time
 }
 
 func newFlowgraphManager() *flowgraphManager {
-	return &flowgraphManager{}
+	return &flowgraphManager{segmentOwners: newSegmentOwners(), clock: time.Now}
+}
+
+func (fm *flowgraphManager) now() time.Time {
+	if fm.clock == nil {
+		return time.Now()
+	}
+	return fm.clock()
 }
 
 func (fm *flowgraphManager) addAndStart(dn *DataNode, vchan *datapb.VchannelInfo, schema *schemapb.CollectionSchema) error {
@@ -43,7 +55,12 @@ func (fm *flowgraphManager) addAndStart(dn *DataNode, vchan *datapb.VchannelInfo
 		return nil
 	}
 
-	channel := newChannel(vchan.GetChannelName(), vchan.GetCollectionID(), schema, dn.rootCoord, dn.chunkManager)
+	channel := newChannel(vchan.GetChannelName(), vchan.GetCollectionID(), schema, dn.rootCoord, dn.chunkManager,
+		WithSegmentOwners(fm.segmentOwners))
+	if err := channel.warmUp(); err != nil {
+		log.Warn("failed to warm up channel, caches are built on first access",
+			zap.String("vChannelName", vchan.GetChannelName()), zap.Error(err))
+	}
 
 	var alloc allocatorInterface = newAllocator(dn.rootCoord)
 
@@ -64,8 +81,14 @@ func (fm *flowgraphManager) release(vchanName string) {
 	if fg, loaded := fm.flowgraphs.LoadAndDelete(vchanName); loaded {
 		fg.(*dataSyncService).close()
 		metrics.DataNodeNumFlowGraphs.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Dec()
+		if collID := fg.(*dataSyncService).channel.getCollectionID(); len(fm.getCollectionChannels(collID)) == 0 {
+			metrics.ReleaseDataNodeCollectionLabel(fmt.Sprint(paramtable.GetNodeID()), collectionLabels.label(collID))
+			collectionLabels.unregister(collID)
+		}
+		fm.segmentOwners.releaseChannel(vchanName)
 	}
 	rateCol.removeFlowGraphChannel(vchanName)
+	metrics.DataNodeChannelCheckpointLag.DeleteLabelValues(fmt.Sprint(paramtable.GetNodeID()), vchanName)
 }
 
 func (fm *flowgraphManager) getFlushCh(segID UniqueID) (chan<- flushMsg, error) {
@@ -109,6 +132,144 @@ func (fm *flowgraphManager) getChannel(segID UniqueID) (Channel, error) {
 	return nil, fmt.Errorf("cannot find segment %d in all flowgraphs", segID)
 }
 
+// getCollectionSafeTimestamp returns the timestamp up to which all data of the collection on this node
+// has been accounted, that is the min over the channels of the collection of their safe timestamps,
+// the max end position timestamp of each channel's segments (see getChannelSafeTimestamp).
+// Channels without any end position yet are excluded rather than taken as 0. It returns an error if
+// the collection has no channel on this node or none of its channels has data.
+// The result is also exported by the collection_safe_ts_ms metric in physical milliseconds.
+func (fm *flowgraphManager) getCollectionSafeTimestamp(collectionID UniqueID) (Timestamp, error) {
+	var (
+		safeTs      Timestamp
+		numChannels int
+		numWithData int
+	)
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		fg := value.(*dataSyncService)
+		if fg.channel.getCollectionID() != collectionID {
+			return true
+		}
+		numChannels++
+		ts, ok := fg.channel.getChannelSafeTimestamp()
+		if !ok {
+			return true
+		}
+		if numWithData == 0 || ts < safeTs {
+			safeTs = ts
+		}
+		numWithData++
+		return true
+	})
+
+	if numChannels == 0 {
+		return 0, fmt.Errorf("no channel of collection %d on this node", collectionID)
+	}
+	if numWithData == 0 {
+		return 0, fmt.Errorf("no channel of collection %d has data yet", collectionID)
+	}
+	physical, _ := tsoutil.ParseTS(safeTs)
+	metrics.DataNodeCollectionSafeTimestamp.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), collectionLabels.label(collectionID)).
+		Set(float64(physical.UnixMilli()))
+	return safeTs, nil
+}
+
+// getCollectionChannels returns the sorted names of all channels of the collection on this node, active or not.
+func (fm *flowgraphManager) getCollectionChannels(collectionID UniqueID) []string {
+	var channels []string
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		if value.(*dataSyncService).channel.getCollectionID() == collectionID {
+			channels = append(channels, key.(string))
+		}
+		return true
+	})
+	sort.Strings(channels)
+	return channels
+}
+
+// getActiveChannelCount returns the number of channels of the collection whose end positions
+// moved forward within the duration, and exports it by the active_channel_num metric.
+func (fm *flowgraphManager) getActiveChannelCount(collectionID UniqueID, within time.Duration) int {
+	count := 0
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		channel := value.(*dataSyncService).channel
+		if channel.getCollectionID() == collectionID && channel.isActive(within) {
+			count++
+		}
+		return true
+	})
+	metrics.DataNodeActiveChannelNum.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), collectionLabels.label(collectionID)).
+		Set(float64(count))
+	return count
+}
+
+// getAllCollectionStats returns the stats of every collection on this node, merged over its channels,
+// each channel scanned in one pass under its lock. Collections without segments are included with zero counts,
+// and every collection is exported by the collection_segment_num and collection_row_num metrics.
+func (fm *flowgraphManager) getAllCollectionStats() map[UniqueID]*CollectionStats {
+	all := make(map[UniqueID]*CollectionStats)
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		channel := value.(*dataSyncService).channel
+		if channel.InterfaceVersion() < channelVersionCollectionStats {
+			return true
+		}
+		stats, err := channel.getCollectionStats(channel.getCollectionID())
+		if err != nil {
+			log.Warn("failed to get collection stats", zap.String("channel", key.(string)), zap.Error(err))
+			return true
+		}
+		if merged, ok := all[stats.CollectionID]; ok {
+			merged.merge(stats)
+		} else {
+			all[stats.CollectionID] = stats
+		}
+		return true
+	})
+
+	nodeID := fmt.Sprint(paramtable.GetNodeID())
+	for collID, stats := range all {
+		label := collectionLabels.label(collID)
+		metrics.DataNodeCollectionSegmentNum.WithLabelValues(nodeID, label).Set(float64(stats.NumSegments))
+		metrics.DataNodeCollectionRowNum.WithLabelValues(nodeID, label).Set(float64(stats.NumRows))
+	}
+	return all
+}
+
+// getEmptyCollections returns the sorted IDs of the collections on this node which never had a segment
+// on any of their channels, although created more than olderThan ago, that is the collections the DDL
+// reached but no data did.
+func (fm *flowgraphManager) getEmptyCollections(olderThan time.Duration) []UniqueID {
+	now := fm.now()
+	var empty []UniqueID
+	for collID, stats := range fm.getAllCollectionStats() {
+		if !stats.ReceivedSegments && now.Sub(stats.CreatedAt) > olderThan {
+			empty = append(empty, collID)
+		}
+	}
+	sort.Slice(empty, func(i, j int) bool { return empty[i] < empty[j] })
+	return empty
+}
+
+// getAllSegmentsByCreateTimeRange returns the valid segments of every channel on this node created within
+// [start, end], regardless of collection, ordered by create time. See getSegmentsByCreateTimeRange.
+func (fm *flowgraphManager) getAllSegmentsByCreateTimeRange(start, end Timestamp) []*datapb.SegmentInfo {
+	var creations []SegmentCreation
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		channel := value.(*dataSyncService).channel
+		if channel.InterfaceVersion() < channelVersionCreateTimeRange {
+			return true
+		}
+		creations = append(creations, channel.getSegmentsByCreateTimeRange(start, end)...)
+		return true
+	})
+
+	sortSegmentCreations(creations)
+	infos := make([]*datapb.SegmentInfo, 0, len(creations))
+	for _, creation := range creations {
+		infos = append(infos, creation.Info)
+	}
+	return infos
+}
+
 // resendTT loops through flow graphs, looks for segments that are not flushed,
 // and sends them to that flow graph's `resendTTCh` channel so stats of
 // these segments will be resent.
@@ -19,11 +19,16 @@ package datanode
 import (
 	"context"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
 
 	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/metrics"
 	"github.com/milvus-io/milvus/internal/proto/datapb"
 	"github.com/milvus-io/milvus/internal/proto/internalpb"
 	"github.com/milvus-io/milvus/internal/util/etcd"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -184,3 +189,273 @@ func TestFlowGraphManager(t *testing.T) {
 		assert.Nil(t, fg)
 	})
 }
+
+func TestFlowGraphManager_getCollectionSafeTimestamp(t *testing.T) {
+	newChannelWithEndTs := func(name string, collID UniqueID, endTs ...Timestamp) *ChannelMeta {
+		channel := newChannel(name, collID, nil, nil, nil)
+		for i, ts := range endTs {
+			seg := &Segment{collectionID: collID, segmentID: UniqueID(i), endPos: &internalpb.MsgPosition{Timestamp: ts}}
+			seg.setType(datapb.SegmentType_Normal)
+			channel.putSegmentWithoutLock(seg)
+		}
+		return channel
+	}
+
+	fm := newFlowgraphManager()
+	_, err := fm.getCollectionSafeTimestamp(1)
+	assert.Error(t, err)
+
+	// channel without data is excluded
+	fm.flowgraphs.Store("ch-0", &dataSyncService{channel: newChannelWithEndTs("ch-0", 1)})
+	_, err = fm.getCollectionSafeTimestamp(1)
+	assert.Error(t, err)
+
+	fm.flowgraphs.Store("ch-1", &dataSyncService{channel: newChannelWithEndTs("ch-1", 1, 100, 300)})
+	fm.flowgraphs.Store("ch-2", &dataSyncService{channel: newChannelWithEndTs("ch-2", 1, 200, 250)})
+	fm.flowgraphs.Store("ch-3", &dataSyncService{channel: newChannelWithEndTs("ch-3", 2, 50)})
+
+	// min over channels of max end timestamp of each channel
+	ts, err := fm.getCollectionSafeTimestamp(1)
+	assert.NoError(t, err)
+	assert.Equal(t, Timestamp(250), ts)
+
+	ts, err = fm.getCollectionSafeTimestamp(2)
+	assert.NoError(t, err)
+	assert.Equal(t, Timestamp(50), ts)
+
+	// compacted segments do not count
+	fg, ok := fm.getFlowgraphService("ch-2")
+	require.True(t, ok)
+	fg.channel.(*ChannelMeta).segments[1].setType(datapb.SegmentType_Compacted)
+	ts, err = fm.getCollectionSafeTimestamp(1)
+	assert.NoError(t, err)
+	assert.Equal(t, Timestamp(200), ts)
+}
+
+func TestFlowGraphManager_getActiveChannelCount(t *testing.T) {
+	now := time.Unix(10000, 0)
+	clock := func() time.Time { return now }
+
+	fm := newFlowgraphManager()
+	var channels []*ChannelMeta
+	for _, name := range []string{"ch-0", "ch-1", "ch-2"} {
+		channel := newChannel(name, 1, nil, nil, nil)
+		channel.clock = clock
+		seg := &Segment{collectionID: 1, segmentID: 1}
+		seg.setType(datapb.SegmentType_Normal)
+		channel.putSegmentWithoutLock(seg)
+		channels = append(channels, channel)
+		fm.flowgraphs.Store(name, &dataSyncService{channel: channel})
+	}
+	other := newChannel("ch-3", 2, nil, nil, nil)
+	other.clock = clock
+	fm.flowgraphs.Store("ch-3", &dataSyncService{channel: other})
+
+	assert.Equal(t, 0, fm.getActiveChannelCount(1, time.Minute))
+
+	channels[0].updateSegmentEndPosition(1, &internalpb.MsgPosition{Timestamp: 100})
+	now = now.Add(30 * time.Second)
+	channels[1].updateSegmentEndPosition(1, &internalpb.MsgPosition{Timestamp: 100})
+	assert.Equal(t, 2, fm.getActiveChannelCount(1, time.Minute))
+
+	// ch-0 decays, and an end position not moving forward is no activity of ch-1
+	now = now.Add(45 * time.Second)
+	channels[1].updateSegmentEndPosition(1, &internalpb.MsgPosition{Timestamp: 100})
+	assert.Equal(t, 1, fm.getActiveChannelCount(1, time.Minute))
+
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, 0, fm.getActiveChannelCount(1, time.Minute))
+	assert.Equal(t, 0, fm.getActiveChannelCount(2, time.Minute))
+
+	// inactive channels are still listed
+	assert.Equal(t, []string{"ch-0", "ch-1", "ch-2"}, fm.getCollectionChannels(1))
+	assert.Equal(t, []string{"ch-3"}, fm.getCollectionChannels(2))
+	assert.Empty(t, fm.getCollectionChannels(3))
+}
+
+func TestFlowGraphManager_getAllCollectionStats(t *testing.T) {
+	fm := newFlowgraphManager()
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	base := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	for i, ch := range []struct {
+		name   string
+		collID UniqueID
+		segs   []addSegmentReq
+	}{
+		{"ch-0", 1, []addSegmentReq{
+			{segType: datapb.SegmentType_New, segID: 1, collID: 1, partitionID: 10},
+			{segType: datapb.SegmentType_Flushed, segID: 2, collID: 1, partitionID: 11, numOfRows: 100},
+		}},
+		{"ch-1", 1, []addSegmentReq{
+			{segType: datapb.SegmentType_New, segID: 3, collID: 1, partitionID: 10},
+		}},
+		{"ch-2", 2, []addSegmentReq{
+			{segType: datapb.SegmentType_Flushed, segID: 4, collID: 2, partitionID: 20, numOfRows: 7},
+		}},
+	} {
+		channel := newChannel(ch.name, ch.collID, nil, rc, nil)
+		channel.createdAt = base.Add(-time.Duration(i) * time.Minute)
+		for _, req := range ch.segs {
+			require.NoError(t, channel.addSegment(req))
+			channel.updateStatistics(req.segID, 5)
+			if req.segType == datapb.SegmentType_New {
+				require.NoError(t, channel.recordSpill(req.segID, 1024, 5))
+			}
+		}
+		fm.flowgraphs.Store(ch.name, &dataSyncService{channel: channel})
+	}
+
+	all := fm.getAllCollectionStats()
+	require.Len(t, all, 2)
+	assert.Equal(t, &CollectionStats{
+		CollectionID:       1,
+		NumChannels:        2,
+		NumSegments:        3,
+		NumGrowingSegments: 2,
+		NumFlushedSegments: 1,
+		NumRows:            110,
+		PartitionIDs:       []UniqueID{10, 11},
+		CreatedAt:          base.Add(-time.Minute),
+		ReceivedSegments:   true,
+		SpillCount:         2,
+		SpilledBytes:       2048,
+	}, all[1])
+
+	// matches the per-collection queries
+	for collID, stats := range all {
+		var rows int64
+		var numChannels int
+		for _, name := range fm.getCollectionChannels(collID) {
+			fg, ok := fm.getFlowgraphService(name)
+			require.True(t, ok)
+			channelRows, err := fg.channel.(*ChannelMeta).getCollectionRows(collID)
+			require.NoError(t, err)
+			rows += channelRows
+			numChannels++
+		}
+		assert.Equal(t, rows, stats.NumRows)
+		assert.Equal(t, numChannels, stats.NumChannels)
+	}
+	assert.Equal(t, int64(7), all[2].NumRows)
+}
+
+func TestFlowGraphManager_getAllSegmentsByCreateTimeRange(t *testing.T) {
+	base := time.Now()
+	ts := func(offset time.Duration) Timestamp {
+		return tsoutil.ComposeTSByTime(base.Add(offset), 0)
+	}
+
+	fm := newFlowgraphManager()
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	for _, ch := range []struct {
+		name   string
+		collID UniqueID
+		segs   map[UniqueID]Timestamp
+	}{
+		{"ch-0", 1, map[UniqueID]Timestamp{1: ts(3 * time.Second), 2: ts(-time.Second)}},
+		{"ch-1", 1, map[UniqueID]Timestamp{3: ts(time.Second)}},
+		{"ch-2", 2, map[UniqueID]Timestamp{4: ts(2 * time.Second), 5: ts(time.Hour)}},
+	} {
+		channel := newChannel(ch.name, ch.collID, nil, rc, nil)
+		for segID, startTs := range ch.segs {
+			require.NoError(t, channel.addSegment(addSegmentReq{
+				segType:     datapb.SegmentType_New,
+				segID:       segID,
+				collID:      ch.collID,
+				partitionID: 10,
+				startPos:    &internalpb.MsgPosition{ChannelName: ch.name, Timestamp: startTs},
+			}))
+		}
+		fm.flowgraphs.Store(ch.name, &dataSyncService{channel: channel})
+	}
+
+	infos := fm.getAllSegmentsByCreateTimeRange(ts(0), ts(time.Minute))
+	var ids []UniqueID
+	for _, info := range infos {
+		ids = append(ids, info.GetID())
+	}
+	assert.Equal(t, []UniqueID{3, 4, 1}, ids)
+	assert.Equal(t, UniqueID(2), infos[1].GetCollectionID())
+	assert.Empty(t, fm.getAllSegmentsByCreateTimeRange(ts(2*time.Hour), ts(3*time.Hour)))
+}
+
+func TestFlowGraphManager_emptyCollections(t *testing.T) {
+	now := time.Date(2022, 12, 1, 0, 0, 0, 0, time.UTC)
+	fm := newFlowgraphManager()
+	fm.clock = func() time.Time { return now }
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	for _, ch := range []struct {
+		name    string
+		collID  UniqueID
+		age     time.Duration
+		segIDs  []UniqueID
+		removed bool
+	}{
+		// empty for an hour
+		{"ch-0", 1, time.Hour, nil, false},
+		{"ch-1", 1, 10 * time.Minute, nil, false},
+		// empty, but too young
+		{"ch-2", 2, time.Minute, nil, false},
+		// with data
+		{"ch-3", 3, time.Hour, []UniqueID{1}, false},
+		// data received, then all of it removed
+		{"ch-4", 4, time.Hour, []UniqueID{2}, true},
+	} {
+		channel := newChannel(ch.name, ch.collID, nil, rc, nil)
+		channel.createdAt = now.Add(-ch.age)
+		for _, segID := range ch.segIDs {
+			require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: segID, collID: ch.collID, partitionID: 10}))
+			if ch.removed {
+				channel.removeSegments(segID)
+			}
+		}
+		fm.flowgraphs.Store(ch.name, &dataSyncService{channel: channel})
+	}
+
+	assert.Equal(t, []UniqueID{1}, fm.getEmptyCollections(30*time.Minute))
+	assert.Equal(t, []UniqueID{1, 2}, fm.getEmptyCollections(0))
+	assert.Empty(t, fm.getEmptyCollections(2*time.Hour))
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, []UniqueID{1, 2}, fm.getEmptyCollections(time.Hour))
+
+	// present with zero counts in the stats
+	all := fm.getAllCollectionStats()
+	require.Len(t, all, 4)
+	assert.Equal(t, &CollectionStats{
+		CollectionID: 1,
+		NumChannels:  2,
+		PartitionIDs: []UniqueID{},
+		CreatedAt:    now.Add(-3 * time.Hour),
+	}, all[1])
+	assert.Zero(t, all[4].NumSegments)
+	assert.True(t, all[4].ReceivedSegments)
+}
+
+func TestFlowGraphManager_releaseCollectionLabel(t *testing.T) {
+	const collID = UniqueID(9001)
+	fm := newFlowgraphManager()
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	for i, name := range []string{"release-label-1", "release-label-2"} {
+		channel := newChannel(name, collID, nil, rc, nil)
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: UniqueID(i + 1), collID: collID, partitionID: 10}))
+		channel.updateStatistics(UniqueID(i+1), 10)
+		fm.flowgraphs.Store(name, &dataSyncService{
+			channel:          channel,
+			cancelFn:         func() {},
+			flushManager:     &mockFlushManager{},
+			flushingSegCache: newCache(),
+		})
+	}
+	label := collectionLabels.label(collID)
+	addSeries := testutil.CollectAndCount(metrics.DataNodeSegmentAddCount)
+	updateSeries := testutil.CollectAndCount(metrics.DataNodeSegmentUpdateCount)
+
+	// the collection still has a channel on the node
+	fm.release("release-label-1")
+	assert.Equal(t, addSeries, testutil.CollectAndCount(metrics.DataNodeSegmentAddCount))
+
+	fm.release("release-label-2")
+	assert.Equal(t, addSeries-1, testutil.CollectAndCount(metrics.DataNodeSegmentAddCount))
+	assert.Equal(t, updateSeries-1, testutil.CollectAndCount(metrics.DataNodeSegmentUpdateCount))
+	assert.False(t, metrics.DataNodeCollectionLabels.Release(label), "the label value is released")
+}
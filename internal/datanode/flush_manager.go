@@ -648,7 +648,11 @@ func dropVirtualChannelFunc(dsService *dataSyncService, opts ...retry.Option) fl
 		}
 
 		segmentPack := make(map[UniqueID]*datapb.DropVirtualChannelSegment)
+		packRows := make(map[UniqueID]int64)
+		packSize := make(map[UniqueID]int64)
 		for _, pack := range packs {
+			packRows[pack.segmentID] += insertLogRows(pack.insertLogs)
+			packSize[pack.segmentID] += insertLogSize(pack.insertLogs)
 			segment, has := segmentPack[pack.segmentID]
 			if !has {
 				segment = &datapb.DropVirtualChannelSegment{
@@ -741,7 +745,10 @@ func dropVirtualChannelFunc(dsService *dataSyncService, opts ...retry.Option) fl
 			panic(err)
 		}
 		for segID := range segmentPack {
-			dsService.channel.segmentFlushed(segID)
+			if dsService.channel.InterfaceVersion() >= channelVersionSegmentSize {
+				dsService.channel.addBinlogSize(segID, packSize[segID])
+			}
+			dsService.channel.segmentFlushed(segID, tallyFlushedRows(dsService.channel, segID, packRows[segID]))
 			dsService.flushingSegCache.Remove(segID)
 		}
 	}
@@ -848,8 +855,17 @@ func flushNotifyFunc(dsService *dataSyncService, opts ...retry.Option) notifyMet
 			// TODO change to graceful stop
 			panic(err)
 		}
+		if dsService.channel.InterfaceVersion() >= channelVersionBinlogIndex {
+			if err := dsService.channel.registerBinlogPaths(pack.segmentID, fieldBinlogPaths(fieldInsert, fieldStats, deltaInfos)); err != nil {
+				log.Warn("failed to index binlog paths", zap.Int64("segment ID", pack.segmentID), zap.Error(err))
+			}
+		}
+		flushedRows := tallyFlushedRows(dsService.channel, pack.segmentID, insertLogRows(pack.insertLogs))
+		if dsService.channel.InterfaceVersion() >= channelVersionSegmentSize {
+			dsService.channel.addBinlogSize(pack.segmentID, insertLogSize(pack.insertLogs))
+		}
 		if pack.flushed || pack.dropped {
-			dsService.channel.segmentFlushed(pack.segmentID)
+			dsService.channel.segmentFlushed(pack.segmentID, flushedRows)
 		}
 		dsService.flushingSegCache.Remove(req.GetSegmentID())
 	}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"crypto/rand"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -607,6 +608,71 @@ func TestFlushNotifyFunc(t *testing.T) {
 	})
 }
 
+func TestFlushNotifyFunc_reconcileFlushedRows(t *testing.T) {
+	rcf := &RootCoordFactory{
+		pkType: schemapb.DataType_Int64,
+	}
+	cm := storage.NewLocalChunkManager(storage.RootPath(flushTestDir))
+	channel := newChannel("channel", 1, nil, rcf, cm)
+	dsService := &dataSyncService{
+		collectionID:     1,
+		channel:          channel,
+		dataCoord:        &DataCoordFactory{},
+		flushingSegCache: newCache(),
+	}
+	notifyFunc := flushNotifyFunc(dsService, retry.Attempts(1))
+
+	insertLogs := func(segID UniqueID, flush int, rows int64) map[UniqueID]*datapb.Binlog {
+		return map[UniqueID]*datapb.Binlog{
+			0:   {EntriesNum: rows, LogPath: fmt.Sprintf("/dev/test/%d/0/%d", segID, flush)},
+			100: {EntriesNum: rows, LogPath: fmt.Sprintf("/dev/test/%d/100/%d", segID, flush)},
+		}
+	}
+	for _, req := range []addSegmentReq{
+		{segType: datapb.SegmentType_New, segID: 100, collID: 1, partitionID: 10},
+		{segType: datapb.SegmentType_New, segID: 101, collID: 1, partitionID: 10},
+		{segType: datapb.SegmentType_Normal, segID: 102, collID: 1, partitionID: 10, numOfRows: 5},
+	} {
+		require.NoError(t, channel.addSegment(req))
+	}
+
+	t.Run("rows of all flushes match", func(t *testing.T) {
+		channel.updateStatistics(100, 10)
+		notifyFunc(&segmentFlushPack{segmentID: 100, insertLogs: insertLogs(100, 1, 6)})
+		notifyFunc(&segmentFlushPack{segmentID: 100, insertLogs: insertLogs(100, 2, 4), flushed: true})
+
+		assert.False(t, channel.hasSegment(100, false))
+		assert.NotContains(t, channel.listUnreconciledSegments(), UniqueID(100))
+	})
+
+	t.Run("rows recovered match", func(t *testing.T) {
+		channel.updateStatistics(102, 3)
+		notifyFunc(&segmentFlushPack{segmentID: 102, insertLogs: insertLogs(102, 1, 3), flushed: true})
+
+		assert.NotContains(t, channel.listUnreconciledSegments(), UniqueID(102))
+	})
+
+	t.Run("rows flushed mismatch", func(t *testing.T) {
+		channel.updateStatistics(101, 10)
+		notifyFunc(&segmentFlushPack{segmentID: 101, insertLogs: insertLogs(101, 1, 7), flushed: true})
+
+		assert.Equal(t, []UniqueID{101}, channel.listUnreconciledSegments())
+	})
+
+	t.Run("dropped channel", func(t *testing.T) {
+		require.NoError(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 103, collID: 1, partitionID: 10}))
+		channel.updateStatistics(103, 8)
+		dropFunc := dropVirtualChannelFunc(dsService, retry.Attempts(1))
+		dropFunc([]*segmentFlushPack{
+			{segmentID: 103, insertLogs: insertLogs(103, 1, 5)},
+			{segmentID: 103, insertLogs: insertLogs(103, 2, 3), dropped: true},
+		})
+
+		assert.False(t, channel.hasSegment(103, false))
+		assert.Equal(t, []UniqueID{101}, channel.listUnreconciledSegments())
+	})
+}
+
 func TestDropVirtualChannelFunc(t *testing.T) {
 	rcf := &RootCoordFactory{
 		pkType: schemapb.DataType_Int64,
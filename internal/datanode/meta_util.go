@@ -17,9 +17,12 @@
 package datanode
 
 import (
+	"sort"
+
 	"github.com/milvus-io/milvus/internal/common"
 	"github.com/milvus-io/milvus/internal/proto/datapb"
 	"github.com/milvus-io/milvus/internal/proto/etcdpb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
 )
 
 // reviseVChannelInfo will revise the datapb.VchannelInfo for upgrade compatibility from 2.0.2
@@ -76,3 +79,35 @@ func getPKID(meta *etcdpb.CollectionMeta) UniqueID {
 
 	return common.InvalidFieldID
 }
+
+// mergePositions merges the positions of two segments covering possibly overlapping channels, as for
+// the segment compacted from both. start has the earliest position of each channel and end the latest,
+// both sorted by channel name. The positions are not copied, nil positions are ignored.
+func mergePositions(a, b []*internalpb.MsgPosition) (start, end []*internalpb.MsgPosition) {
+	earliest := make(map[string]*internalpb.MsgPosition)
+	latest := make(map[string]*internalpb.MsgPosition)
+	for _, positions := range [][]*internalpb.MsgPosition{a, b} {
+		for _, pos := range positions {
+			if pos == nil {
+				continue
+			}
+			channel := pos.GetChannelName()
+			if cur, ok := earliest[channel]; !ok || pos.GetTimestamp() < cur.GetTimestamp() {
+				earliest[channel] = pos
+			}
+			if cur, ok := latest[channel]; !ok || pos.GetTimestamp() > cur.GetTimestamp() {
+				latest[channel] = pos
+			}
+		}
+	}
+
+	sortedPositions := func(positions map[string]*internalpb.MsgPosition) []*internalpb.MsgPosition {
+		result := make([]*internalpb.MsgPosition, 0, len(positions))
+		for _, pos := range positions {
+			result = append(result, pos)
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].GetChannelName() < result[j].GetChannelName() })
+		return result
+	}
+	return sortedPositions(earliest), sortedPositions(latest)
+}
@@ -0,0 +1,78 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+)
+
+func TestMergePositions(t *testing.T) {
+	pos := func(channel string, ts Timestamp) *internalpb.MsgPosition {
+		return &internalpb.MsgPosition{ChannelName: channel, Timestamp: ts}
+	}
+
+	tests := []struct {
+		description string
+		a, b        []*internalpb.MsgPosition
+		start, end  []*internalpb.MsgPosition
+	}{
+		{
+			"empty",
+			nil, nil,
+			[]*internalpb.MsgPosition{}, []*internalpb.MsgPosition{},
+		},
+		{
+			"disjoint channels",
+			[]*internalpb.MsgPosition{pos("ch-1", 100)},
+			[]*internalpb.MsgPosition{pos("ch-0", 200), pos("ch-2", 50)},
+			[]*internalpb.MsgPosition{pos("ch-0", 200), pos("ch-1", 100), pos("ch-2", 50)},
+			[]*internalpb.MsgPosition{pos("ch-0", 200), pos("ch-1", 100), pos("ch-2", 50)},
+		},
+		{
+			"identical channels",
+			[]*internalpb.MsgPosition{pos("ch-0", 100), pos("ch-1", 300)},
+			[]*internalpb.MsgPosition{pos("ch-1", 200), pos("ch-0", 400)},
+			[]*internalpb.MsgPosition{pos("ch-0", 100), pos("ch-1", 200)},
+			[]*internalpb.MsgPosition{pos("ch-0", 400), pos("ch-1", 300)},
+		},
+		{
+			"partially overlapping channels",
+			[]*internalpb.MsgPosition{pos("ch-0", 100), pos("ch-1", 300)},
+			[]*internalpb.MsgPosition{pos("ch-1", 200), pos("ch-2", 400), nil},
+			[]*internalpb.MsgPosition{pos("ch-0", 100), pos("ch-1", 200), pos("ch-2", 400)},
+			[]*internalpb.MsgPosition{pos("ch-0", 100), pos("ch-1", 300), pos("ch-2", 400)},
+		},
+		{
+			"one side only",
+			[]*internalpb.MsgPosition{pos("ch-0", 100), pos("ch-0", 50)},
+			nil,
+			[]*internalpb.MsgPosition{pos("ch-0", 50)},
+			[]*internalpb.MsgPosition{pos("ch-0", 100)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			start, end := mergePositions(test.a, test.b)
+			assert.Equal(t, test.start, start)
+			assert.Equal(t, test.end, end)
+		})
+	}
+}
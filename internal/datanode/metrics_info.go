@@ -18,6 +18,10 @@ package datanode
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/milvus-io/milvus-proto/go-api/commonpb"
 	"github.com/milvus-io/milvus-proto/go-api/milvuspb"
@@ -148,3 +152,174 @@ func (node *DataNode) getSystemInfoMetrics(ctx context.Context, req *milvuspb.Ge
 		ComponentName: metricsinfo.ConstructComponentName(typeutil.DataNodeRole, paramtable.GetNodeID()),
 	}, nil
 }
+
+// checkpointBlockersRequest is the parameters of a CheckpointBlockersMetrics request.
+type checkpointBlockersRequest struct {
+	Channel string `json:"channel"`
+	TopN    int    `json:"top_n"`
+}
+
+func failedMetricsResponse(reason string) *milvuspb.GetMetricsResponse {
+	return &milvuspb.GetMetricsResponse{
+		Status: &commonpb.Status{
+			ErrorCode: commonpb.ErrorCode_UnexpectedError,
+			Reason:    reason,
+		},
+		ComponentName: metricsinfo.ConstructComponentName(typeutil.DataNodeRole, paramtable.GetNodeID()),
+	}
+}
+
+// unsupportedMetricsResponse fails a metrics request the interface version of the channel does not support.
+func unsupportedMetricsResponse(name string, channel Channel) *milvuspb.GetMetricsResponse {
+	return failedMetricsResponse(fmt.Sprintf("channel %s of interface version %d does not support the request",
+		name, channel.InterfaceVersion()))
+}
+
+// getCheckpointBlockersMetrics returns the json of the segments blocking the checkpoint of the requested channel.
+func (node *DataNode) getCheckpointBlockersMetrics(req *milvuspb.GetMetricsRequest) *milvuspb.GetMetricsResponse {
+	var params checkpointBlockersRequest
+	if err := json.Unmarshal([]byte(req.GetRequest()), &params); err != nil {
+		return failedMetricsResponse(fmt.Sprintf("failed to decode the request: %s", err.Error()))
+	}
+	fg, ok := node.flowgraphManager.flowgraphs.Load(params.Channel)
+	if !ok {
+		return failedMetricsResponse(fmt.Sprintf("channel %s not found on datanode %d", params.Channel, paramtable.GetNodeID()))
+	}
+	return checkpointBlockersResponse(fg.(*dataSyncService).channel, params)
+}
+
+func checkpointBlockersResponse(channel Channel, params checkpointBlockersRequest) *milvuspb.GetMetricsResponse {
+	if channel.InterfaceVersion() < channelVersionCheckpointBlockers {
+		return unsupportedMetricsResponse(params.Channel, channel)
+	}
+	blockers := channel.getCheckpointBlockers(params.Channel, params.TopN)
+	resp, err := json.Marshal(blockers)
+	if err != nil {
+		return failedMetricsResponse(err.Error())
+	}
+	return &milvuspb.GetMetricsResponse{
+		Status:        &commonpb.Status{ErrorCode: commonpb.ErrorCode_Success},
+		Response:      string(resp),
+		ComponentName: metricsinfo.ConstructComponentName(typeutil.DataNodeRole, paramtable.GetNodeID()),
+	}
+}
+
+// partitionSkewRequest is the parameters of a PartitionSkewMetrics request.
+type partitionSkewRequest struct {
+	Channel   string  `json:"channel"`
+	Threshold float64 `json:"threshold"`
+}
+
+// getPartitionSkewMetrics returns the json of the skewed partitions of the requested channel.
+func (node *DataNode) getPartitionSkewMetrics(req *milvuspb.GetMetricsRequest) *milvuspb.GetMetricsResponse {
+	var params partitionSkewRequest
+	if err := json.Unmarshal([]byte(req.GetRequest()), &params); err != nil {
+		return failedMetricsResponse(fmt.Sprintf("failed to decode the request: %s", err.Error()))
+	}
+	fg, ok := node.flowgraphManager.flowgraphs.Load(params.Channel)
+	if !ok {
+		return failedMetricsResponse(fmt.Sprintf("channel %s not found on datanode %d", params.Channel, paramtable.GetNodeID()))
+	}
+	channel := fg.(*dataSyncService).channel
+	if channel.InterfaceVersion() < channelVersionPartitionSkew {
+		return unsupportedMetricsResponse(params.Channel, channel)
+	}
+	reports, err := channel.detectPartitionSkew(channel.getCollectionID(), params.Threshold)
+	if err != nil {
+		return failedMetricsResponse(err.Error())
+	}
+	resp, err := json.Marshal(reports)
+	if err != nil {
+		return failedMetricsResponse(err.Error())
+	}
+	return &milvuspb.GetMetricsResponse{
+		Status:        &commonpb.Status{ErrorCode: commonpb.ErrorCode_Success},
+		Response:      string(resp),
+		ComponentName: metricsinfo.ConstructComponentName(typeutil.DataNodeRole, paramtable.GetNodeID()),
+	}
+}
+
+// positionGapsRequest is the parameters of a PositionGapsMetrics request.
+type positionGapsRequest struct {
+	Channel  string `json:"channel"`
+	MaxGapMs int64  `json:"max_gap_ms"`
+}
+
+// getPositionGapsMetrics returns the json of the segments of the requested channel whose positions span
+// more than the requested threshold.
+func (node *DataNode) getPositionGapsMetrics(req *milvuspb.GetMetricsRequest) *milvuspb.GetMetricsResponse {
+	var params positionGapsRequest
+	if err := json.Unmarshal([]byte(req.GetRequest()), &params); err != nil {
+		return failedMetricsResponse(fmt.Sprintf("failed to decode the request: %s", err.Error()))
+	}
+	if params.MaxGapMs <= 0 {
+		return failedMetricsResponse(fmt.Sprintf("invalid max_gap_ms %d, must be positive", params.MaxGapMs))
+	}
+	fg, ok := node.flowgraphManager.flowgraphs.Load(params.Channel)
+	if !ok {
+		return failedMetricsResponse(fmt.Sprintf("channel %s not found on datanode %d", params.Channel, paramtable.GetNodeID()))
+	}
+	channel := fg.(*dataSyncService).channel
+	if channel.InterfaceVersion() < channelVersionPositionGaps {
+		return unsupportedMetricsResponse(params.Channel, channel)
+	}
+	gaps := channel.DetectPositionGaps(params.MaxGapMs)
+	if gaps == nil {
+		gaps = []PositionGap{}
+	}
+	resp, err := json.Marshal(gaps)
+	if err != nil {
+		return failedMetricsResponse(err.Error())
+	}
+	return &milvuspb.GetMetricsResponse{
+		Status:        &commonpb.Status{ErrorCode: commonpb.ErrorCode_Success},
+		Response:      string(resp),
+		ComponentName: metricsinfo.ConstructComponentName(typeutil.DataNodeRole, paramtable.GetNodeID()),
+	}
+}
+
+// getCollectionLabelsMetrics returns the json of the mapping from the collection labels back to the collections.
+func (node *DataNode) getCollectionLabelsMetrics() *milvuspb.GetMetricsResponse {
+	resp, err := json.Marshal(collectionLabels.mappings())
+	if err != nil {
+		return failedMetricsResponse(err.Error())
+	}
+	return &milvuspb.GetMetricsResponse{
+		Status:        &commonpb.Status{ErrorCode: commonpb.ErrorCode_Success},
+		Response:      string(resp),
+		ComponentName: metricsinfo.ConstructComponentName(typeutil.DataNodeRole, paramtable.GetNodeID()),
+	}
+}
+
+// getCollectionStatsMetrics returns the json of the stats of every collection on this node ordered by collection ID,
+// collections without segments included with zero counts.
+func (node *DataNode) getCollectionStatsMetrics() *milvuspb.GetMetricsResponse {
+	all := node.flowgraphManager.getAllCollectionStats()
+	stats := make([]*CollectionStats, 0, len(all))
+	for _, s := range all {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].CollectionID < stats[j].CollectionID })
+	resp, err := json.Marshal(stats)
+	if err != nil {
+		return failedMetricsResponse(err.Error())
+	}
+	return &milvuspb.GetMetricsResponse{
+		Status:        &commonpb.Status{ErrorCode: commonpb.ErrorCode_Success},
+		Response:      string(resp),
+		ComponentName: metricsinfo.ConstructComponentName(typeutil.DataNodeRole, paramtable.GetNodeID()),
+	}
+}
+
+// getStartupReportMetrics returns the json of the startup reports of the channels restored within startupReportWindow.
+func (node *DataNode) getStartupReportMetrics() *milvuspb.GetMetricsResponse {
+	resp, err := json.Marshal(node.flowgraphManager.getStartupReports(time.Now()))
+	if err != nil {
+		return failedMetricsResponse(err.Error())
+	}
+	return &milvuspb.GetMetricsResponse{
+		Status:        &commonpb.Status{ErrorCode: commonpb.ErrorCode_Success},
+		Response:      string(resp),
+		ComponentName: metricsinfo.ConstructComponentName(typeutil.DataNodeRole, paramtable.GetNodeID()),
+	}
+}
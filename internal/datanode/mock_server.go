@@ -0,0 +1,186 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	"github.com/milvus-io/milvus-proto/go-api/commonpb"
+	"github.com/milvus-io/milvus-proto/go-api/milvuspb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/util/metricsinfo"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+	"github.com/milvus-io/milvus/internal/util/tsoutil"
+	"github.com/milvus-io/milvus/internal/util/typeutil"
+)
+
+// mockDataNodeServer serves the DataNode RPCs from a single channel, without flow graph,
+// message streams or coordinators. RPCs needing those fail with an unexpected error status.
+type mockDataNodeServer struct {
+	channel Channel
+}
+
+var _ datapb.DataNodeServer = (*mockDataNodeServer)(nil)
+
+// NewMockDataNodeServer returns a grpc server with the DataNode service backed by channel,
+// for client integration tests without a Milvus cluster. The caller serves and stops it.
+func NewMockDataNodeServer(channel Channel) *grpc.Server {
+	server := grpc.NewServer()
+	datapb.RegisterDataNodeServer(server, &mockDataNodeServer{channel: channel})
+	return server
+}
+
+func mockUnsupportedStatus(method string) *commonpb.Status {
+	return &commonpb.Status{
+		ErrorCode: commonpb.ErrorCode_UnexpectedError,
+		Reason:    fmt.Sprintf("%s is not supported by mock data node", method),
+	}
+}
+
+func mockStatus(err error) *commonpb.Status {
+	if err != nil {
+		return &commonpb.Status{ErrorCode: commonpb.ErrorCode_UnexpectedError, Reason: err.Error()}
+	}
+	return &commonpb.Status{ErrorCode: commonpb.ErrorCode_Success}
+}
+
+func (s *mockDataNodeServer) GetComponentStates(ctx context.Context, req *milvuspb.GetComponentStatesRequest) (*milvuspb.ComponentStates, error) {
+	return &milvuspb.ComponentStates{
+		State: &milvuspb.ComponentInfo{
+			NodeID:    paramtable.GetNodeID(),
+			Role:      typeutil.DataNodeRole,
+			StateCode: commonpb.StateCode_Healthy,
+		},
+		SubcomponentStates: make([]*milvuspb.ComponentInfo, 0),
+		Status:             mockStatus(nil),
+	}, nil
+}
+
+func (s *mockDataNodeServer) GetStatisticsChannel(ctx context.Context, req *internalpb.GetStatisticsChannelRequest) (*milvuspb.StringResponse, error) {
+	return &milvuspb.StringResponse{Status: mockStatus(nil)}, nil
+}
+
+func (s *mockDataNodeServer) WatchDmChannels(ctx context.Context, req *datapb.WatchDmChannelsRequest) (*commonpb.Status, error) {
+	return mockUnsupportedStatus("WatchDmChannels"), nil
+}
+
+// FlushSegments marks the segments flushed in channel, there is no data to sync.
+func (s *mockDataNodeServer) FlushSegments(ctx context.Context, req *datapb.FlushSegmentsRequest) (*commonpb.Status, error) {
+	if req.GetCollectionID() != s.channel.getCollectionID() {
+		return mockStatus(fmt.Errorf("%w, ID=%d", errMismatchCollection, req.GetCollectionID())), nil
+	}
+	for _, segID := range req.GetSegmentIDs() {
+		if !s.channel.hasSegment(segID, false) {
+			return mockStatus(fmt.Errorf("no growing segment %d to flush", segID)), nil
+		}
+	}
+	for _, segID := range req.GetSegmentIDs() {
+		s.channel.segmentFlushed(segID, unknownFlushedRows)
+	}
+	return mockStatus(nil), nil
+}
+
+func (s *mockDataNodeServer) ShowConfigurations(ctx context.Context, req *internalpb.ShowConfigurationsRequest) (*internalpb.ShowConfigurationsResponse, error) {
+	return &internalpb.ShowConfigurationsResponse{Status: mockStatus(nil)}, nil
+}
+
+// GetMetrics serves CheckpointBlockersMetrics of channel.
+func (s *mockDataNodeServer) GetMetrics(ctx context.Context, req *milvuspb.GetMetricsRequest) (*milvuspb.GetMetricsResponse, error) {
+	metricType, err := metricsinfo.ParseMetricType(req.GetRequest())
+	if err != nil {
+		return failedMetricsResponse(err.Error()), nil
+	}
+	if metricType != metricsinfo.CheckpointBlockersMetrics {
+		return failedMetricsResponse(metricsinfo.MsgUnimplementedMetric), nil
+	}
+	var params checkpointBlockersRequest
+	if err := json.Unmarshal([]byte(req.GetRequest()), &params); err != nil {
+		return failedMetricsResponse(err.Error()), nil
+	}
+	return checkpointBlockersResponse(s.channel, params), nil
+}
+
+func (s *mockDataNodeServer) Compaction(ctx context.Context, req *datapb.CompactionPlan) (*commonpb.Status, error) {
+	return mockUnsupportedStatus("Compaction"), nil
+}
+
+func (s *mockDataNodeServer) GetCompactionState(ctx context.Context, req *datapb.CompactionStateRequest) (*datapb.CompactionStateResponse, error) {
+	return &datapb.CompactionStateResponse{Status: mockStatus(nil)}, nil
+}
+
+// SyncSegments merges the compacted segments into the target one in channel.
+func (s *mockDataNodeServer) SyncSegments(ctx context.Context, req *datapb.SyncSegmentsRequest) (*commonpb.Status, error) {
+	if len(req.GetCompactedFrom()) == 0 {
+		return mockStatus(fmt.Errorf("invalid request, compacted from segments shouldn't be empty")), nil
+	}
+	collID, partID, err := s.channel.getCollectionAndPartitionID(req.GetCompactedFrom()[0])
+	if err != nil {
+		return mockStatus(err), nil
+	}
+	targetSeg := &Segment{
+		collectionID: collID,
+		partitionID:  partID,
+		segmentID:    req.GetCompactedTo(),
+		numRows:      req.GetNumOfRows(),
+	}
+	targetSeg.setType(datapb.SegmentType_Flushed)
+	if err := s.channel.InitPKstats(ctx, targetSeg, req.GetStatsLogs(), tsoutil.GetCurrentTime()); err != nil {
+		return mockStatus(err), nil
+	}
+	return mockStatus(s.channel.mergeFlushedSegments(targetSeg, req.GetPlanID(), req.GetCompactedFrom())), nil
+}
+
+func (s *mockDataNodeServer) Import(ctx context.Context, req *datapb.ImportTaskRequest) (*commonpb.Status, error) {
+	return mockUnsupportedStatus("Import"), nil
+}
+
+// ResendSegmentStats returns the unflushed segments of channel, whose stats a data node would resend.
+func (s *mockDataNodeServer) ResendSegmentStats(ctx context.Context, req *datapb.ResendSegmentStatsRequest) (*datapb.ResendSegmentStatsResponse, error) {
+	return &datapb.ResendSegmentStatsResponse{
+		Status:    mockStatus(nil),
+		SegResent: s.channel.listNotFlushedSegmentIDs(),
+	}, nil
+}
+
+// AddImportSegment adds the imported segment to channel as flushed, at the request timestamp.
+func (s *mockDataNodeServer) AddImportSegment(ctx context.Context, req *datapb.AddImportSegmentRequest) (*datapb.AddImportSegmentResponse, error) {
+	if s.channel.hasSegment(req.GetSegmentId(), true) {
+		return &datapb.AddImportSegmentResponse{Status: mockStatus(nil)}, nil
+	}
+	pos := &internalpb.MsgPosition{
+		ChannelName: req.GetChannelName(),
+		Timestamp:   req.GetBase().GetTimestamp(),
+	}
+	err := s.channel.addSegment(addSegmentReq{
+		segType:      datapb.SegmentType_Flushed,
+		segID:        req.GetSegmentId(),
+		collID:       req.GetCollectionId(),
+		partitionID:  req.GetPartitionId(),
+		numOfRows:    req.GetRowNum(),
+		statsBinLogs: req.GetStatsLog(),
+		startPos:     pos,
+		endPos:       pos,
+		recoverTs:    req.GetBase().GetTimestamp(),
+		importing:    true,
+	})
+	return &datapb.AddImportSegmentResponse{Status: mockStatus(err)}, nil
+}
@@ -0,0 +1,140 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/milvus-io/milvus-proto/go-api/commonpb"
+	"github.com/milvus-io/milvus-proto/go-api/milvuspb"
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/util/metricsinfo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestMockDataNodeServer(t *testing.T) {
+	const channelName = "by-dev-dml_0_1v0"
+	channel := newChannel(channelName, 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := NewMockDataNodeServer(channel)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithBlock(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+	client := datapb.NewDataNodeClient(conn)
+
+	// rows inserted from the insert stream
+	for _, seg := range []struct {
+		segID   UniqueID
+		numRows int64
+	}{{1, 10}, {2, 20}} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       seg.segID,
+			collID:      1,
+			partitionID: 10,
+			startPos:    &internalpb.MsgPosition{ChannelName: channelName, Timestamp: Timestamp(100 * seg.segID)},
+		}))
+		channel.updateStatistics(seg.segID, seg.numRows)
+	}
+
+	states, err := client.GetComponentStates(ctx, &milvuspb.GetComponentStatesRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, commonpb.StateCode_Healthy, states.GetState().GetStateCode())
+
+	resent, err := client.ResendSegmentStats(ctx, &datapb.ResendSegmentStatsRequest{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []UniqueID{1, 2}, resent.GetSegResent())
+
+	t.Run("segment statistics", func(t *testing.T) {
+		req, err := json.Marshal(map[string]interface{}{
+			metricsinfo.MetricTypeKey: metricsinfo.CheckpointBlockersMetrics,
+			"channel":                 channelName,
+		})
+		require.NoError(t, err)
+		resp, err := client.GetMetrics(ctx, &milvuspb.GetMetricsRequest{Request: string(req)})
+		require.NoError(t, err)
+		require.Equal(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+
+		var views []SegmentView
+		require.NoError(t, json.Unmarshal([]byte(resp.GetResponse()), &views))
+		require.Len(t, views, 2)
+		assert.Equal(t, int64(10), views[0].NumRows)
+		assert.Equal(t, int64(20), views[1].NumRows)
+	})
+
+	t.Run("flush and compact", func(t *testing.T) {
+		status, err := client.FlushSegments(ctx, &datapb.FlushSegmentsRequest{CollectionID: 1, SegmentIDs: []UniqueID{1, 2}})
+		require.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, status.GetErrorCode())
+		resent, err := client.ResendSegmentStats(ctx, &datapb.ResendSegmentStatsRequest{})
+		require.NoError(t, err)
+		assert.Empty(t, resent.GetSegResent())
+
+		status, err = client.SyncSegments(ctx, &datapb.SyncSegmentsRequest{
+			PlanID:        1,
+			CompactedTo:   3,
+			CompactedFrom: []UniqueID{1, 2},
+			NumOfRows:     30,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, status.GetErrorCode())
+		assert.True(t, channel.hasSegment(3, true))
+		assert.False(t, channel.hasSegment(1, true))
+
+		// segments already flushed
+		status, err = client.FlushSegments(ctx, &datapb.FlushSegmentsRequest{CollectionID: 1, SegmentIDs: []UniqueID{3}})
+		require.NoError(t, err)
+		assert.NotEqual(t, commonpb.ErrorCode_Success, status.GetErrorCode())
+	})
+
+	t.Run("import", func(t *testing.T) {
+		resp, err := client.AddImportSegment(ctx, &datapb.AddImportSegmentRequest{
+			Base:         &commonpb.MsgBase{Timestamp: 1000},
+			SegmentId:    4,
+			ChannelName:  channelName,
+			CollectionId: 1,
+			PartitionId:  10,
+			RowNum:       40,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+		assert.True(t, channel.hasSegment(4, true))
+
+		resp, err = client.AddImportSegment(ctx, &datapb.AddImportSegmentRequest{SegmentId: 5, CollectionId: 2})
+		require.NoError(t, err)
+		assert.NotEqual(t, commonpb.ErrorCode_Success, resp.GetStatus().GetErrorCode())
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		status, err := client.WatchDmChannels(ctx, &datapb.WatchDmChannelsRequest{})
+		require.NoError(t, err)
+		assert.NotEqual(t, commonpb.ErrorCode_Success, status.GetErrorCode())
+	})
+}
@@ -0,0 +1,158 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+)
+
+// WatchReplicaEventsRequest selects the channels whose segment events are streamed by WatchReplicaEvents.
+type WatchReplicaEventsRequest struct {
+	// CollectionID selects the channels of the collection, 0 selects all
+	CollectionID UniqueID
+	// Channel selects the channel of the name, empty selects all
+	Channel string
+	// BufferSize is the number of events held for the client, non-positive takes channelEventBufferSize
+	BufferSize int
+}
+
+// ReplicaEvent is a message of the WatchReplicaEvents stream, a segment event of a channel.
+type ReplicaEvent struct {
+	Channel string
+	Event   ChannelEvent
+	// DroppedEvents is the number of events dropped right before this one as the client fell behind
+	DroppedEvents int64
+}
+
+// ReplicaEventStream is the server side of the WatchReplicaEvents stream,
+// in the shape of a generated gRPC server stream.
+type ReplicaEventStream interface {
+	Send(*ReplicaEvent) error
+	Context() context.Context
+}
+
+// replicaEventWatcher buffers the events of a WatchReplicaEvents stream between the channels and the client.
+// offer never blocks the channel mutation, the events offered while the buffer is full are dropped and
+// counted, and the count is reported with the next event sent.
+type replicaEventWatcher struct {
+	mu      sync.Mutex
+	size    int
+	events  []ReplicaEvent
+	dropped int64
+	notify  chan struct{}
+}
+
+func newReplicaEventWatcher(size int) *replicaEventWatcher {
+	if size <= 0 {
+		size = channelEventBufferSize
+	}
+	return &replicaEventWatcher{
+		size:   size,
+		events: make([]ReplicaEvent, 0, size),
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (w *replicaEventWatcher) offer(channel string, ev ChannelEvent) {
+	w.mu.Lock()
+	if len(w.events) >= w.size {
+		w.dropped++
+		w.mu.Unlock()
+		metrics.DataNodeListenerDroppedEvents.WithLabelValues(fmt.Sprint(paramtable.GetNodeID())).Inc()
+		return
+	}
+	w.events = append(w.events, ReplicaEvent{Channel: channel, Event: ev})
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the buffered events, the first of them carrying the count of events dropped before it.
+func (w *replicaEventWatcher) take() []ReplicaEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	events := w.events
+	if len(events) > 0 {
+		events[0].DroppedEvents = w.dropped
+		w.dropped = 0
+	}
+	w.events = make([]ReplicaEvent, 0, w.size)
+	return events
+}
+
+// serve sends the buffered events to the stream until the client goes away or a send fails.
+func (w *replicaEventWatcher) serve(stream ReplicaEventStream) error {
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-w.notify:
+		}
+		for _, ev := range w.take() {
+			ev := ev
+			if err := stream.Send(&ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchReplicaEvents streams the segment events of the channels on this node selected by req until
+// the client goes away. The channels are selected when the watch starts, channels added later are not watched.
+func (fm *flowgraphManager) watchReplicaEvents(req *WatchReplicaEventsRequest, stream ReplicaEventStream) error {
+	var filter ListenerFilter
+	if req.CollectionID != 0 {
+		filter = CollectionFilter(req.CollectionID)
+	}
+
+	w := newReplicaEventWatcher(req.BufferSize)
+	var registrations []*ListenerRegistration
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		name, channel := key.(string), value.(*dataSyncService).channel
+		if req.Channel != "" && name != req.Channel {
+			return true
+		}
+		if req.CollectionID != 0 && channel.getCollectionID() != req.CollectionID {
+			return true
+		}
+		if channel.InterfaceVersion() < channelVersionListeners {
+			return true
+		}
+		registrations = append(registrations, channel.registerListener(filter, func(ev ChannelEvent) {
+			w.offer(name, ev)
+		}))
+		return true
+	})
+	defer func() {
+		for _, r := range registrations {
+			r.Unregister()
+		}
+	}()
+
+	if len(registrations) == 0 {
+		return fmt.Errorf("no channel to watch, collection %d, channel %q", req.CollectionID, req.Channel)
+	}
+	return w.serve(stream)
+}
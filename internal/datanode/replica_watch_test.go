@@ -0,0 +1,133 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/commonpb"
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReplicaEventStream struct {
+	ctx     context.Context
+	sent    chan *ReplicaEvent
+	sendErr error
+}
+
+func (s *fakeReplicaEventStream) Send(ev *ReplicaEvent) error {
+	if s.sendErr != nil {
+		return s.sendErr
+	}
+	s.sent <- ev
+	return nil
+}
+
+func (s *fakeReplicaEventStream) Context() context.Context {
+	return s.ctx
+}
+
+func listenerCount(c *ChannelMeta) int {
+	c.events.mu.Lock()
+	defer c.events.mu.Unlock()
+	return len(c.events.listeners)
+}
+
+func TestReplicaEventWatcher(t *testing.T) {
+	t.Run("drops and reports when full", func(t *testing.T) {
+		w := newReplicaEventWatcher(2)
+		for i := 1; i <= 5; i++ {
+			w.offer("ch", ChannelEvent{Type: SegmentAdded, SegmentID: UniqueID(i)})
+		}
+		events := w.take()
+		require.Len(t, events, 2)
+		assert.Equal(t, UniqueID(1), events[0].Event.SegmentID)
+		assert.Equal(t, int64(3), events[0].DroppedEvents)
+		assert.Zero(t, events[1].DroppedEvents)
+
+		w.offer("ch", ChannelEvent{Type: SegmentRemoved, SegmentID: 6})
+		events = w.take()
+		require.Len(t, events, 1)
+		assert.Zero(t, events[0].DroppedEvents)
+		assert.Empty(t, w.take())
+	})
+
+	t.Run("send failure ends the stream", func(t *testing.T) {
+		w := newReplicaEventWatcher(0)
+		assert.Equal(t, channelEventBufferSize, w.size)
+		w.offer("ch", ChannelEvent{Type: SegmentAdded, SegmentID: 1})
+		sendErr := errors.New("broken stream")
+		err := w.serve(&fakeReplicaEventStream{ctx: context.Background(), sendErr: sendErr})
+		assert.ErrorIs(t, err, sendErr)
+	})
+}
+
+func TestDataNode_WatchReplicaEvents(t *testing.T) {
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	node := &DataNode{flowgraphManager: newFlowgraphManager()}
+	node.stateCode.Store(commonpb.StateCode_Healthy)
+	watched := newChannel("ch-0", 1, nil, rc, nil)
+	other := newChannel("ch-1", 2, nil, rc, nil)
+	node.flowgraphManager.flowgraphs.Store("ch-0", &dataSyncService{channel: watched})
+	node.flowgraphManager.flowgraphs.Store("ch-1", &dataSyncService{channel: other})
+
+	t.Run("streams the events of the selected collection", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := &fakeReplicaEventStream{ctx: ctx, sent: make(chan *ReplicaEvent, 10)}
+		done := make(chan error, 1)
+		go func() { done <- node.WatchReplicaEvents(&WatchReplicaEventsRequest{CollectionID: 1}, stream) }()
+		require.Eventually(t, func() bool { return listenerCount(watched) == 1 }, time.Second, time.Millisecond)
+		assert.Zero(t, listenerCount(other))
+
+		require.NoError(t, other.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 20, collID: 2, partitionID: 10}))
+		require.NoError(t, watched.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 10, collID: 1, partitionID: 10}))
+		watched.removeSegments(10)
+
+		for _, want := range []ChannelEventType{SegmentAdded, SegmentRemoved} {
+			select {
+			case ev := <-stream.sent:
+				assert.Equal(t, "ch-0", ev.Channel)
+				assert.Equal(t, want, ev.Event.Type)
+				assert.Equal(t, UniqueID(10), ev.Event.SegmentID)
+				assert.Zero(t, ev.DroppedEvents)
+			case <-time.After(time.Second):
+				t.Fatal("event not streamed")
+			}
+		}
+
+		cancel()
+		assert.NoError(t, <-done)
+		assert.Zero(t, listenerCount(watched))
+	})
+
+	t.Run("nothing to watch", func(t *testing.T) {
+		stream := &fakeReplicaEventStream{ctx: context.Background()}
+		assert.Error(t, node.WatchReplicaEvents(&WatchReplicaEventsRequest{Channel: "ch-9"}, stream))
+	})
+
+	t.Run("unhealthy", func(t *testing.T) {
+		node := &DataNode{flowgraphManager: newFlowgraphManager()}
+		node.stateCode.Store(commonpb.StateCode_Abnormal)
+		assert.Error(t, node.WatchReplicaEvents(&WatchReplicaEventsRequest{}, &fakeReplicaEventStream{ctx: context.Background()}))
+	})
+}
@@ -17,8 +17,10 @@
 package datanode
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/bits-and-blooms/bloom/v3"
 	"github.com/milvus-io/milvus/internal/proto/datapb"
@@ -28,14 +30,57 @@ import (
 
 // Segment contains the latest segment infos from channel.
 type Segment struct {
+	// collectionID and partitionID are the owner of the segment, set on creation and read by
+	// getCollectionID and getPartitionID, moveSegmentToPartition is the only path changing partitionID
 	collectionID UniqueID
 	partitionID  UniqueID
 	segmentID    UniqueID
 	sType        atomic.Value // datapb.SegmentType
+	namespace    string
 
 	numRows     int64
 	memorySize  int64
 	compactedTo UniqueID
+	// deletedRows are the rows matched by deletes, counted by addDeletedRows
+	deletedRows int64
+
+	// spill statistics, updated when insert buffer is synced before the segment is sealed
+	spillCount   int64
+	spilledBytes int64
+	spilledRows  int64
+
+	// bufferedSize is the bytes of the segment in the insert buffer, binlogSize the bytes of its insert binlogs
+	// with the recovered ones, together the memory size of the segment
+	bufferedSize int64
+	binlogSize   int64
+
+	// allocationTime is when the segment is added to channel, firstRowTime is when the
+	// first rows are counted into it, zero firstRowTime means the segment is still empty
+	allocationTime time.Time
+	firstRowTime   time.Time
+
+	// lease of the segment ownership, empty leaseHolder means not leased
+	leaseHolder string
+	leaseExpire Timestamp
+
+	// transitions are the latest type changes of the segment with reasons
+	transitions segmentTransitions
+	// assignments are the latest data nodes owning the segment, guarded by segMu of the channel
+	assignments []AssignmentHistory
+	// rowHistory are the latest row counts of the segment, for forecasting
+	rowHistory rowCountHistory
+	// flushedRows are the rows written to the insert binlogs of the segment, recovered rows included
+	flushedRows int64
+	// reconcileFailed is set if the rows flushed mismatch the rows counted beyond tolerance
+	reconcileFailed bool
+	// appliedMsgs are the IDs of the messages applied by updateStatisticsIdempotent, guarded by segMu of the channel
+	appliedMsgs *bloom.BloomFilter
+	// reported is the statistics of the last getSegmentStatisticsDelta, guarded by segMu of the channel
+	reported *StatsDelta
+	// nullCounts are the null rows per field ID, guarded by segMu of the channel
+	nullCounts map[int64]int64
+	// fieldStats are the min, max and null count per field name, guarded by segMu of the channel
+	fieldStats map[string]*FieldStats
 
 	statLock     sync.Mutex
 	currentStat  *storage.PkStatistics
@@ -49,10 +94,29 @@ type addSegmentReq struct {
 	segType                    datapb.SegmentType
 	segID, collID, partitionID UniqueID
 	numOfRows                  int64
+	binlogSize                 int64 // bytes of the insert binlogs of a recovered segment
 	startPos, endPos           *internalpb.MsgPosition
 	statsBinLogs               []*datapb.FieldBinlog
 	recoverTs                  Timestamp
 	importing                  bool
+	// namespace is the tenant of the segment, checked against the namespace of the channel
+	namespace string
+	// ctx bounds the wait of rate limited addSegment, nil means no bound
+	ctx context.Context
+	// adoptReusedID replaces a flushed or dropped segment of the same ID instead of refusing it,
+	// for controlled migrations only
+	adoptReusedID bool
+}
+
+// getCollectionID returns the collection of the segment, which never changes.
+func (s *Segment) getCollectionID() UniqueID {
+	return s.collectionID
+}
+
+// getPartitionID returns the partition of the segment, guarded by segMu of the channel
+// as moveSegmentToPartition may change it.
+func (s *Segment) getPartitionID() UniqueID {
+	return s.partitionID
 }
 
 func (s *Segment) isValid() bool {
@@ -63,10 +127,29 @@ func (s *Segment) notFlushed() bool {
 	return s.isValid() && s.getType() != datapb.SegmentType_Flushed
 }
 
+// hasStartPosition returns whether the start position has ever been set.
+// A nil position or a position with zero timestamp is regarded as unset.
+func (s *Segment) hasStartPosition() bool {
+	return s.startPos != nil && s.startPos.GetTimestamp() != 0
+}
+
+// hasEndPosition returns whether the end position has ever been set.
+// A nil position or a position with zero timestamp is regarded as unset.
+func (s *Segment) hasEndPosition() bool {
+	return s.endPos != nil && s.endPos.GetTimestamp() != 0
+}
+
 func (s *Segment) getType() datapb.SegmentType {
 	return s.sType.Load().(datapb.SegmentType)
 }
 
+// loadType returns the type of the segment, ok is false if the type has never been set,
+// as on the segments built by callers before they are put into channel.
+func (s *Segment) loadType() (t datapb.SegmentType, ok bool) {
+	t, ok = s.sType.Load().(datapb.SegmentType)
+	return t, ok
+}
+
 func (s *Segment) setType(t datapb.SegmentType) {
 	s.sType.Store(t)
 }
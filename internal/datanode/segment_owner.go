@@ -0,0 +1,121 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// segmentOwners records the channel holding each segment ID, shared by the channels of a data node so that
+// an ID held by one channel is refused by the others, whatever their collections. An ID is held from
+// the segment is added until it is removed, replacing the segment or evicting it in lazy-load mode keeps it.
+type segmentOwners struct {
+	mu     sync.Mutex
+	owners map[UniqueID]string
+}
+
+func newSegmentOwners() *segmentOwners {
+	return &segmentOwners{owners: make(map[UniqueID]string)}
+}
+
+// claim records channel as the holder of segID unless another channel holds it, and returns the holder.
+func (o *segmentOwners) claim(segID UniqueID, channel string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if owner, ok := o.owners[segID]; ok {
+		return owner
+	}
+	o.owners[segID] = channel
+	return channel
+}
+
+// release forgets segID if channel holds it.
+func (o *segmentOwners) release(segID UniqueID, channel string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.owners[segID] == channel {
+		delete(o.owners, segID)
+	}
+}
+
+// releaseChannel forgets all the IDs held by channel, when the channel is released from the data node.
+func (o *segmentOwners) releaseChannel(channel string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for segID, owner := range o.owners {
+		if owner == channel {
+			delete(o.owners, segID)
+		}
+	}
+}
+
+// WithSegmentOwners shares owners between the channels of a data node, addSegment refuses an ID held by another channel.
+func WithSegmentOwners(owners *segmentOwners) ChannelMetaOption {
+	return func(c *ChannelMeta) {
+		c.segmentOwners = owners
+	}
+}
+
+// claimSegmentIDWithoutLock claims the ID of seg for the channel, refusing it if another channel holds it.
+// Call it as the last check before putting seg, caller shall hold the segMu.
+func (c *ChannelMeta) claimSegmentIDWithoutLock(seg *Segment) error {
+	if c.segmentOwners == nil {
+		return nil
+	}
+	if owner := c.segmentOwners.claim(seg.segmentID, c.channelName); owner != c.channelName {
+		return fmt.Errorf("%w, segment %d of collection %d is held by channel %s",
+			ErrSegmentIDConflict, seg.segmentID, seg.getCollectionID(), owner)
+	}
+	return nil
+}
+
+// validate checks the segment IDs are unique among the channels of the data node, regardless of collection.
+func (fm *flowgraphManager) validate() error {
+	type holder struct {
+		channel string
+		collID  UniqueID
+	}
+	holders := make(map[UniqueID][]holder)
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		channel := value.(*dataSyncService).channel
+		for _, segID := range channel.listAllSegmentIDs() {
+			holders[segID] = append(holders[segID], holder{channel: key.(string), collID: channel.getCollectionID()})
+		}
+		return true
+	})
+
+	var conflicts []string
+	for segID, hs := range holders {
+		if len(hs) < 2 {
+			continue
+		}
+		sort.Slice(hs, func(i, j int) bool { return hs[i].channel < hs[j].channel })
+		desc := make([]string, 0, len(hs))
+		for _, h := range hs {
+			desc = append(desc, fmt.Sprintf("%s(collection %d)", h.channel, h.collID))
+		}
+		conflicts = append(conflicts, fmt.Sprintf("segment %d in %s", segID, strings.Join(desc, ", ")))
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+	return fmt.Errorf("%w: %s", ErrSegmentIDConflict, strings.Join(conflicts, "; "))
+}
@@ -0,0 +1,71 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+)
+
+func TestChannelMeta_segmentIDAcrossCollections(t *testing.T) {
+	rc := &RootCoordFactory{pkType: schemapb.DataType_Int64}
+	newReq := func(collID UniqueID) addSegmentReq {
+		return addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: collID, partitionID: 10}
+	}
+
+	t.Run("rejected", func(t *testing.T) {
+		owners := newSegmentOwners()
+		ch1 := newChannel("ch-1", 1, nil, rc, nil, WithSegmentOwners(owners))
+		ch2 := newChannel("ch-2", 2, nil, rc, nil, WithSegmentOwners(owners))
+
+		require.NoError(t, ch1.addSegment(newReq(1)))
+		assert.ErrorIs(t, ch2.addSegment(newReq(2)), ErrSegmentIDConflict)
+		_, err := ch2.addSegments([]addSegmentReq{newReq(2)})
+		assert.ErrorIs(t, err, ErrSegmentIDConflict)
+		assert.False(t, ch2.hasSegment(1, true))
+
+		// the ID is released once the segment is removed
+		ch1.removeSegments(1)
+		assert.NoError(t, ch2.addSegment(newReq(2)))
+		assert.ErrorIs(t, ch1.addSegment(newReq(1)), ErrSegmentIDConflict)
+
+		owners.releaseChannel("ch-2")
+		assert.NoError(t, ch1.addSegment(newReq(1)))
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		fm := newFlowgraphManager()
+		ch1 := newChannel("ch-1", 1, nil, rc, nil)
+		ch2 := newChannel("ch-2", 2, nil, rc, nil)
+		fm.flowgraphs.Store("ch-1", &dataSyncService{channel: ch1})
+		fm.flowgraphs.Store("ch-2", &dataSyncService{channel: ch2})
+
+		require.NoError(t, ch1.addSegment(newReq(1)))
+		assert.NoError(t, fm.validate())
+
+		// channels not sharing the owners are caught by validate only
+		require.NoError(t, ch2.addSegment(newReq(2)))
+		err := fm.validate()
+		assert.ErrorIs(t, err, ErrSegmentIDConflict)
+		assert.Contains(t, err.Error(), "segment 1 in ch-1(collection 1), ch-2(collection 2)")
+	})
+}
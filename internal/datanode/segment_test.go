@@ -18,9 +18,12 @@ package datanode
 
 import (
 	"math/rand"
+	"runtime"
 	"testing"
 
 	"github.com/milvus-io/milvus/internal/common"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
 	"github.com/milvus-io/milvus/internal/storage"
 	"github.com/stretchr/testify/assert"
 )
@@ -56,3 +59,53 @@ func TestEmptySegment(t *testing.T) {
 	pk := newInt64PrimaryKey(1000)
 	assert.False(t, seg.isPKExist(pk))
 }
+
+func TestSegment_hasPosition(t *testing.T) {
+	tests := []struct {
+		description string
+		pos         *internalpb.MsgPosition
+		expected    bool
+	}{
+		{"nil position", nil, false},
+		{"zero timestamp", &internalpb.MsgPosition{ChannelName: "ch"}, false},
+		{"valid position", &internalpb.MsgPosition{ChannelName: "ch", Timestamp: 100}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			seg := &Segment{startPos: test.pos, endPos: test.pos}
+			assert.Equal(t, test.expected, seg.hasStartPosition())
+			assert.Equal(t, test.expected, seg.hasEndPosition())
+		})
+	}
+}
+
+// BenchmarkSegmentFootprint reports the heap bytes a flushed segment costs in channel meta,
+// the baseline for any more compact segment layout.
+func BenchmarkSegmentFootprint(b *testing.B) {
+	const numSegments = 100000
+	var ms runtime.MemStats
+	for i := 0; i < b.N; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&ms)
+		before := ms.HeapAlloc
+
+		channel := newChannel("channel", 1, nil, nil, nil)
+		for j := 0; j < numSegments; j++ {
+			seg := &Segment{
+				collectionID: 1,
+				partitionID:  UniqueID(j % 1000),
+				segmentID:    UniqueID(j),
+				numRows:      1024,
+				startPos:     &internalpb.MsgPosition{ChannelName: "channel", Timestamp: 100},
+				endPos:       &internalpb.MsgPosition{ChannelName: "channel", Timestamp: 200},
+			}
+			seg.setType(datapb.SegmentType_Flushed)
+			channel.putSegmentWithoutLock(seg)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&ms)
+		b.ReportMetric(float64(ms.HeapAlloc-before)/numSegments, "B/segment")
+		runtime.KeepAlive(channel)
+	}
+}
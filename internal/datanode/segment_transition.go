@@ -0,0 +1,112 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+)
+
+// TransitionReason tells why a segment changed its type.
+type TransitionReason int32
+
+const (
+	// TransitionAdded is recorded when the segment is added to the channel, with From equal to To.
+	TransitionAdded TransitionReason = iota
+	// TransitionReported is a new segment whose start position has been reported to datacoord.
+	TransitionReported
+	// TransitionFlushed is a segment flushed by the flush manager.
+	TransitionFlushed
+	// TransitionCompacted is a segment compacted into another one, or the result of a compaction.
+	TransitionCompacted
+)
+
+var transitionReasonNames = map[TransitionReason]string{
+	TransitionAdded:     "added",
+	TransitionReported:  "reported",
+	TransitionFlushed:   "flushed",
+	TransitionCompacted: "compacted",
+}
+
+func (r TransitionReason) String() string {
+	if name, ok := transitionReasonNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("TransitionReason(%d)", int32(r))
+}
+
+// maxSegmentTransitions is the number of latest transitions kept per segment.
+const maxSegmentTransitions = 8
+
+// SegmentTransition is a change of segment type.
+type SegmentTransition struct {
+	From, To datapb.SegmentType
+	Reason   TransitionReason
+	Time     time.Time
+}
+
+// segmentTransitions keeps the latest maxSegmentTransitions transitions in a ring, guarded by segMu of the channel.
+type segmentTransitions struct {
+	ring  [maxSegmentTransitions]SegmentTransition
+	total int
+}
+
+func (st *segmentTransitions) record(t SegmentTransition) {
+	st.ring[st.total%maxSegmentTransitions] = t
+	st.total++
+}
+
+// list returns the kept transitions, oldest first.
+func (st *segmentTransitions) list() []SegmentTransition {
+	n := st.total
+	if n > maxSegmentTransitions {
+		n = maxSegmentTransitions
+	}
+	result := make([]SegmentTransition, 0, n)
+	for i := st.total - n; i < st.total; i++ {
+		result = append(result, st.ring[i%maxSegmentTransitions])
+	}
+	return result
+}
+
+// transitSegment sets the type of seg and records the transition with reason, caller shall hold the segMu.
+// A segment whose type has never been set, such as the result of a compaction, is taken as added, From equal to To.
+func (c *ChannelMeta) transitSegment(seg *Segment, to datapb.SegmentType, reason TransitionReason) {
+	from, ok := seg.loadType()
+	if !ok || reason == TransitionAdded {
+		from = to
+	}
+	seg.setType(to)
+	seg.transitions.record(SegmentTransition{From: from, To: to, Reason: reason, Time: c.now()})
+	metrics.DataNodeSegmentTransitionCount.WithLabelValues(fmt.Sprint(paramtable.GetNodeID()), reason.String()).Inc()
+}
+
+// getSegmentTransitions returns the latest transitions of a segment, oldest first.
+func (c *ChannelMeta) getSegmentTransitions(segID UniqueID) ([]SegmentTransition, error) {
+	c.segMu.RLock()
+	defer c.segMu.RUnlock()
+
+	seg, ok := c.segments[segID]
+	if !ok {
+		return nil, fmt.Errorf("cannot find segment, id = %d", segID)
+	}
+	return seg.transitions.list(), nil
+}
@@ -0,0 +1,112 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/metrics"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/util/paramtable"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelMeta_segmentTransitions(t *testing.T) {
+	now := time.Unix(1000, 0)
+	channel := newChannel("channel", 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, nil)
+	channel.clock = func() time.Time { return now }
+
+	transitionCount := func(reason TransitionReason) float64 {
+		return testutil.ToFloat64(metrics.DataNodeSegmentTransitionCount.WithLabelValues(
+			fmt.Sprint(paramtable.GetNodeID()), reason.String()))
+	}
+	compactedBefore := transitionCount(TransitionCompacted)
+
+	for _, segID := range []UniqueID{1, 2} {
+		require.NoError(t, channel.addSegment(addSegmentReq{
+			segType:     datapb.SegmentType_New,
+			segID:       segID,
+			collID:      1,
+			partitionID: 10,
+		}))
+	}
+	channel.transferNewSegments([]UniqueID{1, 2})
+	channel.segmentFlushed(1, unknownFlushedRows)
+	channel.segmentFlushed(2, unknownFlushedRows)
+	require.NoError(t, channel.mergeFlushedSegments(&Segment{segmentID: 3, collectionID: 1, partitionID: 10, numRows: 10},
+		100, []UniqueID{1, 2}))
+
+	transitions, err := channel.getSegmentTransitions(1)
+	require.NoError(t, err)
+	assert.Equal(t, []SegmentTransition{
+		{From: datapb.SegmentType_New, To: datapb.SegmentType_New, Reason: TransitionAdded, Time: now},
+		{From: datapb.SegmentType_New, To: datapb.SegmentType_Normal, Reason: TransitionReported, Time: now},
+		{From: datapb.SegmentType_Normal, To: datapb.SegmentType_Flushed, Reason: TransitionFlushed, Time: now},
+		{From: datapb.SegmentType_Flushed, To: datapb.SegmentType_Compacted, Reason: TransitionCompacted, Time: now},
+	}, transitions)
+
+	transitions, err = channel.getSegmentTransitions(3)
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, TransitionCompacted, transitions[0].Reason)
+	assert.Equal(t, datapb.SegmentType_Flushed, transitions[0].To)
+	assert.Equal(t, compactedBefore+3, transitionCount(TransitionCompacted))
+
+	_, err = channel.getSegmentTransitions(4)
+	assert.Error(t, err)
+
+	t.Run("keeps the latest transitions", func(t *testing.T) {
+		seg := &Segment{segmentID: 5}
+		seg.setType(datapb.SegmentType_New)
+		for i := 0; i < maxSegmentTransitions+3; i++ {
+			now = time.Unix(int64(2000+i), 0)
+			channel.transitSegment(seg, datapb.SegmentType_Normal, TransitionReported)
+		}
+		transitions := seg.transitions.list()
+		require.Len(t, transitions, maxSegmentTransitions)
+		assert.Equal(t, time.Unix(2003, 0), transitions[0].Time)
+		assert.Equal(t, time.Unix(int64(2000+maxSegmentTransitions+2), 0), transitions[maxSegmentTransitions-1].Time)
+	})
+
+	t.Run("refused segments record no transition", func(t *testing.T) {
+		addedBefore := transitionCount(TransitionAdded)
+		req := addSegmentReq{segType: datapb.SegmentType_New, segID: 7, collID: 1, partitionID: 10}
+		_, err := channel.addSegments([]addSegmentReq{req})
+		require.NoError(t, err)
+		_, err = channel.addSegments([]addSegmentReq{req})
+		assert.ErrorIs(t, err, ErrSegmentExists)
+		// segment 1 is compacted, its ID cannot be reused
+		assert.ErrorIs(t, channel.addSegment(addSegmentReq{segType: datapb.SegmentType_New, segID: 1, collID: 1, partitionID: 10}),
+			ErrSegmentIDReused)
+
+		assert.Equal(t, addedBefore+1, transitionCount(TransitionAdded))
+		transitions, err := channel.getSegmentTransitions(7)
+		require.NoError(t, err)
+		assert.Len(t, transitions, 1)
+		transitions, err = channel.getSegmentTransitions(1)
+		require.NoError(t, err)
+		assert.Len(t, transitions, 4)
+	})
+
+	assert.Equal(t, "flushed", TransitionFlushed.String())
+	assert.Equal(t, "TransitionReason(100)", TransitionReason(100).String())
+}
@@ -0,0 +1,91 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+// startupReportWindow is how long after a channel is restored its StartupReport is kept in the startup_report metrics.
+const startupReportWindow = 10 * time.Minute
+
+// RestoreSource is where the segments of a channel are restored from when its flowgraph starts.
+type RestoreSource string
+
+const (
+	// RestoreFromService means the segments are restored from the segment infos of datacoord.
+	RestoreFromService RestoreSource = "service"
+	// RestoreEmpty means the channel starts without segments.
+	RestoreEmpty RestoreSource = "empty"
+)
+
+// StartupReport is how a channel was restored when its flowgraph started.
+type StartupReport struct {
+	Channel string        `json:"channel"`
+	Source  RestoreSource `json:"source"`
+	// FetchDuration is spent fetching the segment infos from datacoord
+	FetchDuration time.Duration `json:"fetch_duration"`
+	// RestoreDuration is spent adding the segments into the channel, loading their pk stats included
+	RestoreDuration time.Duration `json:"restore_duration"`
+	TotalDuration   time.Duration `json:"total_duration"`
+	GrowingSegments int           `json:"growing_segments"`
+	FlushedSegments int           `json:"flushed_segments"`
+	// SkippedSegments are segment infos of other collections or channels
+	SkippedSegments int       `json:"skipped_segments"`
+	FinishedAt      time.Time `json:"finished_at"`
+	// Capabilities are the features of the restored channel
+	Capabilities ReplicaCapabilities `json:"capabilities"`
+}
+
+// log prints the summary of the restore once the channel is restored.
+func (r StartupReport) log() {
+	log.Info("channel restored",
+		zap.String("vChannelName", r.Channel),
+		zap.String("source", string(r.Source)),
+		zap.Duration("fetch", r.FetchDuration),
+		zap.Duration("restore", r.RestoreDuration),
+		zap.Duration("total", r.TotalDuration),
+		zap.Int("growingSegments", r.GrowingSegments),
+		zap.Int("flushedSegments", r.FlushedSegments),
+		zap.Int("skippedSegments", r.SkippedSegments),
+		zap.Any("capabilities", r.Capabilities))
+}
+
+// getStartupReport returns how the channel of the service was restored.
+func (dsService *dataSyncService) getStartupReport() StartupReport {
+	return dsService.startupReport
+}
+
+// getStartupReports returns the reports of the channels restored within startupReportWindow before now,
+// sorted by channel name.
+func (fm *flowgraphManager) getStartupReports(now time.Time) []StartupReport {
+	reports := make([]StartupReport, 0)
+	fm.flowgraphs.Range(func(key, value interface{}) bool {
+		report := value.(*dataSyncService).getStartupReport()
+		if !report.FinishedAt.IsZero() && now.Sub(report.FinishedAt) <= startupReportWindow {
+			reports = append(reports, report)
+		}
+		return true
+	})
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Channel < reports[j].Channel })
+	return reports
+}
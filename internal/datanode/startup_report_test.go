@@ -0,0 +1,94 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package datanode
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/milvus-io/milvus-proto/go-api/schemapb"
+	"github.com/milvus-io/milvus/internal/proto/datapb"
+	"github.com/milvus-io/milvus/internal/proto/internalpb"
+	"github.com/milvus-io/milvus/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataSyncService_startupReport(t *testing.T) {
+	ctx := context.Background()
+	cm := storage.NewLocalChunkManager(storage.RootPath(dataSyncServiceTestDir))
+	defer cm.RemoveWithPrefix(ctx, "")
+
+	newService := func(t *testing.T, vchan *datapb.VchannelInfo) *dataSyncService {
+		channel := newChannel(vchan.GetChannelName(), 1, nil, &RootCoordFactory{pkType: schemapb.DataType_Int64}, cm)
+		ds, err := newDataSyncService(ctx, make(chan flushMsg), make(chan resendTTMsg), channel,
+			NewAllocatorFactory(), &mockMsgStreamFactory{true, true}, vchan, make(chan string),
+			&DataCoordFactory{}, newCache(), cm, newCompactionExecutor())
+		require.NoError(t, err)
+		return ds
+	}
+
+	t.Run("service", func(t *testing.T) {
+		ds := newService(t, &datapb.VchannelInfo{
+			CollectionID:        1,
+			ChannelName:         "by-dev-rootcoord-dml-test_v1",
+			SeekPosition:        &internalpb.MsgPosition{},
+			UnflushedSegmentIds: []int64{1},
+			// segment 4 is not known to datacoord
+			FlushedSegmentIds: []int64{2, 3, 4},
+		})
+		report := ds.getStartupReport()
+		assert.Equal(t, "by-dev-rootcoord-dml-test_v1", report.Channel)
+		assert.Equal(t, RestoreFromService, report.Source)
+		assert.Equal(t, 1, report.GrowingSegments)
+		assert.Equal(t, 2, report.FlushedSegments)
+		assert.Equal(t, 1, report.SkippedSegments)
+		assert.False(t, report.FinishedAt.IsZero())
+		assert.Equal(t, report.TotalDuration, report.FetchDuration+report.RestoreDuration)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		ds := newService(t, &datapb.VchannelInfo{
+			CollectionID: 1,
+			ChannelName:  "by-dev-rootcoord-dml-test_v2",
+			SeekPosition: &internalpb.MsgPosition{},
+		})
+		report := ds.getStartupReport()
+		assert.Equal(t, RestoreEmpty, report.Source)
+		assert.Zero(t, report.GrowingSegments+report.FlushedSegments+report.SkippedSegments)
+		assert.False(t, report.FinishedAt.IsZero())
+	})
+}
+
+func TestFlowgraphManager_getStartupReports(t *testing.T) {
+	fm := newFlowgraphManager()
+	now := time.Now()
+	for name, finishedAt := range map[string]time.Time{
+		"ch-1": now.Add(-time.Minute),
+		"ch-0": now,
+		"ch-2": now.Add(-startupReportWindow - time.Second),
+		"ch-3": {},
+	} {
+		fm.flowgraphs.Store(name, &dataSyncService{startupReport: StartupReport{Channel: name, FinishedAt: finishedAt}})
+	}
+
+	reports := fm.getStartupReports(now)
+	require.Len(t, reports, 2)
+	assert.Equal(t, "ch-0", reports[0].Channel)
+	assert.Equal(t, "ch-1", reports[1].Channel)
+}
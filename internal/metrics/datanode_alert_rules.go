@@ -0,0 +1,135 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/milvus-io/milvus/internal/util/typeutil"
+)
+
+const (
+	defaultAlertRuleFor      = 5 * time.Minute
+	defaultAlertRuleSeverity = "warning"
+)
+
+// AlertRuleConfig is the thresholds of the DataNode alerting rules generated by GenerateAlertRules,
+// a zero threshold leaves its rule out.
+type AlertRuleConfig struct {
+	// MaxOpenSegmentAgeSeconds bounds how far the checkpoint of a channel may lag behind its latest position,
+	// that is the age of the oldest segment not flushed yet.
+	MaxOpenSegmentAgeSeconds int64
+	// MaxReplicaMemoryGB bounds the resident memory of a DataNode process.
+	MaxReplicaMemoryGB float64
+	// MaxSegmentCountPerCollection bounds the valid segments of a collection on a DataNode.
+	MaxSegmentCountPerCollection int64
+	// For is how long a threshold shall be exceeded before the alert fires, 5m by default.
+	For time.Duration
+	// Severity is the severity label of the alerts, warning by default.
+	Severity string
+}
+
+// alertRule is a rule of the generated file.
+type alertRule struct {
+	Alert       string
+	Expr        string
+	For         string
+	Severity    string
+	Summary     string
+	Description string
+}
+
+var alertRulesTemplate = template.Must(template.New("alert_rules").Parse(`groups:
+- name: milvus-datanode
+  rules:{{if not .}} []{{end}}
+{{- range .}}
+  - alert: {{.Alert}}
+    expr: {{printf "%q" .Expr}}
+    for: {{.For}}
+    labels:
+      severity: {{printf "%q" .Severity}}
+    annotations:
+      summary: {{printf "%q" .Summary}}
+      description: {{printf "%q" .Description}}
+{{- end}}
+`))
+
+// GenerateAlertRules writes a Prometheus alerting rules file in YAML for the DataNode thresholds of config,
+// the rules query the metrics registered by RegisterDataNode, and the process collector for memory.
+func GenerateAlertRules(w io.Writer, config AlertRuleConfig) error {
+	if config.MaxOpenSegmentAgeSeconds < 0 || config.MaxReplicaMemoryGB < 0 || config.MaxSegmentCountPerCollection < 0 {
+		return fmt.Errorf("negative alert rule threshold: %+v", config)
+	}
+	if config.For < 0 {
+		return fmt.Errorf("negative alert rule duration %s", config.For)
+	}
+	forDuration := config.For
+	if forDuration == 0 {
+		forDuration = defaultAlertRuleFor
+	}
+	severity := config.Severity
+	if severity == "" {
+		severity = defaultAlertRuleSeverity
+	}
+	// seconds are valid in every Prometheus version, time.Duration.String is not
+	forStr := strconv.FormatInt(int64(forDuration/time.Second), 10) + "s"
+
+	var rules []alertRule
+	if config.MaxOpenSegmentAgeSeconds > 0 {
+		rules = append(rules, alertRule{
+			Alert: "MilvusDataNodeOpenSegmentTooOld",
+			Expr: fmt.Sprintf("%s > %d",
+				dataNodeMetricName("channel_checkpoint_lag_ms"), config.MaxOpenSegmentAgeSeconds*1000),
+			Summary:     "DataNode channel checkpoint lags behind",
+			Description: fmt.Sprintf("The oldest unflushed segment of channel {{ $labels.channel_name }} on DataNode {{ $labels.node_id }} is older than %ds.", config.MaxOpenSegmentAgeSeconds),
+		})
+	}
+	if config.MaxReplicaMemoryGB > 0 {
+		rules = append(rules, alertRule{
+			Alert: "MilvusDataNodeMemoryTooHigh",
+			// the process collector has no node label, the processes running flowgraphs are DataNodes
+			Expr: fmt.Sprintf("process_resident_memory_bytes > %d and on (instance) %s",
+				int64(config.MaxReplicaMemoryGB*(1<<30)), dataNodeMetricName("flowgraph_num")),
+			Summary:     "DataNode memory usage is high",
+			Description: fmt.Sprintf("The resident memory of DataNode {{ $labels.instance }} exceeds %sGB.", strconv.FormatFloat(config.MaxReplicaMemoryGB, 'f', -1, 64)),
+		})
+	}
+	if config.MaxSegmentCountPerCollection > 0 {
+		rules = append(rules, alertRule{
+			Alert: "MilvusDataNodeTooManySegments",
+			Expr: fmt.Sprintf("%s > %d",
+				dataNodeMetricName("collection_segment_num"), config.MaxSegmentCountPerCollection),
+			Summary:     "DataNode holds too many segments of a collection",
+			Description: fmt.Sprintf("Collection {{ $labels.collection_id }} has more than %d segments on DataNode {{ $labels.node_id }}.", config.MaxSegmentCountPerCollection),
+		})
+	}
+	for i := range rules {
+		rules[i].For = forStr
+		rules[i].Severity = severity
+	}
+	return alertRulesTemplate.Execute(w, rules)
+}
+
+func dataNodeMetricName(name string) string {
+	return prometheus.BuildFQName(milvusNamespace, typeutil.DataNodeRole, name)
+}
@@ -0,0 +1,88 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateAlertRules(t *testing.T) {
+	t.Run("golden", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, GenerateAlertRules(&buf, AlertRuleConfig{
+			MaxOpenSegmentAgeSeconds:     600,
+			MaxReplicaMemoryGB:           8,
+			MaxSegmentCountPerCollection: 2000,
+			For:                          10 * time.Minute,
+			Severity:                     "critical",
+		}))
+		golden, err := os.ReadFile(filepath.Join("testdata", "datanode_alert_rules.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, string(golden), buf.String())
+	})
+
+	t.Run("defaults and disabled rules", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, GenerateAlertRules(&buf, AlertRuleConfig{MaxReplicaMemoryGB: 0.5}))
+		assert.Contains(t, buf.String(), "process_resident_memory_bytes > 536870912 and on (instance) milvus_datanode_flowgraph_num")
+		assert.Contains(t, buf.String(), "for: 300s")
+		assert.Contains(t, buf.String(), `severity: "warning"`)
+		assert.NotContains(t, buf.String(), "MilvusDataNodeOpenSegmentTooOld")
+		assert.NotContains(t, buf.String(), "MilvusDataNodeTooManySegments")
+
+		buf.Reset()
+		require.NoError(t, GenerateAlertRules(&buf, AlertRuleConfig{}))
+		assert.Equal(t, "groups:\n- name: milvus-datanode\n  rules: []\n", buf.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Error(t, GenerateAlertRules(&buf, AlertRuleConfig{MaxSegmentCountPerCollection: -1}))
+		assert.Error(t, GenerateAlertRules(&buf, AlertRuleConfig{For: -time.Second}))
+		assert.Zero(t, buf.Len())
+	})
+
+	t.Run("metrics registered", func(t *testing.T) {
+		r := prometheus.NewRegistry()
+		RegisterDataNode(r)
+		DataNodeChannelCheckpointLag.WithLabelValues("1", "ch").Set(0)
+		DataNodeNumFlowGraphs.WithLabelValues("1").Set(0)
+		DataNodeCollectionSegmentNum.WithLabelValues("1", "1").Set(0)
+		defer func() {
+			DataNodeChannelCheckpointLag.DeleteLabelValues("1", "ch")
+			DataNodeNumFlowGraphs.DeleteLabelValues("1")
+			DataNodeCollectionSegmentNum.DeleteLabelValues("1", "1")
+		}()
+		families, err := r.Gather()
+		require.NoError(t, err)
+		registered := make(map[string]bool)
+		for _, family := range families {
+			registered[family.GetName()] = true
+		}
+		for _, name := range []string{"channel_checkpoint_lag_ms", "flowgraph_num", "collection_segment_num"} {
+			assert.True(t, registered[dataNodeMetricName(name)], name)
+		}
+	})
+}
@@ -88,6 +88,17 @@ var (
 			collectionIDLabelName,
 		})
 
+	DataNodeChannelCheckpointLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "channel_checkpoint_lag_ms",
+			Help:      "latest end position minus checkpoint per virtual channel",
+		}, []string{
+			nodeIDLabelName,
+			channelNameLabelName,
+		})
+
 	DataNodeProduceTimeTickLag = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: milvusNamespace,
@@ -167,6 +178,173 @@ var (
 			statusLabelName,
 		})
 
+	// DataNodeSegmentSpillCount counts the insert buffer syncs of growing segments caused by full buffer.
+	DataNodeSegmentSpillCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "segment_spill_count",
+			Help:      "count of insert buffer spills of growing segments",
+		}, []string{
+			nodeIDLabelName,
+		})
+
+	// DataNodeMemorySizeUnderReportCount counts the memory size reports smaller than the schema lower bound.
+	DataNodeMemorySizeUnderReportCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "memory_size_under_report_count",
+			Help:      "count of segment memory size reports smaller than the schema lower bound",
+		}, []string{
+			nodeIDLabelName,
+		})
+
+	// DataNodeCollectionSafeTimestamp records the physical time up to which all data of a collection is accounted.
+	DataNodeCollectionSafeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "collection_safe_ts_ms",
+			Help:      "physical time in milliseconds up to which all data of the collection is accounted",
+		}, []string{
+			nodeIDLabelName,
+			collectionIDLabelName,
+		})
+
+	// DataNodeActiveChannelNum records the number of channels actively delivering data, per collection.
+	DataNodeActiveChannelNum = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "active_channel_num",
+			Help:      "number of channels whose end positions advanced recently per collection",
+		}, []string{
+			nodeIDLabelName,
+			collectionIDLabelName,
+		})
+
+	// DataNodeCollectionSegmentNum is the number of valid segments of a collection on the node, 0 for collections without any.
+	DataNodeCollectionSegmentNum = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "collection_segment_num",
+			Help:      "number of valid segments of a collection on the node",
+		}, []string{
+			nodeIDLabelName,
+			collectionIDLabelName,
+		})
+
+	// DataNodeCollectionRowNum is the number of rows of the valid segments of a collection on the node.
+	DataNodeCollectionRowNum = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "collection_row_num",
+			Help:      "number of rows of the valid segments of a collection on the node",
+		}, []string{
+			nodeIDLabelName,
+			collectionIDLabelName,
+		})
+
+	// DataNodeCollectionLabels caps the distinct collection_id values of segment_add_total and segment_update_total,
+	// see ReleaseDataNodeCollectionLabel.
+	DataNodeCollectionLabels = NewLabelValueLimiter()
+
+	// DataNodeSegmentAddCount counts segments added into channels, per collection.
+	DataNodeSegmentAddCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "segment_add_total",
+			Help:      "count of segments added, collections beyond the label cap are counted as other",
+		}, []string{
+			nodeIDLabelName,
+			collectionIDLabelName,
+		})
+
+	// DataNodeSegmentUpdateCount counts segment statistics updates, per collection.
+	DataNodeSegmentUpdateCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "segment_update_total",
+			Help:      "count of segment statistics updates, collections beyond the label cap are counted as other",
+		}, []string{
+			nodeIDLabelName,
+			collectionIDLabelName,
+		})
+
+	// DataNodeSegmentTransitionCount counts segment type transitions by reason.
+	DataNodeSegmentTransitionCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "segment_transition_total",
+			Help:      "count of segment type transitions by reason",
+		}, []string{
+			nodeIDLabelName,
+			reasonLabelName,
+		})
+
+	// DataNodeAggregateDriftCount counts the channel aggregates found drifted from segments and repaired.
+	DataNodeAggregateDriftCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "replica_aggregate_drift_total",
+			Help:      "count of channel aggregates drifted from segments and repaired by the auditor",
+		}, []string{
+			nodeIDLabelName,
+			aggregateLabelName,
+		})
+
+	// DataNodeFlushReconcileFailureCount counts the flushed segments whose flushed rows mismatch the rows counted.
+	DataNodeFlushReconcileFailureCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "flush_reconcile_failure_total",
+			Help:      "count of flushed segments whose rows in binlogs mismatch the rows counted by the channel",
+		}, []string{
+			nodeIDLabelName,
+		})
+
+	// DataNodeSegmentIDReuseCount counts segments refused for reusing the ID of a flushed or dropped segment.
+	DataNodeSegmentIDReuseCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "segment_id_reuse_total",
+			Help:      "count of segments refused for reusing the ID of a flushed or dropped segment",
+		}, []string{
+			nodeIDLabelName,
+			segmentStateLabelName,
+		})
+
+	// DataNodeSegmentRowCapCount counts growing segments hitting the local row cap of the channel.
+	DataNodeSegmentRowCapCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "segment_row_cap_total",
+			Help:      "count of growing segments hitting the local row cap, sealed as a backstop of the seal policy",
+		}, []string{
+			nodeIDLabelName,
+		})
+
+	// DataNodeListenerDroppedEvents counts channel events dropped for asynchronous listeners falling behind.
+	DataNodeListenerDroppedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: milvusNamespace,
+			Subsystem: typeutil.DataNodeRole,
+			Name:      "listener_dropped_events_total",
+			Help:      "count of channel events dropped for asynchronous listeners falling behind",
+		}, []string{
+			nodeIDLabelName,
+		})
+
 	DataNodeCompactionLatency = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: milvusNamespace,
@@ -209,8 +387,28 @@ var (
 		}, []string{nodeIDLabelName})
 )
 
+// ReleaseDataNodeCollectionLabel releases the collection label value from DataNodeCollectionLabels and deletes
+// the segment_add_total and segment_update_total series of it, for a collection leaving the node.
+func ReleaseDataNodeCollectionLabel(nodeID, collection string) {
+	if !DataNodeCollectionLabels.Release(collection) {
+		return
+	}
+	DataNodeSegmentAddCount.DeleteLabelValues(nodeID, collection)
+	DataNodeSegmentUpdateCount.DeleteLabelValues(nodeID, collection)
+}
+
+// dataNodeGatherer gathers the registry DataNode metrics are registered to.
+var dataNodeGatherer prometheus.Gatherer = prometheus.DefaultGatherer
+
+// DataNodeGatherer returns the gatherer of the registry passed to RegisterDataNode,
+// the default gatherer if DataNode metrics are not registered yet.
+func DataNodeGatherer() prometheus.Gatherer {
+	return dataNodeGatherer
+}
+
 //RegisterDataNode registers DataNode metrics
 func RegisterDataNode(registry *prometheus.Registry) {
+	dataNodeGatherer = registry
 	registry.MustRegister(DataNodeNumFlowGraphs)
 	registry.MustRegister(DataNodeConsumeMsgRowsCount)
 	registry.MustRegister(DataNodeFlushedSize)
@@ -222,10 +420,25 @@ func RegisterDataNode(registry *prometheus.Registry) {
 	registry.MustRegister(DataNodeSave2StorageLatency)
 	registry.MustRegister(DataNodeFlushBufferCount)
 	registry.MustRegister(DataNodeAutoFlushBufferCount)
+	registry.MustRegister(DataNodeSegmentSpillCount)
+	registry.MustRegister(DataNodeMemorySizeUnderReportCount)
+	registry.MustRegister(DataNodeCollectionSafeTimestamp)
+	registry.MustRegister(DataNodeActiveChannelNum)
+	registry.MustRegister(DataNodeSegmentAddCount)
+	registry.MustRegister(DataNodeSegmentUpdateCount)
+	registry.MustRegister(DataNodeSegmentTransitionCount)
+	registry.MustRegister(DataNodeAggregateDriftCount)
+	registry.MustRegister(DataNodeFlushReconcileFailureCount)
+	registry.MustRegister(DataNodeSegmentIDReuseCount)
+	registry.MustRegister(DataNodeSegmentRowCapCount)
+	registry.MustRegister(DataNodeListenerDroppedEvents)
+	registry.MustRegister(DataNodeCollectionSegmentNum)
+	registry.MustRegister(DataNodeCollectionRowNum)
 	registry.MustRegister(DataNodeCompactionLatency)
 	registry.MustRegister(DataNodeFlushReqCounter)
 	registry.MustRegister(DataNodeConsumeMsgCount)
 	registry.MustRegister(DataNodeProduceTimeTickLag)
+	registry.MustRegister(DataNodeChannelCheckpointLag)
 	registry.MustRegister(DataNodeConsumeBytesCount)
 	registry.MustRegister(DataNodeForwardDeleteMsgTimeTaken)
 }
@@ -247,6 +460,27 @@ func CleanupDataNodeCollectionMetrics(nodeID int64, collectionID int64, channel
 				channelNameLabelName:  channel,
 			})
 
+	DataNodeCollectionSafeTimestamp.
+		Delete(
+			prometheus.Labels{
+				nodeIDLabelName:       fmt.Sprint(nodeID),
+				collectionIDLabelName: fmt.Sprint(collectionID),
+			})
+
+	DataNodeCollectionSegmentNum.
+		Delete(
+			prometheus.Labels{
+				nodeIDLabelName:       fmt.Sprint(nodeID),
+				collectionIDLabelName: fmt.Sprint(collectionID),
+			})
+
+	DataNodeCollectionRowNum.
+		Delete(
+			prometheus.Labels{
+				nodeIDLabelName:       fmt.Sprint(nodeID),
+				collectionIDLabelName: fmt.Sprint(collectionID),
+			})
+
 	for _, label := range []string{AllLabel, DeleteLabel, InsertLabel} {
 		DataNodeConsumeMsgCount.
 			Delete(
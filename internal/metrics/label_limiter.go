@@ -0,0 +1,95 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import "sync"
+
+const (
+	// OtherLabel is the label value excess values fall back to once a LabelValueLimiter is full.
+	OtherLabel = "other"
+
+	defaultMaxLabelValues = 100
+)
+
+// LabelValueLimiter caps the distinct values of a label to prevent cardinality explosion,
+// values beyond the cap are reported as OtherLabel. Admitted values are kept until released.
+type LabelValueLimiter struct {
+	mu             sync.RWMutex
+	maxLabelValues int
+	values         map[string]struct{}
+}
+
+// LabelValueLimiterOption is the option to setup LabelValueLimiter.
+type LabelValueLimiterOption func(l *LabelValueLimiter)
+
+// WithMaxLabelValues sets the max number of distinct label values.
+func WithMaxLabelValues(n int) LabelValueLimiterOption {
+	return func(l *LabelValueLimiter) {
+		l.maxLabelValues = n
+	}
+}
+
+// NewLabelValueLimiter creates a LabelValueLimiter admitting 100 distinct values by default.
+func NewLabelValueLimiter(opts ...LabelValueLimiterOption) *LabelValueLimiter {
+	l := &LabelValueLimiter{
+		maxLabelValues: defaultMaxLabelValues,
+		values:         make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Value returns value if it is admitted or there is room for it, otherwise OtherLabel.
+func (l *LabelValueLimiter) Value(value string) string {
+	l.mu.RLock()
+	_, ok := l.values[value]
+	l.mu.RUnlock()
+	if ok {
+		return value
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.values[value]; ok {
+		return value
+	}
+	if len(l.values) >= l.maxLabelValues {
+		return OtherLabel
+	}
+	l.values[value] = struct{}{}
+	return value
+}
+
+// SetMaxLabelValues changes the max number of distinct label values, values admitted beyond a lowered cap are kept.
+func (l *LabelValueLimiter) SetMaxLabelValues(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxLabelValues = n
+}
+
+// Release drops an admitted value to make room for others, returns false if value is not admitted.
+func (l *LabelValueLimiter) Release(value string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.values[value]; !ok {
+		return false
+	}
+	delete(l.values, value)
+	return true
+}
@@ -0,0 +1,50 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabelValueLimiter(t *testing.T) {
+	l := NewLabelValueLimiter(WithMaxLabelValues(2))
+	assert.Equal(t, "1", l.Value("1"))
+	assert.Equal(t, "2", l.Value("2"))
+	assert.Equal(t, OtherLabel, l.Value("3"))
+
+	// admitted values are kept
+	assert.Equal(t, "1", l.Value("1"))
+	assert.Equal(t, OtherLabel, l.Value("4"))
+
+	assert.Equal(t, defaultMaxLabelValues, NewLabelValueLimiter().maxLabelValues)
+
+	t.Run("release", func(t *testing.T) {
+		assert.True(t, l.Release("1"))
+		assert.False(t, l.Release("1"))
+		assert.False(t, l.Release("4"))
+		assert.Equal(t, "4", l.Value("4"))
+		assert.Equal(t, OtherLabel, l.Value("1"))
+	})
+
+	t.Run("cap changed", func(t *testing.T) {
+		l.SetMaxLabelValues(3)
+		assert.Equal(t, "1", l.Value("1"))
+		assert.Equal(t, OtherLabel, l.Value("5"))
+	})
+}
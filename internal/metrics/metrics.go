@@ -65,6 +65,8 @@ const (
 	queryTypeLabelName       = "query_type"
 	collectionName           = "collection_name"
 	segmentStateLabelName    = "segment_state"
+	reasonLabelName          = "reason"
+	aggregateLabelName       = "aggregate"
 	usernameLabelName        = "username"
 	roleNameLabelName        = "role_name"
 	cacheNameLabelName       = "cache_name"
@@ -27,6 +27,9 @@ func TestRegisterMetrics(t *testing.T) {
 	// Make sure it doesn't panic.
 	RegisterRootCoord(r)
 	RegisterDataNode(r)
+	if DataNodeGatherer() != prometheus.Gatherer(r) {
+		t.Error("DataNodeGatherer should gather the registry DataNode metrics are registered to")
+	}
 	RegisterDataCoord(r)
 	RegisterIndexNode(r)
 	RegisterIndexCoord(r)
@@ -0,0 +1,230 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/milvus-io/milvus/internal/log"
+)
+
+const defaultRemoteWriteInterval = 15 * time.Second
+
+// RemoteWriteExporter periodically pushes the gathered metrics to a Prometheus remote-write
+// endpoint, e.g. VictoriaMetrics, for deployments where metrics can not be pulled.
+type RemoteWriteExporter struct {
+	gatherer prometheus.Gatherer
+	url      string
+	interval time.Duration
+	client   *http.Client
+
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+// RemoteWriteOption is the option to setup RemoteWriteExporter.
+type RemoteWriteOption func(e *RemoteWriteExporter)
+
+// WithRemoteWriteURL sets the remote-write endpoint and the push interval.
+func WithRemoteWriteURL(url string, interval time.Duration) RemoteWriteOption {
+	return func(e *RemoteWriteExporter) {
+		e.url = url
+		if interval > 0 {
+			e.interval = interval
+		}
+	}
+}
+
+// NewRemoteWriteExporter creates a RemoteWriteExporter pushing the metrics of gatherer.
+func NewRemoteWriteExporter(gatherer prometheus.Gatherer, opts ...RemoteWriteOption) *RemoteWriteExporter {
+	e := &RemoteWriteExporter{
+		gatherer: gatherer,
+		interval: defaultRemoteWriteInterval,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.client = &http.Client{Timeout: e.interval}
+	e.ctx, e.cancel = context.WithCancel(context.Background())
+	return e
+}
+
+// Start starts pushing metrics every interval, it does nothing if no url is set.
+func (e *RemoteWriteExporter) Start() {
+	if e.url == "" {
+		return
+	}
+	e.startOnce.Do(func() {
+		e.wg.Add(1)
+		go e.loop()
+	})
+}
+
+// Stop stops pushing metrics and waits for the in-flight push.
+func (e *RemoteWriteExporter) Stop() {
+	e.stopOnce.Do(func() {
+		e.cancel()
+		e.wg.Wait()
+	})
+}
+
+func (e *RemoteWriteExporter) loop() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.Push(e.ctx); err != nil {
+				log.Warn("failed to push metrics by remote write", zap.String("url", e.url), zap.Error(err))
+			}
+		}
+	}
+}
+
+// Push gathers the metrics and sends them to the remote-write endpoint once.
+func (e *RemoteWriteExporter) Push(ctx context.Context) error {
+	mfs, err := e.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	body := snappy.Encode(nil, encodeWriteRequest(mfs, time.Now().UnixMilli()))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write returns status %s: %s", resp.Status, msg)
+	}
+	return nil
+}
+
+type remoteWriteLabel struct {
+	name, value string
+}
+
+// encodeWriteRequest encodes metric families into a remote-write WriteRequest protobuf:
+//
+//	WriteRequest { repeated TimeSeries timeseries = 1; }
+//	TimeSeries   { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label        { string name = 1; string value = 2; }
+//	Sample       { double value = 1; int64 timestamp = 2; }
+//
+// The encoding is hand-rolled with protowire on purpose: client_golang/api/prometheus/v1
+// is a query client and cannot remote-write, and the prompb types live in the prometheus
+// server module, too heavy a dependency for four messages.
+func encodeWriteRequest(mfs []*dto.MetricFamily, ts int64) []byte {
+	var buf []byte
+	appendSeries := func(name string, labels []*dto.LabelPair, value float64, extra ...remoteWriteLabel) {
+		lbs := make([]remoteWriteLabel, 0, len(labels)+len(extra)+1)
+		lbs = append(lbs, remoteWriteLabel{name: "__name__", value: name})
+		for _, lp := range labels {
+			lbs = append(lbs, remoteWriteLabel{name: lp.GetName(), value: lp.GetValue()})
+		}
+		lbs = append(lbs, extra...)
+		sort.Slice(lbs, func(i, j int) bool { return lbs[i].name < lbs[j].name })
+
+		var series []byte
+		for _, lb := range lbs {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, lb.name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, lb.value)
+			series = protowire.AppendTag(series, 1, protowire.BytesType)
+			series = protowire.AppendBytes(series, label)
+		}
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(ts))
+		series = protowire.AppendTag(series, 2, protowire.BytesType)
+		series = protowire.AppendBytes(series, sample)
+
+		buf = protowire.AppendTag(buf, 1, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, series)
+	}
+
+	for _, mf := range mfs {
+		name := mf.GetName()
+		for _, m := range mf.GetMetric() {
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				appendSeries(name, m.GetLabel(), m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				appendSeries(name, m.GetLabel(), m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				appendSeries(name, m.GetLabel(), m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				summary := m.GetSummary()
+				for _, q := range summary.GetQuantile() {
+					appendSeries(name, m.GetLabel(), q.GetValue(),
+						remoteWriteLabel{name: "quantile", value: formatFloat(q.GetQuantile())})
+				}
+				appendSeries(name+"_sum", m.GetLabel(), summary.GetSampleSum())
+				appendSeries(name+"_count", m.GetLabel(), float64(summary.GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				histogram := m.GetHistogram()
+				for _, b := range histogram.GetBucket() {
+					appendSeries(name+"_bucket", m.GetLabel(), float64(b.GetCumulativeCount()),
+						remoteWriteLabel{name: "le", value: formatFloat(b.GetUpperBound())})
+				}
+				appendSeries(name+"_bucket", m.GetLabel(), float64(histogram.GetSampleCount()),
+					remoteWriteLabel{name: "le", value: "+Inf"})
+				appendSeries(name+"_sum", m.GetLabel(), histogram.GetSampleSum())
+				appendSeries(name+"_count", m.GetLabel(), float64(histogram.GetSampleCount()))
+			}
+		}
+	}
+	return buf
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,160 @@
+// Licensed to the LF AI & Data foundation under one
+// or more contributor license agreements. See the NOTICE file
+// distributed with this work for additional information
+// regarding copyright ownership. The ASF licenses this file
+// to you under the Apache License, Version 2.0 (the
+// "License"); you may not use this file except in compliance
+// with the License. You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeWriteRequest decodes a WriteRequest into "name{label=value,...}" -> value.
+func decodeWriteRequest(t *testing.T, buf []byte) map[string]float64 {
+	consumeBytes := func(b []byte) (protowire.Number, []byte, []byte) {
+		num, typ, n := protowire.ConsumeTag(b)
+		require.True(t, n > 0)
+		b = b[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, m := protowire.ConsumeBytes(b)
+			require.True(t, m > 0)
+			return num, v, b[m:]
+		case protowire.Fixed64Type:
+			_, m := protowire.ConsumeFixed64(b)
+			require.True(t, m > 0)
+			return num, b[:m], b[m:]
+		default:
+			m := protowire.ConsumeFieldValue(num, typ, b)
+			require.True(t, m > 0)
+			return num, b[:m], b[m:]
+		}
+	}
+
+	result := make(map[string]float64)
+	for len(buf) > 0 {
+		_, series, rest := consumeBytes(buf)
+		buf = rest
+
+		var name string
+		var labels []string
+		var value float64
+		for len(series) > 0 {
+			num, field, rest := consumeBytes(series)
+			series = rest
+			switch num {
+			case 1:
+				_, lname, lrest := consumeBytes(field)
+				_, lvalue, _ := consumeBytes(lrest)
+				if string(lname) == "__name__" {
+					name = string(lvalue)
+				} else {
+					labels = append(labels, string(lname)+"="+string(lvalue))
+				}
+			case 2:
+				_, v, _ := consumeBytes(field)
+				bits, _ := protowire.ConsumeFixed64(v)
+				value = math.Float64frombits(bits)
+			}
+		}
+		sort.Strings(labels)
+		result[name+"{"+strings.Join(labels, ",")+"}"] = value
+	}
+	return result
+}
+
+func TestRemoteWriteExporter(t *testing.T) {
+	r := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_counter"}, []string{"node_id"})
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_histogram", Buckets: []float64{1, 10}})
+	r.MustRegister(counter, histogram)
+	counter.WithLabelValues("1").Add(3)
+	histogram.Observe(5)
+
+	var mu sync.Mutex
+	var received []map[string]float64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "snappy", req.Header.Get("Content-Encoding"))
+		assert.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+		compressed, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		buf, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+
+		mu.Lock()
+		received = append(received, decodeWriteRequest(t, buf))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	t.Run("push", func(t *testing.T) {
+		e := NewRemoteWriteExporter(r, WithRemoteWriteURL(server.URL, time.Hour))
+		require.NoError(t, e.Push(context.Background()))
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, received, 1)
+		series := received[0]
+		assert.Equal(t, float64(3), series["test_counter{node_id=1}"])
+		assert.Equal(t, float64(0), series["test_histogram_bucket{le=1}"])
+		assert.Equal(t, float64(1), series["test_histogram_bucket{le=10}"])
+		assert.Equal(t, float64(1), series["test_histogram_bucket{le=+Inf}"])
+		assert.Equal(t, float64(5), series["test_histogram_sum{}"])
+		assert.Equal(t, float64(1), series["test_histogram_count{}"])
+	})
+
+	t.Run("periodic push", func(t *testing.T) {
+		e := NewRemoteWriteExporter(r, WithRemoteWriteURL(server.URL, 10*time.Millisecond))
+		e.Start()
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(received) >= 3
+		}, 5*time.Second, 10*time.Millisecond)
+		e.Stop()
+		e.Stop()
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer failing.Close()
+
+		e := NewRemoteWriteExporter(r, WithRemoteWriteURL(failing.URL, time.Hour))
+		assert.Error(t, e.Push(context.Background()))
+	})
+
+	t.Run("no url", func(t *testing.T) {
+		e := NewRemoteWriteExporter(r)
+		e.Start()
+		e.Stop()
+	})
+}
@@ -18,6 +18,7 @@ package msgstream
 
 import (
 	"context"
+	"strconv"
 	"strings"
 
 	"github.com/apache/pulsar-client-go/pulsar"
@@ -40,21 +41,28 @@ import (
 type PmsFactory struct {
 	dispatcherFactory ProtoUDFactory
 	// the following members must be public, so that mapstructure.Decode() can access them
-	PulsarAddress    string
-	PulsarWebAddress string
-	ReceiveBufSize   int64
-	PulsarBufSize    int64
+	PulsarAddress     string
+	PulsarWebAddress  string
+	ReceiveBufSize    int64
+	PulsarBufSize     int64
+	PulsarMaxMsgBytes int
 }
 
 func NewPmsFactory(config *paramtable.PulsarConfig) *PmsFactory {
 	return &PmsFactory{
-		PulsarBufSize:    1024,
-		ReceiveBufSize:   1024,
-		PulsarAddress:    config.Address,
-		PulsarWebAddress: config.WebAddress,
+		PulsarBufSize:     1024,
+		ReceiveBufSize:    1024,
+		PulsarAddress:     config.Address,
+		PulsarWebAddress:  config.WebAddress,
+		PulsarMaxMsgBytes: config.MaxMessageSize,
 	}
 }
 
+// MaxMessageSize returns pulsar.maxMessageSize.
+func (f *PmsFactory) MaxMessageSize() int {
+	return f.PulsarMaxMsgBytes
+}
+
 // NewMsgStream is used to generate a new Msgstream object
 func (f *PmsFactory) NewMsgStream(ctx context.Context) (MsgStream, error) {
 	pulsarClient, err := puslarmqwrapper.NewClient(pulsar.ClientOptions{URL: f.PulsarAddress})
@@ -120,6 +128,11 @@ type RmsFactory struct {
 	RmqBufSize     int64
 }
 
+// MaxMessageSize returns 0, rocksmq does not limit the message size.
+func (f *RmsFactory) MaxMessageSize() int {
+	return 0
+}
+
 // NewMsgStream is used to generate a new Msgstream object
 func (f *RmsFactory) NewMsgStream(ctx context.Context) (MsgStream, error) {
 	rmqClient, err := rmqwrapper.NewClientWithDefaultOptions()
@@ -174,12 +187,25 @@ func NewRmsFactory(path string) *RmsFactory {
 	return f
 }
 
+const (
+	kafkaMaxMessageBytesKey     = "message.max.bytes"
+	defaultKafkaMaxMessageBytes = 1000000
+)
+
 type KmsFactory struct {
 	dispatcherFactory ProtoUDFactory
 	config            *paramtable.KafkaConfig
 	ReceiveBufSize    int64
 }
 
+// MaxMessageSize returns message.max.bytes of the kafka producer config, librdkafka's default if not set.
+func (f *KmsFactory) MaxMessageSize() int {
+	if size, err := strconv.Atoi(f.config.ProducerExtraConfig[kafkaMaxMessageBytesKey]); err == nil {
+		return size
+	}
+	return defaultKafkaMaxMessageBytes
+}
+
 func (f *KmsFactory) NewMsgStream(ctx context.Context) (MsgStream, error) {
 	kafkaClient := kafkawrapper.NewKafkaClientInstanceWithConfig(f.config)
 	return NewMqMsgStream(ctx, f.ReceiveBufSize, -1, kafkaClient, f.dispatcherFactory.NewUnmarshalDispatcher())
@@ -22,6 +22,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/milvus-io/milvus/internal/util/paramtable"
 )
 
 func TestPmsFactory(t *testing.T) {
@@ -69,3 +71,18 @@ func TestKafkaFactory(t *testing.T) {
 	_, err = kmsFactory.NewQueryMsgStream(ctx)
 	assert.Nil(t, err)
 }
+
+func TestFactoryMaxMessageSize(t *testing.T) {
+	var _ MaxMessageSizer = &PmsFactory{}
+	var _ MaxMessageSizer = &RmsFactory{}
+	var _ MaxMessageSizer = &KmsFactory{}
+
+	pulsarCfg := paramtable.PulsarConfig{MaxMessageSize: 1024}
+	assert.Equal(t, 1024, NewPmsFactory(&pulsarCfg).MaxMessageSize())
+	assert.Equal(t, 0, (&RmsFactory{}).MaxMessageSize())
+
+	kafkaCfg := paramtable.KafkaConfig{}
+	assert.Equal(t, defaultKafkaMaxMessageBytes, NewKmsFactory(&kafkaCfg).(*KmsFactory).MaxMessageSize())
+	kafkaCfg.ProducerExtraConfig = map[string]string{kafkaMaxMessageBytesKey: "2048"}
+	assert.Equal(t, 2048, NewKmsFactory(&kafkaCfg).(*KmsFactory).MaxMessageSize())
+}
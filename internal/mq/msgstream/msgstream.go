@@ -78,3 +78,9 @@ type Factory interface {
 	NewQueryMsgStream(ctx context.Context) (MsgStream, error)
 	NewMsgStreamDisposer(ctx context.Context) func([]string, string) error
 }
+
+// MaxMessageSizer is implemented by the factories telling the max message size of their MQ.
+type MaxMessageSizer interface {
+	// MaxMessageSize returns the max bytes of a message produced, non-positive if unlimited.
+	MaxMessageSize() int
+}
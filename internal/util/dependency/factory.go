@@ -78,6 +78,14 @@ func (f *DefaultFactory) initMQRemoteService(params *paramtable.ComponentParam)
 	return nil
 }
 
+// MaxMessageSize returns the max message size of the MQ in use, 0 if its factory does not tell.
+func (f *DefaultFactory) MaxMessageSize() int {
+	if sizer, ok := f.msgStreamFactory.(msgstream.MaxMessageSizer); ok {
+		return sizer.MaxMessageSize()
+	}
+	return 0
+}
+
 func (f *DefaultFactory) NewMsgStream(ctx context.Context) (msgstream.MsgStream, error) {
 	return f.msgStreamFactory.NewMsgStream(ctx)
 }
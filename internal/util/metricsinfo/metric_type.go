@@ -27,6 +27,29 @@ const (
 
 	// SystemInfoMetrics means users request for system information metrics.
 	SystemInfoMetrics = "system_info"
+
+	// CheckpointBlockersMetrics means users request for the segments blocking the checkpoint of a channel,
+	// with the channel name in "channel" and the max number of segments in "top_n" of the request.
+	CheckpointBlockersMetrics = "checkpoint_blockers"
+
+	// PartitionSkewMetrics means users request for the partitions with skewed row counts in a channel,
+	// with the channel name in "channel" and the relative deviation threshold in "threshold" of the request.
+	PartitionSkewMetrics = "partition_skew"
+
+	// CollectionLabelsMetrics means users request for the mapping from the collection labels
+	// in metrics and logs back to the collections.
+	CollectionLabelsMetrics = "collection_labels"
+
+	// StartupReportMetrics means users request for how the channels restored recently were restored.
+	StartupReportMetrics = "startup_report"
+
+	// PositionGapsMetrics means users request for the segments of a channel whose positions span too long,
+	// with the channel name in "channel" and the threshold in milliseconds in "max_gap_ms" of the request.
+	PositionGapsMetrics = "position_gaps"
+
+	// CollectionStatsMetrics means users request for the statistics of every collection on the node,
+	// collections without segments included.
+	CollectionStatsMetrics = "collection_stats"
 )
 
 // ParseMetricType returns the metric type of req
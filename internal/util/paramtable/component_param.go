@@ -1289,6 +1289,14 @@ type dataNodeConfig struct {
 	// io concurrency to fetch stats logs
 	IOConcurrency int
 
+	// how collections are identified in metric labels and logs: full, hashed or idOnly
+	CollectionLabelPolicy string
+	// max distinct collections labeled in per-collection segment counters, the others are labeled other
+	MaxCollectionLabels int
+	// remote-write endpoint DataNode metrics are pushed to, pushing is off when empty
+	MetricsRemoteWriteURL      string
+	MetricsRemoteWriteInterval time.Duration
+
 	CreatedTime time.Time
 	UpdatedTime time.Time
 }
@@ -1299,6 +1307,9 @@ func (p *dataNodeConfig) init(base *BaseTable) {
 	p.initFlowGraphMaxParallelism()
 	p.initFlushInsertBufferSize()
 	p.initIOConcurrency()
+	p.initCollectionLabelPolicy()
+	p.initMaxCollectionLabels()
+	p.initMetricsRemoteWrite()
 
 	p.initChannelWatchPath()
 }
@@ -1333,6 +1344,19 @@ func (p *dataNodeConfig) initIOConcurrency() {
 	p.IOConcurrency = p.Base.ParseIntWithDefault("dataNode.dataSync.ioConcurrency", 10)
 }
 
+func (p *dataNodeConfig) initCollectionLabelPolicy() {
+	p.CollectionLabelPolicy = p.Base.LoadWithDefault("dataNode.metrics.collectionLabelPolicy", "idOnly")
+}
+
+func (p *dataNodeConfig) initMaxCollectionLabels() {
+	p.MaxCollectionLabels = p.Base.ParseIntWithDefault("dataNode.metrics.maxCollectionLabels", 100)
+}
+
+func (p *dataNodeConfig) initMetricsRemoteWrite() {
+	p.MetricsRemoteWriteURL = p.Base.LoadWithDefault("dataNode.metrics.remoteWrite.url", "")
+	p.MetricsRemoteWriteInterval = time.Duration(p.Base.ParseInt64WithDefault("dataNode.metrics.remoteWrite.interval", 15)) * time.Second
+}
+
 // /////////////////////////////////////////////////////////////////////////////
 // --- indexcoord ---
 type indexCoordConfig struct {
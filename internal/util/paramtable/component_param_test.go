@@ -313,6 +313,9 @@ func TestComponentParam(t *testing.T) {
 		size := Params.FlushInsertBufferSize
 		t.Logf("FlushInsertBufferSize: %d", size)
 
+		assert.Equal(t, "", Params.MetricsRemoteWriteURL)
+		assert.Equal(t, 15*time.Second, Params.MetricsRemoteWriteInterval)
+
 		Params.CreatedTime = time.Now()
 		t.Logf("CreatedTime: %v", Params.CreatedTime)
 